@@ -5,15 +5,16 @@ import (
 	"errors"
 	db "github.com/aybarsacar/simplebank/db/sqlc"
 	"github.com/aybarsacar/simplebank/token"
+	"github.com/aybarsacar/simplebank/util"
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
-	"log"
 	"net/http"
+	"time"
 )
 
 // CreateAccountRequest balance = 0 when creating
 type createAccountRequest struct {
-	Currency string `json:"currency" binding:"required,currency"`
+	Currency string `json:"currency" binding:"required,enabled_currency"`
 }
 
 func (server *Server) createAccount(ctx *gin.Context) {
@@ -38,7 +39,6 @@ func (server *Server) createAccount(ctx *gin.Context) {
 	account, err := server.store.CreateAccount(ctx, args)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok {
-			log.Println(pqErr.Code.Name())
 			switch pqErr.Code.Name() {
 			case "foreign_key_violation", "unique_violation":
 				ctx.JSON(http.StatusForbidden, errorResponse(err))
@@ -50,8 +50,10 @@ func (server *Server) createAccount(ctx *gin.Context) {
 		return
 	}
 
+	setAuditResource(ctx, "account_id", account.ID)
+
 	// account is successfully created - send account back to client
-	ctx.JSON(http.StatusOK, account)
+	ctx.JSON(http.StatusOK, newAccountResponse(account))
 }
 
 type getAccountRequest struct {
@@ -82,18 +84,32 @@ func (server *Server) getAccount(ctx *gin.Context) {
 	// get the token payload from the middleware
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
-	if account.Owner != authPayload.Username {
+	if !canReadAccount(authPayload, account) {
 		err := errors.New("account does not belong to the user")
 		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, account)
+	ctx.JSON(http.StatusOK, newAccountResponse(account))
+}
+
+// canReadAccount tells if the user can read the account, its entries and its transfers
+// customers can only read their own accounts while the staff can read every account
+func canReadAccount(payload *token.Payload, account db.Account) bool {
+	return account.Owner == payload.Username || util.IsStaffRole(payload.Role)
 }
 
 type listAccountRequest struct {
-	PageID   int32 `form:"page_id" binding:"required,min=1"`
-	PageSize int32 `form:"page_size" binding:"required,min=5,max=20"`
+	// PageID selects offset pagination, without it the list is paginated with cursors
+	PageID   int32  `form:"page_id" binding:"omitempty,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=20"`
+	Cursor   string `form:"cursor"`
+}
+
+type listAccountResponse struct {
+	Accounts   []accountResponse `json:"accounts"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+	PrevCursor string            `json:"prev_cursor,omitempty"`
 }
 
 func (server *Server) listAccounts(ctx *gin.Context) {
@@ -105,20 +121,130 @@ func (server *Server) listAccounts(ctx *gin.Context) {
 		return
 	}
 
+	cursor, err := parsePagination(req.PageID, req.Cursor)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
 	// get the token payload from the middleware
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
-	args := db.ListAccountsParams{
-		Owner:  authPayload.Username,
-		Limit:  req.PageSize,
-		Offset: (req.PageID - 1) * req.PageSize,
+	if req.PageID > 0 {
+		args := db.ListAccountsParams{
+			Owner:  authPayload.Username,
+			Limit:  req.PageSize,
+			Offset: (req.PageID - 1) * req.PageSize,
+		}
+
+		accounts, err := server.store.ListAccounts(ctx, args)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusOK, newAccountResponses(accounts))
+		return
+	}
+
+	page, err := fetchKeysetPage(
+		cursor,
+		req.PageSize,
+		func(createdAt time.Time, id int64, limit int32) ([]db.Account, error) {
+			return server.store.ListAccountsAfter(ctx, db.ListAccountsAfterParams{
+				Owner:           authPayload.Username,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				PageLimit:       limit,
+			})
+		},
+		func(createdAt time.Time, id int64, limit int32) ([]db.Account, error) {
+			return server.store.ListAccountsBefore(ctx, db.ListAccountsBeforeParams{
+				Owner:           authPayload.Username,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				PageLimit:       limit,
+			})
+		},
+		func(account db.Account) (time.Time, int64) {
+			return account.CreatedAt, account.ID
+		},
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, listAccountResponse{
+		Accounts:   newAccountResponses(page.Items),
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	})
+}
+
+type updateAccountStatusURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type updateAccountStatusRequest struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+// freezeAccount stops money from leaving an account, e.g. while a fraud report is investigated
+func (server *Server) freezeAccount(ctx *gin.Context) {
+	server.updateAccountStatus(ctx, db.AccountStatusFrozen)
+}
+
+// unfreezeAccount lets money leave a frozen account again
+func (server *Server) unfreezeAccount(ctx *gin.Context) {
+	server.updateAccountStatus(ctx, db.AccountStatusActive)
+}
+
+// closeAccount closes an account with a zero balance, the account is kept so its history can still be read
+func (server *Server) closeAccount(ctx *gin.Context) {
+	server.updateAccountStatus(ctx, db.AccountStatusClosed)
+}
+
+func (server *Server) updateAccountStatus(ctx *gin.Context, status string) {
+	var uri updateAccountStatusURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	setAuditResource(ctx, "account_id", uri.ID)
+
+	var req updateAccountStatusRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
 	}
 
-	account, err := server.store.ListAccounts(ctx, args)
+	account, err := server.store.UpdateAccountStatusTx(ctx, db.UpdateAccountStatusTxParams{
+		AccountID: uri.ID,
+		Status:    status,
+		Reason:    req.Reason,
+	})
+
 	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		if errors.Is(err, db.ErrAccountClosed) {
+			ctx.JSON(http.StatusConflict, errorCodeResponse(errCodeAccountClosed, err))
+			return
+		}
+
+		if errors.Is(err, db.ErrAccountBalanceNotZero) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeAccountBalanceNotZero, err))
+			return
+		}
+
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, account)
+	ctx.JSON(http.StatusOK, newAccountResponse(account))
 }
@@ -5,10 +5,12 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"github.com/aybarsacar/simplebank/db/dbtest"
 	mockdb "github.com/aybarsacar/simplebank/db/mock"
 	db "github.com/aybarsacar/simplebank/db/sqlc"
 	"github.com/aybarsacar/simplebank/token"
 	"github.com/aybarsacar/simplebank/util"
+	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/require"
 	"io/ioutil"
@@ -24,6 +26,8 @@ func TestGetAccountAPI(t *testing.T) {
 	}
 
 	account := randomAccount(user.Username)
+	otherUser := util.RandomOwner()
+	teller := util.RandomOwner()
 
 	testCases := []struct {
 		name          string
@@ -37,9 +41,11 @@ func TestGetAccountAPI(t *testing.T) {
 			accountID: account.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				// create new access token and add to the auth header
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.CustomerRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuthorized(store, user.Username)
+
 				// build stubs that returns our random account and nil error
 				store.
 					EXPECT().
@@ -55,14 +61,56 @@ func TestGetAccountAPI(t *testing.T) {
 				requireBodyMatchAccount(t, recorder.Body, account)
 			},
 		},
+		{
+			name:      "OtherUser",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, otherUser, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuthorized(store, otherUser)
+
+				store.
+					EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "Teller",
+			accountID: account.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				// the staff can read the accounts of every customer
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, teller, util.TellerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuthorized(store, teller)
+
+				store.
+					EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, account)
+			},
+		},
 		{
 			name:      "NotFound",
 			accountID: account.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				// create new access token and add to the auth header
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.CustomerRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuthorized(store, user.Username)
+
 				// build stubs that returns our random account and nil error
 				store.
 					EXPECT().
@@ -80,9 +128,11 @@ func TestGetAccountAPI(t *testing.T) {
 			accountID: account.ID,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				// create new access token and add to the auth header
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.CustomerRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuthorized(store, user.Username)
+
 				// build stubs that returns our random account and nil error
 				store.
 					EXPECT().
@@ -100,9 +150,11 @@ func TestGetAccountAPI(t *testing.T) {
 			accountID: 0,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				// create new access token and add to the auth header
-				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, util.CustomerRole, time.Minute)
 			},
 			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuthorized(store, user.Username)
+
 				// build stubs that returns our random account and nil error
 				store.
 					EXPECT().
@@ -160,10 +212,173 @@ func requireBodyMatchAccount(t *testing.T, body *bytes.Buffer, account db.Accoun
 	data, err := ioutil.ReadAll(body)
 	require.NoError(t, err)
 
-	var gotAccount db.Account
+	var gotAccount accountResponse
 
 	err = json.Unmarshal(data, &gotAccount)
 	require.NoError(t, err)
 
-	require.Equal(t, account, gotAccount)
+	require.Equal(t, account, gotAccount.Account)
+	require.Equal(t, account.Balance, gotAccount.BalanceMoney.Amount)
+	require.Equal(t, account.Currency, gotAccount.BalanceMoney.Currency)
+}
+
+func TestUpdateAccountStatusAPI(t *testing.T) {
+	account := randomAccount(util.RandomOwner())
+	reason := "fraud report"
+
+	frozenAccount := account
+	frozenAccount.Status = db.AccountStatusFrozen
+	frozenAccount.StatusReason = reason
+
+	testCases := []struct {
+		name          string
+		action        string
+		role          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Freeze",
+			action: "freeze",
+			role:   util.AdminRole,
+			body:   gin.H{"reason": reason},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				args := db.UpdateAccountStatusTxParams{
+					AccountID: account.ID,
+					Status:    db.AccountStatusFrozen,
+					Reason:    reason,
+				}
+
+				store.EXPECT().UpdateAccountStatusTx(gomock.Any(), gomock.Eq(args)).Times(1).Return(frozenAccount, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, frozenAccount)
+			},
+		},
+		{
+			name:   "Unfreeze",
+			action: "unfreeze",
+			role:   util.AdminRole,
+			body:   gin.H{"reason": "cleared"},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				args := db.UpdateAccountStatusTxParams{
+					AccountID: account.ID,
+					Status:    db.AccountStatusActive,
+					Reason:    "cleared",
+				}
+
+				store.EXPECT().UpdateAccountStatusTx(gomock.Any(), gomock.Eq(args)).Times(1).Return(account, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "CloseWithBalance",
+			action: "close",
+			role:   util.AdminRole,
+			body:   gin.H{"reason": "customer request"},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().UpdateAccountStatusTx(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, db.ErrAccountBalanceNotZero)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				requireBodyMatchErrorCode(t, recorder.Body, errCodeAccountBalanceNotZero)
+			},
+		},
+		{
+			name:   "AlreadyClosed",
+			action: "unfreeze",
+			role:   util.AdminRole,
+			body:   gin.H{"reason": "cleared"},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().UpdateAccountStatusTx(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, db.ErrAccountClosed)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireBodyMatchErrorCode(t, recorder.Body, errCodeAccountClosed)
+			},
+		},
+		{
+			name:   "NotFound",
+			action: "freeze",
+			role:   util.AdminRole,
+			body:   gin.H{"reason": reason},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().UpdateAccountStatusTx(gomock.Any(), gomock.Any()).Times(1).Return(db.Account{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "NoReason",
+			action: "freeze",
+			role:   util.AdminRole,
+			body:   gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().UpdateAccountStatusTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "Teller",
+			action: "freeze",
+			role:   util.TellerRole,
+			body:   gin.H{"reason": reason},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateAccountStatusTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(testCase.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/api/v1/admin/accounts/%d/%s", account.ID, testCase.action)
+
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			username := util.RandomOwner()
+			dbtest.ExpectAuthorized(store, username)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, testCase.role, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+
+			testCase.checkResponse(t, recorder)
+		})
+	}
 }
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"database/sql"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+type revokeUserSessionsRequest struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+type revokeUserSessionsResponse struct {
+	RevokedSessions []uuid.UUID `json:"revoked_sessions"`
+}
+
+// revokeUserSessions blocks every active session of a user, so none of their refresh tokens can be used anymore,
+// and rejects the access tokens that were already issued to them
+func (server *Server) revokeUserSessions(ctx *gin.Context) {
+	var req revokeUserSessionsRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	setAuditResource(ctx, "username", req.Username)
+
+	result, err := server.store.RevokeUserSessionsTx(ctx, req.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	res := revokeUserSessionsResponse{
+		RevokedSessions: make([]uuid.UUID, 0, len(result.RevokedSessions)),
+	}
+
+	for _, session := range result.RevokedSessions {
+		server.denylist.Revoked(session.ID, session.ExpiresAt)
+		res.RevokedSessions = append(res.RevokedSessions, session.ID)
+	}
+
+	// the access tokens issued before the revocation
+	server.denylist.CredentialsChanged(result.User.Username, result.User.SessionsRevokedAt)
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+type updateUserRoleURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+type updateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=customer teller admin"`
+}
+
+// updateUserRole changes the role of a user and revokes their sessions,
+// so the refresh tokens that carry the old role can't be used to get new access tokens
+func (server *Server) updateUserRole(ctx *gin.Context) {
+	var uri updateUserRoleURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	setAuditResource(ctx, "username", uri.Username)
+
+	var req updateUserRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	result, err := server.store.UpdateUserRoleTx(ctx, db.UpdateUserRoleParams{
+		Role:     req.Role,
+		Username: uri.Username,
+	})
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	for _, session := range result.RevokedSessions {
+		server.denylist.Revoked(session.ID, session.ExpiresAt)
+	}
+
+	// the access tokens issued before carry the old role
+	server.denylist.CredentialsChanged(result.User.Username, result.User.RoleChangedAt)
+
+	ctx.JSON(http.StatusOK, newUserResponse(result.User))
+}
+
+type unlockUserRequest struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+// unlockUser lets a user that was locked out after too many failed logins log in again before the lockout ends
+// it also resets the failed attempts, so the next lockout is a short one again
+func (server *Server) unlockUser(ctx *gin.Context) {
+	var req unlockUserRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	setAuditResource(ctx, "username", req.Username)
+
+	user, err := server.store.UnlockUser(ctx, req.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
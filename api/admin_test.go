@@ -0,0 +1,412 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/aybarsacar/simplebank/db/dbtest"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/token"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRevokeUserSessionsAPI(t *testing.T) {
+	user := db.User{
+		Username:          util.RandomOwner(),
+		Role:              util.CustomerRole,
+		SessionsRevokedAt: time.Now(),
+	}
+
+	session := db.Session{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				result := db.RevokeUserSessionsTxResult{User: user, RevokedSessions: []db.Session{session}}
+				store.EXPECT().RevokeUserSessionsTx(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res revokeUserSessionsResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &res)
+				require.NoError(t, err)
+				require.Equal(t, []uuid.UUID{session.ID}, res.RevokedSessions)
+			},
+		},
+		{
+			name: "NotFound",
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().RevokeUserSessionsTx(gomock.Any(), gomock.Any()).Times(1).Return(db.RevokeUserSessionsTxResult{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().RevokeUserSessionsTx(gomock.Any(), gomock.Any()).Times(1).Return(db.RevokeUserSessionsTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/api/v1/admin/users/%s/revoke_sessions", user.Username)
+
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			admin := util.RandomOwner()
+			dbtest.ExpectAuthorized(store, admin)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, admin, util.AdminRole, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRevokeUserSessionsRevokesAccessTokens(t *testing.T) {
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	store := mockdb.NewMockStore(controller)
+	server := newTestServer(t, store)
+
+	username := util.RandomOwner()
+
+	// the user still holds an access token when an admin revokes their sessions
+	staleToken, _, err := server.tokenMaker.CreateToken(username, util.CustomerRole, time.Minute, token.TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	revoked := db.User{Username: username, Role: util.CustomerRole, SessionsRevokedAt: time.Now().Add(time.Millisecond)}
+
+	admin := util.RandomOwner()
+	dbtest.ExpectAuthorized(store, admin)
+	dbtest.ExpectAuditEvent(store)
+
+	store.EXPECT().RevokeUserSessionsTx(gomock.Any(), gomock.Eq(username)).Times(1).Return(db.RevokeUserSessionsTxResult{User: revoked}, nil)
+
+	// the access token isn't on the denylist, the revocation the server cached rejects it
+	store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Times(1).Return(false, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+
+	request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/admin/users/%s/revoke_sessions", username), nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, admin, util.AdminRole, time.Minute)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// the access token is rejected before it expires
+	request, err = http.NewRequest(http.MethodGet, "/api/v1/accounts/1", nil)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, staleToken))
+
+	recorder = httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestUpdateUserRoleAPI(t *testing.T) {
+	user := db.User{
+		Username: util.RandomOwner(),
+		FullName: util.RandomOwner(),
+		Email:    util.RandomEmail(),
+		Role:     util.TellerRole,
+	}
+
+	session := db.Session{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	testCases := []struct {
+		name          string
+		username      string
+		role          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: user.Username,
+			role:     util.AdminRole,
+			body:     gin.H{"role": util.TellerRole},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				args := db.UpdateUserRoleParams{
+					Role:     util.TellerRole,
+					Username: user.Username,
+				}
+
+				result := db.UpdateUserRoleTxResult{User: user, RevokedSessions: []db.Session{session}}
+				store.EXPECT().UpdateUserRoleTx(gomock.Any(), gomock.Eq(args)).Times(1).Return(result, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res userResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &res)
+				require.NoError(t, err)
+				require.Equal(t, util.TellerRole, res.Role)
+			},
+		},
+		{
+			name:     "Teller",
+			username: user.Username,
+			role:     util.TellerRole,
+			body:     gin.H{"role": util.AdminRole},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateUserRoleTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "InvalidRole",
+			username: user.Username,
+			role:     util.AdminRole,
+			body:     gin.H{"role": "manager"},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().UpdateUserRoleTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "NotFound",
+			username: user.Username,
+			role:     util.AdminRole,
+			body:     gin.H{"role": util.CustomerRole},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().UpdateUserRoleTx(gomock.Any(), gomock.Any()).Times(1).Return(db.UpdateUserRoleTxResult{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(testCase.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/api/v1/admin/users/%s/role", testCase.username)
+
+			request, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			username := util.RandomOwner()
+			dbtest.ExpectAuthorized(store, username)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, testCase.role, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestUpdateUserRoleRevokesAccessTokens(t *testing.T) {
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	store := mockdb.NewMockStore(controller)
+	server := newTestServer(t, store)
+
+	username := util.RandomOwner()
+
+	// the admin is demoted while they still hold an access token with the admin role
+	staleToken, _, err := server.tokenMaker.CreateToken(username, util.AdminRole, time.Minute, token.TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	demoted := db.User{Username: username, Role: util.CustomerRole, RoleChangedAt: time.Now().Add(time.Millisecond)}
+
+	admin := util.RandomOwner()
+	dbtest.ExpectAuthorized(store, admin)
+	dbtest.ExpectAuditEvent(store)
+
+	store.EXPECT().UpdateUserRoleTx(gomock.Any(), gomock.Any()).Times(1).Return(db.UpdateUserRoleTxResult{User: demoted}, nil)
+
+	// the stale token isn't on the denylist, the role change the server cached rejects it
+	store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Times(1).Return(false, nil)
+
+	url := fmt.Sprintf("/api/v1/admin/users/%s/role", username)
+	data, err := json.Marshal(gin.H{"role": util.CustomerRole})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, admin, util.AdminRole, time.Minute)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// the token with the old role is rejected before it expires
+	request, err = http.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, staleToken))
+
+	recorder = httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestUnlockUserAPI(t *testing.T) {
+	user := db.User{
+		Username: util.RandomOwner(),
+		FullName: util.RandomOwner(),
+		Email:    util.RandomEmail(),
+		Role:     util.CustomerRole,
+	}
+
+	testCases := []struct {
+		name          string
+		role          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UnlockUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					CreateAuditEvent(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, event db.CreateAuditEventParams) (db.AuditEvent, error) {
+						require.Equal(t, auditActionUnlockUser, event.Action)
+						require.JSONEq(t, fmt.Sprintf(`{"username":%q}`, user.Username), string(event.ResourceIds))
+						return db.AuditEvent{}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res userResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &res)
+				require.NoError(t, err)
+				require.Equal(t, user.Username, res.Username)
+				require.Nil(t, res.LockedUntil)
+			},
+		},
+		{
+			name: "Teller",
+			role: util.TellerRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UnlockUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().UnlockUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/api/v1/admin/users/%s/unlock", user.Username)
+
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			username := util.RandomOwner()
+			dbtest.ExpectAuthorized(store, username)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, testCase.role, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"net/http"
+	"time"
+)
+
+// actions of the audit events
+const (
+	auditActionCreateUser           = "user.create"
+	auditActionLoginUser            = "user.login"
+	auditActionCreateAccount        = "account.create"
+	auditActionCreateTransfer       = "transfer.create"
+	auditActionLockUser             = "user.lock"
+	auditActionUnlockUser           = "user.unlock"
+	auditActionChangePassword       = "user.change_password"
+	auditActionRequestPasswordReset = "user.request_password_reset"
+	auditActionResetPassword        = "user.reset_password"
+	auditActionVerifyEmail          = "user.verify_email"
+	auditActionResendVerifyEmail    = "user.resend_verify_email"
+	auditActionUpdateUserRole       = "user.update_role"
+	auditActionRevokeUserSessions   = "user.revoke_sessions"
+	auditActionFreezeAccount        = "account.freeze"
+	auditActionUnfreezeAccount      = "account.unfreeze"
+	auditActionCloseAccount         = "account.close"
+	auditActionCreateDeposit        = "account.deposit"
+	auditActionCreateWithdrawal     = "account.withdraw"
+	auditActionReverseTransfer      = "transfer.reverse"
+	auditActionUpdateCurrency       = "currency.update"
+	auditActionLogoutUser           = "user.logout"
+	auditActionCreateWebhook        = "webhook.create"
+	auditActionDeleteWebhook        = "webhook.delete"
+	auditActionReplayWebhook        = "webhook.replay_delivery"
+)
+
+// actor of the requests made with a service token, it can't be a username as those are alphanumeric
+const auditActorService = "service-token"
+
+const (
+	auditOutcomeSuccess = "success"
+	auditOutcomeFailure = "failure"
+)
+
+const (
+	auditActorKey     = "audit_actor"
+	auditResourcesKey = "audit_resources"
+)
+
+// auditMiddleware records an audit event for the request after its handler has run
+// the response is already written then, so an event that can't be stored is only logged
+func auditMiddleware(store db.Store, action string) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		context.Next()
+
+		status := context.Writer.Status()
+
+		outcome := auditOutcomeSuccess
+		if status >= http.StatusBadRequest {
+			outcome = auditOutcomeFailure
+		}
+
+		recordAuditEvent(context, store, action, outcome, status)
+	}
+}
+
+// recordAuditEvent stores an audit event with the actor and resources of the request,
+// e.g. for an event a handler causes next to the one of its route, an event that can't be stored is only logged
+func recordAuditEvent(context *gin.Context, store db.Store, action string, outcome string, status int) {
+	logger := zerolog.Ctx(context.Request.Context())
+
+	resourceIDs, err := json.Marshal(auditResources(context))
+	if err != nil {
+		logger.Error().Err(err).Str("action", action).Msg("cannot encode the resources of audit event")
+		return
+	}
+
+	_, err = store.CreateAuditEvent(context, db.CreateAuditEventParams{
+		Actor:       auditActor(context),
+		Action:      action,
+		ResourceIds: resourceIDs,
+		ClientIp:    context.ClientIP(),
+		RequestID:   context.GetString(requestIDKey),
+		Outcome:     outcome,
+		StatusCode:  int32(status),
+	})
+
+	if err != nil {
+		logger.Error().Err(err).Str("action", action).Msg("cannot record audit event")
+	}
+}
+
+// setAuditActor names the actor of a request that isn't authenticated, e.g. the username of a login
+func setAuditActor(ctx *gin.Context, username string) {
+	ctx.Set(auditActorKey, username)
+}
+
+// setAuditResource adds the id of a resource the request created or used to its audit event
+func setAuditResource(ctx *gin.Context, name string, id any) {
+	resources := auditResources(ctx)
+	resources[name] = id
+
+	ctx.Set(auditResourcesKey, resources)
+}
+
+func auditActor(ctx *gin.Context) string {
+	if payload, ok := ctx.Get(authorizationPayloadKey); ok {
+		return payload.(*token.Payload).Username
+	}
+
+	return ctx.GetString(auditActorKey)
+}
+
+func auditResources(ctx *gin.Context) map[string]any {
+	if resources, ok := ctx.Get(auditResourcesKey); ok {
+		return resources.(map[string]any)
+	}
+
+	return map[string]any{}
+}
+
+type listAuditEventsRequest struct {
+	Actor    string    `form:"actor" binding:"omitempty,alphanum"`
+	Action   string    `form:"action" binding:"omitempty,max=64"`
+	From     time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To       time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	PageID   int32     `form:"page_id" binding:"required,min=1"`
+	PageSize int32     `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+// listAuditEvents returns the audit events that match the filters, the newest first
+func (server *Server) listAuditEvents(ctx *gin.Context) {
+	var req listAuditEventsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	events, err := server.store.ListAuditEvents(ctx, db.ListAuditEventsParams{
+		Actor:      sql.NullString{String: req.Actor, Valid: req.Actor != ""},
+		Action:     sql.NullString{String: req.Action, Valid: req.Action != ""},
+		FromTime:   sql.NullTime{Time: req.From, Valid: !req.From.IsZero()},
+		ToTime:     sql.NullTime{Time: req.To, Valid: !req.To.IsZero()},
+		PageLimit:  req.PageSize,
+		PageOffset: (req.PageID - 1) * req.PageSize,
+	})
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, events)
+}
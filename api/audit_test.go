@@ -0,0 +1,439 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/aybarsacar/simplebank/db/dbtest"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/token"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditMiddleware(t *testing.T) {
+	username := util.RandomOwner()
+	requestID := util.RandomString(16)
+
+	testCases := []struct {
+		name          string
+		authenticated bool
+		status        int
+		checkEvent    func(t *testing.T, event db.CreateAuditEventParams)
+	}{
+		{
+			name:          "AuthenticatedSuccess",
+			authenticated: true,
+			status:        http.StatusOK,
+			checkEvent: func(t *testing.T, event db.CreateAuditEventParams) {
+				require.Equal(t, username, event.Actor)
+				require.Equal(t, auditOutcomeSuccess, event.Outcome)
+				require.Equal(t, int32(http.StatusOK), event.StatusCode)
+			},
+		},
+		{
+			name:          "AnonymousFailure",
+			authenticated: false,
+			status:        http.StatusUnauthorized,
+			checkEvent: func(t *testing.T, event db.CreateAuditEventParams) {
+				// the handler named the actor of the request
+				require.Equal(t, "anonymous", event.Actor)
+				require.Equal(t, auditOutcomeFailure, event.Outcome)
+				require.Equal(t, int32(http.StatusUnauthorized), event.StatusCode)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+
+			var event db.CreateAuditEventParams
+			store.EXPECT().
+				CreateAuditEvent(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ context.Context, args db.CreateAuditEventParams) (db.AuditEvent, error) {
+					event = args
+					return db.AuditEvent{}, nil
+				})
+
+			server := newTestServer(t, store)
+
+			handlers := []gin.HandlerFunc{auditMiddleware(server.store, auditActionCreateAccount)}
+			if testCase.authenticated {
+				handlers = append([]gin.HandlerFunc{authMiddleware(server.tokenMaker, server.denylist)}, handlers...)
+			}
+
+			handlers = append(handlers, func(ctx *gin.Context) {
+				setAuditActor(ctx, "anonymous")
+				setAuditResource(ctx, "account_id", 7)
+				ctx.JSON(testCase.status, gin.H{})
+			})
+
+			auditPath := "/audit"
+			server.router.POST(auditPath, handlers...)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, auditPath, nil)
+			require.NoError(t, err)
+
+			request.Header.Set(requestIDHeaderKey, requestID)
+			request.RemoteAddr = "10.0.0.1:51234"
+
+			if testCase.authenticated {
+				dbtest.ExpectAuthorized(store, username)
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, util.CustomerRole, time.Minute)
+			}
+
+			server.router.ServeHTTP(recorder, request)
+
+			require.Equal(t, testCase.status, recorder.Code)
+			require.Equal(t, requestID, recorder.Header().Get(requestIDHeaderKey))
+
+			require.Equal(t, auditActionCreateAccount, event.Action)
+			require.Equal(t, requestID, event.RequestID)
+			require.Equal(t, "10.0.0.1", event.ClientIp)
+			require.JSONEq(t, `{"account_id": 7}`, string(event.ResourceIds))
+
+			testCase.checkEvent(t, event)
+		})
+	}
+}
+
+func TestPrivilegedRoutesAudit(t *testing.T) {
+	admin := util.RandomOwner()
+	username := util.RandomOwner()
+
+	testCases := []struct {
+		name       string
+		method     string
+		url        string
+		body       string
+		setupAuth  func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs func(store *mockdb.MockStore)
+		action     string
+		actor      string
+		resources  string
+	}{
+		{
+			name:   "UpdateUserRole",
+			method: http.MethodPut,
+			url:    fmt.Sprintf("/api/v1/admin/users/%s/role", username),
+			body:   `{}`,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin, util.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuthorized(store, admin)
+			},
+			action:    auditActionUpdateUserRole,
+			actor:     admin,
+			resources: fmt.Sprintf(`{"username": %q}`, username),
+		},
+		{
+			name:   "RevokeUserSessions",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/api/v1/admin/users/%s/revoke_sessions", username),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin, util.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuthorized(store, admin)
+
+				store.EXPECT().RevokeUserSessionsTx(gomock.Any(), gomock.Eq(username)).Times(1).Return(db.RevokeUserSessionsTxResult{User: db.User{Username: username}}, nil)
+			},
+			action:    auditActionRevokeUserSessions,
+			actor:     admin,
+			resources: fmt.Sprintf(`{"username": %q}`, username),
+		},
+		{
+			name:   "FreezeAccount",
+			method: http.MethodPost,
+			url:    "/api/v1/admin/accounts/7/freeze",
+			body:   `{}`,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin, util.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuthorized(store, admin)
+			},
+			action:    auditActionFreezeAccount,
+			actor:     admin,
+			resources: `{"account_id": 7}`,
+		},
+		{
+			name:   "UnfreezeAccount",
+			method: http.MethodPost,
+			url:    "/api/v1/admin/accounts/7/unfreeze",
+			body:   `{}`,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin, util.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuthorized(store, admin)
+			},
+			action:    auditActionUnfreezeAccount,
+			actor:     admin,
+			resources: `{"account_id": 7}`,
+		},
+		{
+			name:   "CloseAccount",
+			method: http.MethodPost,
+			url:    "/api/v1/admin/accounts/7/close",
+			body:   `{}`,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin, util.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuthorized(store, admin)
+			},
+			action:    auditActionCloseAccount,
+			actor:     admin,
+			resources: `{"account_id": 7}`,
+		},
+		{
+			name:   "UpdateCurrency",
+			method: http.MethodPatch,
+			url:    "/api/v1/admin/currencies/USD",
+			body:   `{}`,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin, util.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuthorized(store, admin)
+			},
+			action:    auditActionUpdateCurrency,
+			actor:     admin,
+			resources: `{"currency": "USD"}`,
+		},
+		{
+			name:   "ReverseTransfer",
+			method: http.MethodPost,
+			url:    "/api/v1/transfers/7/reverse",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin, util.TellerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuthorized(store, admin)
+
+				store.EXPECT().ReverseTransferTx(gomock.Any(), gomock.Eq(int64(7))).Times(1).Return(db.TransferTxResult{}, sql.ErrNoRows)
+			},
+			action:    auditActionReverseTransfer,
+			actor:     admin,
+			resources: `{"transfer_id": 7}`,
+		},
+		{
+			name:   "CreateDeposit",
+			method: http.MethodPost,
+			url:    "/api/v1/accounts/7/deposits",
+			body:   `{}`,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeService, testServiceToken))
+			},
+			buildStubs: func(store *mockdb.MockStore) {},
+			action:     auditActionCreateDeposit,
+			actor:      auditActorService,
+			resources:  `{"account_id": 7}`,
+		},
+		{
+			name:   "CreateWithdrawal",
+			method: http.MethodPost,
+			url:    "/api/v1/accounts/7/withdrawals",
+			body:   `{}`,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin, util.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuthorized(store, admin)
+			},
+			action:    auditActionCreateWithdrawal,
+			actor:     admin,
+			resources: `{"account_id": 7}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			testCase.buildStubs(store)
+
+			var event db.CreateAuditEventParams
+			store.EXPECT().
+				CreateAuditEvent(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ context.Context, args db.CreateAuditEventParams) (db.AuditEvent, error) {
+					event = args
+					return db.AuditEvent{}, nil
+				})
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(testCase.method, testCase.url, strings.NewReader(testCase.body))
+			require.NoError(t, err)
+
+			testCase.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+
+			require.Equal(t, testCase.action, event.Action)
+			require.Equal(t, testCase.actor, event.Actor)
+			require.Equal(t, int32(recorder.Code), event.StatusCode)
+			require.JSONEq(t, testCase.resources, string(event.ResourceIds))
+		})
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(controller))
+
+	var requestID string
+	server.router.GET("/request_id", func(ctx *gin.Context) {
+		requestID = ctx.GetString(requestIDKey)
+		ctx.JSON(http.StatusOK, gin.H{})
+	})
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/request_id", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+
+	// a new id is generated when the client doesn't send one
+	require.NotEmpty(t, requestID)
+	require.Equal(t, requestID, recorder.Header().Get(requestIDHeaderKey))
+}
+
+func TestListAuditEventsAPI(t *testing.T) {
+	actor := util.RandomOwner()
+
+	events := []db.AuditEvent{
+		{
+			ID:          2,
+			Actor:       actor,
+			Action:      auditActionCreateTransfer,
+			ResourceIds: json.RawMessage(`{"transfer_id":1}`),
+			Outcome:     auditOutcomeSuccess,
+			StatusCode:  http.StatusOK,
+		},
+		{
+			ID:          1,
+			Actor:       actor,
+			Action:      auditActionLoginUser,
+			ResourceIds: json.RawMessage(`{}`),
+			Outcome:     auditOutcomeFailure,
+			StatusCode:  http.StatusUnauthorized,
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		query         url.Values
+		role          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: url.Values{"actor": {actor}, "page_id": {"2"}, "page_size": {"10"}},
+			role:  util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				args := db.ListAuditEventsParams{
+					Actor:      sql.NullString{String: actor, Valid: true},
+					PageLimit:  10,
+					PageOffset: 10,
+				}
+
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Eq(args)).Times(1).Return(events, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res []db.AuditEvent
+				err := json.Unmarshal(recorder.Body.Bytes(), &res)
+				require.NoError(t, err)
+				require.Equal(t, events, res)
+			},
+		},
+		{
+			name:  "Customer",
+			query: url.Values{"page_id": {"1"}, "page_size": {"10"}},
+			role:  util.CustomerRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidPageSize",
+			query: url.Values{"page_id": {"1"}, "page_size": {"1000"}},
+			role:  util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: url.Values{"page_id": {"1"}, "page_size": {"10"}},
+			role:  util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/api/v1/admin/audit_events?"+testCase.query.Encode(), nil)
+			require.NoError(t, err)
+
+			username := util.RandomOwner()
+			dbtest.ExpectAuthorized(store, username)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, testCase.role, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
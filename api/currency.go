@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"github.com/aybarsacar/simplebank/bank"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"time"
+)
+
+// used when CURRENCY_REFRESH_INTERVAL is not configured
+const defaultCurrencyRefreshInterval = 30 * time.Second
+
+// RefreshCurrencies reloads the currency registry from the database every refresh interval until the context is cancelled,
+// the registry is shared by the HTTP and the gRPC servers of the process
+func (server *Server) RefreshCurrencies(ctx context.Context) {
+	ticker := time.NewTicker(durationOrDefault(server.config.CurrencyRefreshInterval, defaultCurrencyRefreshInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// the registry loaded before is kept until the database is back
+			if err := bank.LoadCurrencies(ctx, server.store); err != nil {
+				log.Error().Err(err).Msg("cannot refresh the currencies")
+			}
+		}
+	}
+}
+
+// listCurrencies returns the currencies that can be used for new accounts and incoming transfers
+func (server *Server) listCurrencies(ctx *gin.Context) {
+	currencies := make([]util.Currency, 0)
+	for _, currency := range util.Currencies.List() {
+		if currency.Enabled {
+			currencies = append(currencies, currency)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, currencies)
+}
+
+type updateCurrencyURI struct {
+	Code string `uri:"code" binding:"required,len=3,alpha"`
+}
+
+type updateCurrencyRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// updateCurrency enables or disables a currency
+// accounts in a disabled currency keep their balance and money can still leave them,
+// but no new account can be opened in it and no transfer can go into it
+func (server *Server) updateCurrency(ctx *gin.Context) {
+	var uri updateCurrencyURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	setAuditResource(ctx, "currency", uri.Code)
+
+	var req updateCurrencyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	currency, err := server.store.UpdateCurrencyEnabled(ctx, db.UpdateCurrencyEnabledParams{
+		Enabled: *req.Enabled,
+		Code:    uri.Code,
+	})
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	registryCurrency := bank.RegistryCurrency(currency)
+	util.Currencies.Set(registryCurrency)
+
+	ctx.JSON(http.StatusOK, registryCurrency)
+}
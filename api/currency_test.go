@@ -0,0 +1,253 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/aybarsacar/simplebank/bank"
+	"github.com/aybarsacar/simplebank/db/dbtest"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestUpdateCurrencyAPI(t *testing.T) {
+	disabled := db.Currency{Code: util.JPY, MinorUnits: 0, Symbol: "¥", Enabled: false}
+
+	testCases := []struct {
+		name          string
+		code          string
+		body          gin.H
+		role          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			code: util.JPY,
+			body: gin.H{"enabled": false},
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				args := db.UpdateCurrencyEnabledParams{Enabled: false, Code: util.JPY}
+
+				store.EXPECT().
+					UpdateCurrencyEnabled(gomock.Any(), gomock.Eq(args)).
+					Times(1).
+					Return(disabled, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var currency util.Currency
+				err := json.Unmarshal(recorder.Body.Bytes(), &currency)
+				require.NoError(t, err)
+				require.Equal(t, util.JPY, currency.Code)
+				require.False(t, currency.Enabled)
+
+				// the registry is updated right away
+				require.False(t, util.IsSupportedCurrency(util.JPY))
+			},
+		},
+		{
+			name: "NotFound",
+			code: "XYZ",
+			body: gin.H{"enabled": true},
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().
+					UpdateCurrencyEnabled(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Currency{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "MissingEnabled",
+			code: util.JPY,
+			body: gin.H{},
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().UpdateCurrencyEnabled(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCode",
+			code: "dollars",
+			body: gin.H{"enabled": true},
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().UpdateCurrencyEnabled(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NotAdmin",
+			code: util.JPY,
+			body: gin.H{"enabled": false},
+			role: util.CustomerRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateCurrencyEnabled(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			code: util.JPY,
+			body: gin.H{"enabled": false},
+			role: util.AdminRole,
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().
+					UpdateCurrencyEnabled(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Currency{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(testCase.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/api/v1/admin/currencies/%s", testCase.code)
+			request, err := http.NewRequest(http.MethodPatch, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			username := util.RandomOwner()
+			dbtest.ExpectAuthorized(store, username)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, testCase.role, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListCurrenciesAPI(t *testing.T) {
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	store := mockdb.NewMockStore(controller)
+
+	currencies := dbtest.DefaultCurrencies()
+	for i := range currencies {
+		if currencies[i].Code == util.JPY {
+			currencies[i].Enabled = false
+		}
+	}
+
+	server := newTestServer(t, store)
+
+	// JPY is disabled after the server started, the listing follows the registry once it is refreshed
+	store.EXPECT().ListCurrencies(gomock.Any()).Times(1).Return(currencies, nil)
+
+	defer util.Currencies.Load(util.DefaultCurrencies)
+
+	err := bank.LoadCurrencies(context.Background(), server.store)
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/api/v1/currencies", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var got []util.Currency
+	err = json.Unmarshal(recorder.Body.Bytes(), &got)
+	require.NoError(t, err)
+
+	// disabled currencies are not listed
+	require.Len(t, got, len(currencies)-1)
+	for _, currency := range got {
+		require.NotEqual(t, util.JPY, currency.Code)
+		require.True(t, currency.Enabled)
+	}
+}
+
+func TestRefreshCurrencies(t *testing.T) {
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	store := mockdb.NewMockStore(controller)
+
+	// JPY is disabled on another server after this one started
+	refreshed := dbtest.DefaultCurrencies()
+	for i := range refreshed {
+		if refreshed[i].Code == util.JPY {
+			refreshed[i].Enabled = false
+		}
+	}
+
+	server := newTestServer(t, store)
+
+	store.EXPECT().ListCurrencies(gomock.Any()).MinTimes(1).Return(refreshed, nil)
+	server.config.CurrencyRefreshInterval = 10 * time.Millisecond
+
+	defer util.Currencies.Load(util.DefaultCurrencies)
+
+	require.True(t, util.IsSupportedCurrency(util.JPY))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		server.RefreshCurrencies(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		return !util.IsSupportedCurrency(util.JPY)
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
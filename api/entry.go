@@ -0,0 +1,179 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/token"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+const (
+	directionCredit = "credit"
+	directionDebit  = "debit"
+)
+
+type listAccountEntriesURI struct {
+	AccountID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type listAccountEntriesQuery struct {
+	// PageID selects offset pagination, without it the statement is paginated with cursors
+	PageID    int32     `form:"page_id" binding:"omitempty,min=1"`
+	PageSize  int32     `form:"page_size" binding:"required,min=5,max=20"`
+	Cursor    string    `form:"cursor"`
+	From      time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To        time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Direction string    `form:"direction" binding:"omitempty,oneof=credit debit"`
+}
+
+// statementLine is a single entry of the account statement
+type statementLine struct {
+	ID                int64      `json:"id"`
+	Amount            int64      `json:"amount"`
+	AmountMoney       util.Money `json:"amount_money"`
+	Direction         string     `json:"direction"`
+	BalanceAfter      int64      `json:"balance_after"`
+	BalanceAfterMoney util.Money `json:"balance_after_money"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+type accountStatementResponse struct {
+	Account    accountResponse `json:"account"`
+	Entries    []statementLine `json:"entries"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	PrevCursor string          `json:"prev_cursor,omitempty"`
+}
+
+// listAccountEntries returns the statement of an account, each line shows the balance after that entry
+func (server *Server) listAccountEntries(ctx *gin.Context) {
+	var uri listAccountEntriesURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req listAccountEntriesQuery
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !req.From.IsZero() && !req.To.IsZero() && !req.From.Before(req.To) {
+		err := errors.New("from must be before to")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	cursor, err := parsePagination(req.PageID, req.Cursor)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, err := server.store.GetAccount(ctx, uri.AccountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// get the token payload from the middleware
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	if !canReadAccount(authPayload, account) {
+		err := errors.New("account does not belong to the user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	fromTime := sql.NullTime{Time: req.From, Valid: !req.From.IsZero()}
+	toTime := sql.NullTime{Time: req.To, Valid: !req.To.IsZero()}
+	directionFilter := sql.NullString{String: req.Direction, Valid: req.Direction != ""}
+
+	res := accountStatementResponse{Account: newAccountResponse(account)}
+
+	var rows []db.Entry
+
+	if req.PageID > 0 {
+		args := db.ListAccountStatementParams{
+			AccountID:  account.ID,
+			FromTime:   fromTime,
+			ToTime:     toTime,
+			Direction:  directionFilter,
+			PageLimit:  req.PageSize,
+			PageOffset: (req.PageID - 1) * req.PageSize,
+		}
+
+		rows, err = server.store.ListAccountStatement(ctx, args)
+	} else {
+		var page keysetPage[db.Entry]
+
+		page, err = fetchKeysetPage(
+			cursor,
+			req.PageSize,
+			func(createdAt time.Time, id int64, limit int32) ([]db.Entry, error) {
+				return server.store.ListAccountStatementAfter(ctx, db.ListAccountStatementAfterParams{
+					AccountID:       account.ID,
+					FromTime:        fromTime,
+					ToTime:          toTime,
+					Direction:       directionFilter,
+					CursorCreatedAt: createdAt,
+					CursorID:        id,
+					PageLimit:       limit,
+				})
+			},
+			func(createdAt time.Time, id int64, limit int32) ([]db.Entry, error) {
+				return server.store.ListAccountStatementBefore(ctx, db.ListAccountStatementBeforeParams{
+					AccountID:       account.ID,
+					FromTime:        fromTime,
+					ToTime:          toTime,
+					Direction:       directionFilter,
+					CursorCreatedAt: createdAt,
+					CursorID:        id,
+					PageLimit:       limit,
+				})
+			},
+			func(row db.Entry) (time.Time, int64) {
+				return row.CreatedAt, row.ID
+			},
+		)
+
+		rows = page.Items
+		res.NextCursor = page.NextCursor
+		res.PrevCursor = page.PrevCursor
+	}
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	res.Entries = make([]statementLine, 0, len(rows))
+
+	for _, row := range rows {
+		direction := directionCredit
+		if row.Amount < 0 {
+			direction = directionDebit
+		}
+
+		res.Entries = append(res.Entries, statementLine{
+			ID:                row.ID,
+			Amount:            row.Amount,
+			AmountMoney:       util.Money{Amount: row.Amount, Currency: account.Currency},
+			Direction:         direction,
+			BalanceAfter:      row.BalanceAfter,
+			BalanceAfterMoney: util.Money{Amount: row.BalanceAfter, Currency: account.Currency},
+			CreatedAt:         row.CreatedAt,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
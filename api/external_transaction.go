@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"errors"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type externalTransactionURI struct {
+	AccountID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type externalTransactionRequest struct {
+	// Amount is in minor units, e.g. cents, clients can send the decimal AmountValue instead
+	Amount      int64  `json:"amount" binding:"omitempty,gt=0"`
+	AmountValue string `json:"amount_value" binding:"omitempty,max=32"`
+	Currency    string `json:"currency" binding:"required,currency"`
+	// Source is the integration that moved the money, e.g. card_processor
+	Source string `json:"source" binding:"required,max=64"`
+	// ExternalReference is the id of the transaction in the source system, a source can post it only once
+	ExternalReference string `json:"external_reference" binding:"required,max=255"`
+}
+
+// createDeposit adds money coming from a cash-in integration to an account
+func (server *Server) createDeposit(ctx *gin.Context) {
+	server.createExternalTransaction(ctx, server.store.DepositTx)
+}
+
+// createWithdrawal takes money out of an account for a cash-out integration
+func (server *Server) createWithdrawal(ctx *gin.Context) {
+	server.createExternalTransaction(ctx, server.store.WithdrawTx)
+}
+
+func (server *Server) createExternalTransaction(
+	ctx *gin.Context,
+	execTx func(ctx context.Context, args db.ExternalTxParams) (db.ExternalTxResult, error),
+) {
+	var uri externalTransactionURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	setAuditResource(ctx, "account_id", uri.AccountID)
+
+	var req externalTransactionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	amount, err := requestAmount(req.Amount, req.AmountValue, req.Currency)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, isValid := server.validAccount(ctx, uri.AccountID, req.Currency); !isValid {
+		return
+	}
+
+	result, err := execTx(ctx, db.ExternalTxParams{
+		AccountID:         uri.AccountID,
+		Amount:            amount,
+		Source:            req.Source,
+		ExternalReference: req.ExternalReference,
+	})
+
+	if err != nil {
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeInsufficientFunds, err))
+			return
+		}
+
+		if errors.Is(err, db.ErrAccountFrozen) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeAccountFrozen, err))
+			return
+		}
+
+		if errors.Is(err, db.ErrAccountClosed) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeAccountClosed, err))
+			return
+		}
+
+		if errors.Is(err, db.ErrDuplicateExternalReference) {
+			ctx.JSON(http.StatusConflict, errorCodeResponse(errCodeDuplicateExternalReference, err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	setAuditResource(ctx, "external_transaction_id", result.ExternalTransaction.ID)
+
+	ctx.JSON(http.StatusOK, externalTxResponse{
+		ExternalTxResult: result,
+		AmountMoney:      util.Money{Amount: result.ExternalTransaction.Amount, Currency: req.Currency},
+	})
+}
+
+// externalTxResponse is the result of a deposit or withdrawal with its amount also given as a decimal amount
+type externalTxResponse struct {
+	db.ExternalTxResult
+	AmountMoney util.Money `json:"amount_money"`
+}
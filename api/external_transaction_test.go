@@ -0,0 +1,365 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/aybarsacar/simplebank/db/dbtest"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/token"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCreateDepositAPI(t *testing.T) {
+	account := randomAccount(util.RandomOwner())
+	account.Currency = util.USD
+	admin := util.RandomOwner()
+
+	amount := int64(100)
+	reference := util.RandomString(12)
+
+	args := db.ExternalTxParams{
+		AccountID:         account.ID,
+		Amount:            amount,
+		Source:            "card_processor",
+		ExternalReference: reference,
+	}
+
+	body := gin.H{
+		"amount":             amount,
+		"currency":           util.USD,
+		"source":             "card_processor",
+		"external_reference": reference,
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: body,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeService, testServiceToken))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+				store.EXPECT().
+					DepositTx(gomock.Any(), gomock.Eq(args)).
+					Times(1).
+					Return(randomExternalTxResult(account, db.ExternalTransactionDeposit, args), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var result db.ExternalTxResult
+				err := json.Unmarshal(recorder.Body.Bytes(), &result)
+				require.NoError(t, err)
+
+				require.Equal(t, db.ExternalTransactionDeposit, result.ExternalTransaction.Kind)
+				require.Equal(t, reference, result.ExternalTransaction.ExternalReference)
+				require.Equal(t, account.Balance+amount, result.Account.Balance)
+			},
+		},
+		{
+			name: "AdminUser",
+			body: body,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin, util.AdminRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuthorized(store, admin)
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+				store.EXPECT().
+					DepositTx(gomock.Any(), gomock.Eq(args)).
+					Times(1).
+					Return(randomExternalTxResult(account, db.ExternalTransactionDeposit, args), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "AccountOwner",
+			body: body,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuthorized(store, account.Owner)
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DepositTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "AccountNotFound",
+			body: body,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeService, testServiceToken))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().DepositTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			body: gin.H{
+				"amount":             amount,
+				"currency":           util.EUR,
+				"source":             "card_processor",
+				"external_reference": reference,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeService, testServiceToken))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().DepositTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "MissingExternalReference",
+			body: gin.H{
+				"amount":   amount,
+				"currency": util.USD,
+				"source":   "card_processor",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeService, testServiceToken))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().DepositTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "DuplicateExternalReference",
+			body: body,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeService, testServiceToken))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+				store.EXPECT().
+					DepositTx(gomock.Any(), gomock.Eq(args)).
+					Times(1).
+					Return(db.ExternalTxResult{}, db.ErrDuplicateExternalReference)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+				requireBodyMatchErrorCode(t, recorder.Body, errCodeDuplicateExternalReference)
+			},
+		},
+		{
+			name: "InternalError",
+			body: body,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeService, testServiceToken))
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+				store.EXPECT().
+					DepositTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ExternalTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(testCase.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/api/v1/accounts/%d/deposits", account.ID)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			testCase.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreateWithdrawalAPI(t *testing.T) {
+	account := randomAccount(util.RandomOwner())
+	account.Currency = util.USD
+	account.Balance = 1000
+
+	args := db.ExternalTxParams{
+		AccountID:         account.ID,
+		Amount:            100,
+		Source:            "atm_network",
+		ExternalReference: util.RandomString(12),
+	}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+				store.EXPECT().
+					WithdrawTx(gomock.Any(), gomock.Eq(args)).
+					Times(1).
+					Return(randomExternalTxResult(account, db.ExternalTransactionWithdrawal, args), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var result db.ExternalTxResult
+				err := json.Unmarshal(recorder.Body.Bytes(), &result)
+				require.NoError(t, err)
+
+				require.Equal(t, db.ExternalTransactionWithdrawal, result.ExternalTransaction.Kind)
+				require.Equal(t, -args.Amount, result.Entry.Amount)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+				store.EXPECT().
+					WithdrawTx(gomock.Any(), gomock.Eq(args)).
+					Times(1).
+					Return(db.ExternalTxResult{}, db.ErrInsufficientFunds)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				requireBodyMatchErrorCode(t, recorder.Body, errCodeInsufficientFunds)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{
+				"amount":             args.Amount,
+				"currency":           util.USD,
+				"source":             args.Source,
+				"external_reference": args.ExternalReference,
+			})
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/api/v1/accounts/%d/withdrawals", account.ID)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeService, testServiceToken))
+			server.router.ServeHTTP(recorder, request)
+
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func randomExternalTxResult(account db.Account, kind string, args db.ExternalTxParams) db.ExternalTxResult {
+	entryAmount := args.Amount
+	if kind == db.ExternalTransactionWithdrawal {
+		entryAmount = -args.Amount
+	}
+
+	entry := db.Entry{
+		ID:        util.RandomInt(1, 1000),
+		AccountID: account.ID,
+		Amount:    entryAmount,
+	}
+
+	account.Balance += entryAmount
+
+	return db.ExternalTxResult{
+		ExternalTransaction: db.ExternalTransaction{
+			ID:                util.RandomInt(1, 1000),
+			AccountID:         account.ID,
+			EntryID:           entry.ID,
+			Kind:              kind,
+			Amount:            args.Amount,
+			Source:            args.Source,
+			ExternalReference: args.ExternalReference,
+		},
+		Account: account,
+		Entry:   entry,
+	}
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// the readiness probe fails instead of hanging when the database doesn't answer in time
+const readinessCheckTimeout = 2 * time.Second
+
+const (
+	healthStatusOK       = "ok"
+	healthStatusReady    = "ready"
+	healthStatusNotReady = "not ready"
+)
+
+var errMigrationsNotRun = errors.New("database migrations have not run")
+
+// MigrationSource reports the schema version of the database, *migrate.Migrate implements it
+type MigrationSource interface {
+	Version() (version uint, dirty bool, err error)
+}
+
+// MarkReady lets the readiness probe pass, call it once the database migrations have run
+func (server *Server) MarkReady(migrations MigrationSource) {
+	server.readinessMutex.Lock()
+	defer server.readinessMutex.Unlock()
+
+	server.migrations = migrations
+}
+
+type healthResponse struct {
+	Status string `json:"status"`
+}
+
+// healthz is the liveness probe, it only tells the process can serve requests
+func (server *Server) healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, healthResponse{Status: healthStatusOK})
+}
+
+type readyzResponse struct {
+	Status           string `json:"status"`
+	MigrationVersion uint   `json:"migration_version"`
+	MigrationDirty   bool   `json:"migration_dirty"`
+	Error            string `json:"error,omitempty"`
+}
+
+// readyz is the readiness probe, it passes once the migrations have run and while the database can be reached
+// a dirty migration fails it, the schema is then half migrated and must be fixed by hand
+func (server *Server) readyz(ctx *gin.Context) {
+	server.readinessMutex.RLock()
+	migrations := server.migrations
+	server.readinessMutex.RUnlock()
+
+	if migrations == nil {
+		ctx.JSON(http.StatusServiceUnavailable, readyzResponse{Status: healthStatusNotReady, Error: errMigrationsNotRun.Error()})
+		return
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	defer cancel()
+
+	if err := server.store.Ping(checkCtx); err != nil {
+		err = fmt.Errorf("cannot reach the database: %w", err)
+		ctx.JSON(http.StatusServiceUnavailable, readyzResponse{Status: healthStatusNotReady, Error: err.Error()})
+		return
+	}
+
+	version, dirty, err := migrations.Version()
+	if err != nil {
+		err = fmt.Errorf("cannot read the migration version: %w", err)
+		ctx.JSON(http.StatusServiceUnavailable, readyzResponse{Status: healthStatusNotReady, Error: err.Error()})
+		return
+	}
+
+	res := readyzResponse{
+		Status:           healthStatusReady,
+		MigrationVersion: version,
+		MigrationDirty:   dirty,
+	}
+
+	if dirty {
+		res.Status = healthStatusNotReady
+		res.Error = fmt.Sprintf("migration %d is dirty", version)
+		ctx.JSON(http.StatusServiceUnavailable, res)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
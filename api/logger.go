@@ -0,0 +1,63 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"time"
+)
+
+// withRequestLogger puts a logger that carries the request id into the context of the request,
+// so the handlers and the store log with it
+// the router falls back to the request context, so zerolog.Ctx finds it in the gin context too
+func withRequestLogger(context *gin.Context, requestID string) {
+	loggerContext := log.With().Str("request_id", requestID)
+
+	// the trace id links the log lines to the spans of the request when tracing is enabled
+	if spanContext := trace.SpanContextFromContext(context.Request.Context()); spanContext.HasTraceID() {
+		loggerContext = loggerContext.Str("trace_id", spanContext.TraceID().String())
+	}
+
+	logger := loggerContext.Logger()
+	context.Request = context.Request.WithContext(logger.WithContext(context.Request.Context()))
+}
+
+// withUsername adds the authenticated user to the logger of the request
+func withUsername(context *gin.Context, username string) {
+	zerolog.Ctx(context.Request.Context()).UpdateContext(func(c zerolog.Context) zerolog.Context {
+		return c.Str("username", username)
+	})
+}
+
+// loggerMiddleware writes a structured access log line for every request once it has been handled
+// it must run after the requestIDMiddleware, so the line carries the request id,
+// and the username of the authenticated requests is added to the logger by authenticate
+func loggerMiddleware() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		startTime := time.Now()
+
+		context.Next()
+
+		status := context.Writer.Status()
+		logger := zerolog.Ctx(context.Request.Context())
+
+		event := logger.Info()
+		if status >= http.StatusInternalServerError {
+			event = logger.Error()
+		}
+
+		event.
+			Str("method", context.Request.Method).
+			Str("path", context.Request.URL.Path).
+			Str("route", context.FullPath()).
+			Int("status", status).
+			Dur("duration", time.Since(startTime)).
+			Int("size", context.Writer.Size()).
+			Str("client_ip", context.ClientIP()).
+			Str("user_agent", context.Request.UserAgent())
+
+		event.Msg("http request")
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aybarsacar/simplebank/db/dbtest"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// captureLogs makes the global logger write to a buffer until the test ends
+func captureLogs(t *testing.T) *bytes.Buffer {
+	var buffer bytes.Buffer
+
+	logger := log.Logger
+	log.Logger = zerolog.New(&buffer)
+	t.Cleanup(func() { log.Logger = logger })
+
+	return &buffer
+}
+
+// logLines decodes the JSON lines of the buffer by their message
+func logLines(t *testing.T, buffer *bytes.Buffer) map[string]map[string]any {
+	lines := map[string]map[string]any{}
+
+	decoder := json.NewDecoder(buffer)
+	for decoder.More() {
+		var line map[string]any
+		require.NoError(t, decoder.Decode(&line))
+
+		lines[line["message"].(string)] = line
+	}
+
+	return lines
+}
+
+func TestLoggerMiddleware(t *testing.T) {
+	account := randomAccount(util.RandomOwner())
+
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	store := mockdb.NewMockStore(controller)
+
+	// the store logs with the logger it finds in the context, e.g. when a transaction is rolled back
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, id int64) (db.Account, error) {
+			zerolog.Ctx(ctx).Info().Msg("store")
+			return account, nil
+		})
+
+	logs := captureLogs(t)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/accounts/%d", account.ID), nil)
+	require.NoError(t, err)
+
+	request.Header.Set(requestIDHeaderKey, "test-request-id")
+	dbtest.ExpectAuthorized(store, account.Owner)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+
+	server.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	lines := logLines(t, logs)
+
+	accessLog, ok := lines["http request"]
+	require.True(t, ok)
+	require.Equal(t, "test-request-id", accessLog["request_id"])
+	require.Equal(t, account.Owner, accessLog["username"])
+	require.Equal(t, http.MethodGet, accessLog["method"])
+	require.Equal(t, "/api/v1/accounts/:id", accessLog["route"])
+	require.Equal(t, float64(http.StatusOK), accessLog["status"])
+
+	storeLog, ok := lines["store"]
+	require.True(t, ok)
+	require.Equal(t, "test-request-id", storeLog["request_id"])
+	require.Equal(t, account.Owner, storeLog["username"])
+}
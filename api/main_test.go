@@ -1,9 +1,13 @@
 package api
 
 import (
-	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/db/dbtest"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	"github.com/aybarsacar/simplebank/token"
 	"github.com/aybarsacar/simplebank/util"
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 	"github.com/stretchr/testify/require"
 	"os"
 	"testing"
@@ -13,14 +17,21 @@ import (
 // configure Gin to run in test mode
 // default is running in debug mode
 
-func newTestServer(t *testing.T, store db.Store) *Server {
+var testServiceToken = util.RandomString(32)
+
+func newTestServer(t *testing.T, store *mockdb.MockStore) *Server {
 
 	config := util.Config{
-		TokenSymmetricKey:   util.RandomString(32),
-		AccessTokenDuration: time.Minute,
+		TokenSymmetricKey:    util.RandomString(32),
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
+		ServiceTokens:        []string{testServiceToken},
 	}
 
-	server, err := NewServer(config, store)
+	// a new server loads the currencies once
+	dbtest.ExpectLoadCurrencies(store)
+
+	server, err := NewServer(config, store, token.NewDenylist(store))
 	require.NoError(t, err)
 
 	return server
@@ -31,6 +42,9 @@ func TestMain(m *testing.M) {
 
 	gin.SetMode(gin.TestMode)
 
+	// the tests that check the logs capture them, the others don't write access logs
+	log.Logger = zerolog.Nop()
+
 	// start running the unit tests
 	os.Exit(m.Run())
 }
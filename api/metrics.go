@@ -0,0 +1,26 @@
+package api
+
+import (
+	"github.com/aybarsacar/simplebank/metrics"
+	"github.com/gin-gonic/gin"
+	"time"
+)
+
+// label of the requests that didn't match any route, e.g. the 404s
+const unmatchedRoute = "unmatched"
+
+// metricsMiddleware records the duration of every request by its route template
+func metricsMiddleware() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		startTime := time.Now()
+
+		context.Next()
+
+		route := context.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		metrics.ObserveHTTPRequest(context.Request.Method, route, context.Writer.Status(), time.Since(startTime))
+	}
+}
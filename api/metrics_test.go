@@ -0,0 +1,61 @@
+package api
+
+import (
+	"fmt"
+	"github.com/aybarsacar/simplebank/db/dbtest"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMetricsAPI(t *testing.T) {
+	account := randomAccount(util.RandomOwner())
+
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	store := mockdb.NewMockStore(controller)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+	server := newTestServer(t, store)
+
+	serve := func(request *http.Request) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/accounts/%d", account.ID), nil)
+	require.NoError(t, err)
+
+	dbtest.ExpectAuthorized(store, account.Owner)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+	require.Equal(t, http.StatusOK, serve(request).Code)
+
+	request, err = http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/accounts/%d", account.ID), nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, -time.Minute)
+	require.Equal(t, http.StatusUnauthorized, serve(request).Code)
+
+	request, err = http.NewRequest(http.MethodGet, "/metrics", nil)
+	require.NoError(t, err)
+
+	recorder := serve(request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	body := recorder.Body.String()
+
+	// the requests are labeled with the route template, not the path with the account id
+	require.Contains(t, body, `simple_bank_http_request_duration_seconds_count{method="GET",route="/api/v1/accounts/:id",status="200"}`)
+	require.Contains(t, body, `simple_bank_http_request_duration_seconds_count{method="GET",route="/api/v1/accounts/:id",status="401"}`)
+	require.NotContains(t, body, fmt.Sprintf("/api/v1/accounts/%d", account.ID))
+	require.Contains(t, body, `simple_bank_token_verification_failures_total{reason="expired"}`)
+}
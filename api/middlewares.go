@@ -1,57 +1,170 @@
 package api
 
 import (
+	"crypto/subtle"
 	"errors"
+	"github.com/aybarsacar/simplebank/metrics"
 	"github.com/aybarsacar/simplebank/token"
+	"github.com/aybarsacar/simplebank/util"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"strings"
 )
 
 const (
-	authorizationHeaderKey  = "authorization"
-	authorizationTypeBearer = "bearer"
-	authorizationPayloadKey = "authorization_payload"
+	authorizationHeaderKey   = "authorization"
+	authorizationTypeBearer  = "bearer"
+	authorizationTypeService = "service"
+	authorizationPayloadKey  = "authorization_payload"
 )
 
 // higher order function that will return the authentication middleware function
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+func authMiddleware(tokenMaker token.Maker, denylist *token.Denylist) gin.HandlerFunc {
 	// this is the actual authentication middleware
 	return func(context *gin.Context) {
-		authorizationHeader := context.GetHeader(authorizationHeaderKey)
-
-		if len(authorizationHeader) <= 0 {
-			err := errors.New("authorization header is not provided")
+		authorizationType, credentials, err := parseAuthorizationHeader(context)
+		if err != nil {
 			context.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
 			return
 		}
 
-		fields := strings.Fields(authorizationHeader)
-		if len(fields) < 2 {
-			err := errors.New("invalid authorisation header format")
+		if authorizationType != authorizationTypeBearer {
+			err := errors.New("unsupported authorisation type")
 			context.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
 			return
 		}
 
-		authorizationType := strings.ToLower(fields[0])
-		if authorizationType != authorizationTypeBearer {
-			err := errors.New("unsupported authorisation type")
-			context.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		if !authenticate(context, tokenMaker, denylist, credentials) {
+			return
+		}
+
+		// go to the next handler
+		context.Next()
+	}
+}
+
+// requireRole only lets through the users with one of the given roles
+// it must run after the authMiddleware, so the token payload is in the context
+func requireRole(roles ...string) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		payload := context.MustGet(authorizationPayloadKey).(*token.Payload)
+
+		if !hasRole(payload, roles...) {
+			err := errors.New("user role is not allowed to access the api")
+			context.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
 			return
 		}
 
-		accessToken := fields[1]
-		payload, err := tokenMaker.VerifyToken(accessToken)
+		context.Next()
+	}
+}
+
+// privilegedMiddleware lets through the internal services that send one of the configured service tokens,
+// e.g. "Service <token>", and the admin users with a valid access token
+// the token payload is only in the context when an admin user made the request
+func privilegedMiddleware(tokenMaker token.Maker, denylist *token.Denylist, serviceTokens []string) gin.HandlerFunc {
+	return func(context *gin.Context) {
+		authorizationType, credentials, err := parseAuthorizationHeader(context)
 		if err != nil {
 			context.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
 			return
 		}
 
-		// store the payload in the context with key
-		// we can access this payload in the next handlers in the request pipeline
-		context.Set(authorizationPayloadKey, payload)
+		switch authorizationType {
+		case authorizationTypeService:
+			if !validServiceToken(serviceTokens, credentials) {
+				err := errors.New("invalid service token")
+				context.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+				return
+			}
+
+			setAuditActor(context, auditActorService)
+		case authorizationTypeBearer:
+			if !authenticate(context, tokenMaker, denylist, credentials) {
+				return
+			}
+
+			payload := context.MustGet(authorizationPayloadKey).(*token.Payload)
+
+			if !hasRole(payload, util.AdminRole) {
+				err := errors.New("user is not allowed to access the privileged api")
+				context.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+				return
+			}
+		default:
+			err := errors.New("unsupported authorisation type")
+			context.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
 
-		// go to the next handler
 		context.Next()
 	}
 }
+
+// splits the authorization header into its lower case type and its credentials
+func parseAuthorizationHeader(context *gin.Context) (string, string, error) {
+	authorizationHeader := context.GetHeader(authorizationHeaderKey)
+
+	if len(authorizationHeader) <= 0 {
+		return "", "", errors.New("authorization header is not provided")
+	}
+
+	fields := strings.Fields(authorizationHeader)
+	if len(fields) < 2 {
+		return "", "", errors.New("invalid authorisation header format")
+	}
+
+	return strings.ToLower(fields[0]), fields[1], nil
+}
+
+// authenticate verifies the access token and stores its payload in the context
+// it aborts the request and returns false when the token can't be used
+func authenticate(context *gin.Context, tokenMaker token.Maker, denylist *token.Denylist, accessToken string) bool {
+	payload, err := tokenMaker.VerifyToken(accessToken, token.TokenTypeAccessToken)
+	if err != nil {
+		metrics.TokenVerificationFailed(errors.Is(err, token.ErrExpiredToken))
+		context.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		return false
+	}
+
+	// the token is valid but it could have been revoked before it expired, e.g. the user logged out
+	revoked, err := denylist.IsRevoked(context, payload)
+	if err != nil {
+		context.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	if revoked {
+		err := errors.New("token has been revoked")
+		context.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		return false
+	}
+
+	// store the payload in the context with key
+	// we can access this payload in the next handlers in the request pipeline
+	context.Set(authorizationPayloadKey, payload)
+	withUsername(context, payload.Username)
+
+	return true
+}
+
+// compares in constant time, so the tokens can't be guessed from the response times
+func validServiceToken(serviceTokens []string, serviceToken string) bool {
+	for _, validToken := range serviceTokens {
+		if validToken != "" && subtle.ConstantTimeCompare([]byte(validToken), []byte(serviceToken)) == 1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasRole(payload *token.Payload, roles ...string) bool {
+	for _, role := range roles {
+		if payload.Role == role {
+			return true
+		}
+	}
+
+	return false
+}
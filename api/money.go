@@ -0,0 +1,62 @@
+package api
+
+import (
+	"errors"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/util"
+)
+
+var (
+	errAmountOptions  = errors.New("amount and amount_value can not be used together")
+	errAmountRequired = errors.New("amount or amount_value is required")
+	errAmountPositive = errors.New("amount must be positive")
+)
+
+// requestAmount returns the amount of a request in minor units
+// clients send either the minor units in amount, e.g. 1234, or the decimal amount_value, e.g. "12.34"
+func requestAmount(amount int64, amountValue string, currency string) (int64, error) {
+	if amount != 0 && amountValue != "" {
+		return 0, errAmountOptions
+	}
+
+	if amountValue != "" {
+		money, err := util.ParseMoney(amountValue, currency)
+		if err != nil {
+			return 0, err
+		}
+
+		amount = money.Amount
+	}
+
+	if amount == 0 && amountValue == "" {
+		return 0, errAmountRequired
+	}
+
+	if amount <= 0 {
+		return 0, errAmountPositive
+	}
+
+	return amount, nil
+}
+
+// accountResponse is an account with its balance also given as a decimal amount
+type accountResponse struct {
+	db.Account
+	BalanceMoney util.Money `json:"balance_money"`
+}
+
+func newAccountResponse(account db.Account) accountResponse {
+	return accountResponse{
+		Account:      account,
+		BalanceMoney: util.Money{Amount: account.Balance, Currency: account.Currency},
+	}
+}
+
+func newAccountResponses(accounts []db.Account) []accountResponse {
+	res := make([]accountResponse, 0, len(accounts))
+	for _, account := range accounts {
+		res = append(res, newAccountResponse(account))
+	}
+
+	return res
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRequestAmount(t *testing.T) {
+	testCases := []struct {
+		name        string
+		amount      int64
+		amountValue string
+		currency    string
+		expected    int64
+		err         error
+	}{
+		{name: "MinorUnits", amount: 1234, currency: util.USD, expected: 1234},
+		{name: "DecimalValue", amountValue: "12.34", currency: util.USD, expected: 1234},
+		{name: "NoDecimalPlaces", amountValue: "1500", currency: util.JPY, expected: 1500},
+		{name: "TooManyDecimalPlaces", amountValue: "12.5", currency: util.JPY, err: util.ErrInvalidMoney},
+		{name: "Both", amount: 1234, amountValue: "12.34", currency: util.USD, err: errAmountOptions},
+		{name: "Neither", currency: util.USD, err: errAmountRequired},
+		{name: "ZeroValue", amountValue: "0.00", currency: util.USD, err: errAmountPositive},
+		{name: "NegativeValue", amountValue: "-1.00", currency: util.USD, err: errAmountPositive},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			amount, err := requestAmount(testCase.amount, testCase.amountValue, testCase.currency)
+
+			if testCase.err != nil {
+				require.ErrorIs(t, err, testCase.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, amount)
+		})
+	}
+}
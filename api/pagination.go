@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	errInvalidCursor     = errors.New("invalid cursor")
+	errPaginationOptions = errors.New("page_id and cursor can not be used together")
+)
+
+// pageCursor points at the row a page ends at, rows are ordered by (created_at, id)
+// it is sent to the client as an opaque string
+type pageCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int64     `json:"i"`
+	// Backward is set on cursors that load the rows before the pointed row
+	Backward bool `json:"b,omitempty"`
+}
+
+func encodeCursor(cursor pageCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (pageCursor, error) {
+	var cursor pageCursor
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, errInvalidCursor
+	}
+
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID < 1 {
+		return cursor, errInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+// parsePagination checks which pagination the client asked for
+// a nil cursor with no page_id means the first page of a keyset pagination
+func parsePagination(pageID int32, cursor string) (*pageCursor, error) {
+	if pageID > 0 && cursor != "" {
+		return nil, errPaginationOptions
+	}
+
+	if cursor == "" {
+		return nil, nil
+	}
+
+	pc, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pc, nil
+}
+
+// keysetPage is a page of rows with the cursors of the pages around it
+type keysetPage[T any] struct {
+	Items      []T
+	NextCursor string
+	PrevCursor string
+}
+
+// keysetFetcher loads up to limit rows after (or before) the given created_at and id
+type keysetFetcher[T any] func(createdAt time.Time, id int64, limit int32) ([]T, error)
+
+// fetchKeysetPage loads the page the cursor points at
+// one extra row is loaded to know if there is another page in the same direction
+func fetchKeysetPage[T any](
+	cursor *pageCursor,
+	pageSize int32,
+	after keysetFetcher[T],
+	before keysetFetcher[T],
+	key func(item T) (time.Time, int64),
+) (keysetPage[T], error) {
+	var page keysetPage[T]
+
+	var items []T
+	var err error
+
+	switch {
+	case cursor == nil:
+		// the first page starts before any row
+		items, err = after(time.Time{}, 0, pageSize+1)
+	case cursor.Backward:
+		items, err = before(cursor.CreatedAt, cursor.ID, pageSize+1)
+	default:
+		items, err = after(cursor.CreatedAt, cursor.ID, pageSize+1)
+	}
+
+	if err != nil {
+		return page, err
+	}
+
+	hasMore := len(items) > int(pageSize)
+	if hasMore {
+		items = items[:pageSize]
+	}
+
+	backward := cursor != nil && cursor.Backward
+	if backward {
+		// rows before the cursor come in descending order
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	page.Items = items
+
+	if len(items) == 0 {
+		return page, nil
+	}
+
+	firstCreatedAt, firstID := key(items[0])
+	lastCreatedAt, lastID := key(items[len(items)-1])
+
+	if hasMore || backward {
+		page.NextCursor = encodeCursor(pageCursor{CreatedAt: lastCreatedAt, ID: lastID})
+	}
+
+	if (hasMore && backward) || (cursor != nil && !backward) {
+		page.PrevCursor = encodeCursor(pageCursor{CreatedAt: firstCreatedAt, ID: firstID, Backward: true})
+	}
+
+	return page, nil
+}
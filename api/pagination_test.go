@@ -0,0 +1,119 @@
+package api
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestPageCursor(t *testing.T) {
+	cursor := pageCursor{CreatedAt: time.Now().UTC().Truncate(time.Microsecond), ID: 42, Backward: true}
+
+	decoded, err := decodeCursor(encodeCursor(cursor))
+	require.NoError(t, err)
+	require.Equal(t, cursor, decoded)
+
+	_, err = decodeCursor("not a cursor")
+	require.ErrorIs(t, err, errInvalidCursor)
+
+	_, err = decodeCursor(encodeCursor(pageCursor{}))
+	require.ErrorIs(t, err, errInvalidCursor)
+}
+
+type keysetRow struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+func TestFetchKeysetPage(t *testing.T) {
+	start := time.Now().UTC()
+
+	var rows []keysetRow
+	for i := 1; i <= 12; i++ {
+		rows = append(rows, keysetRow{CreatedAt: start.Add(time.Duration(i) * time.Second), ID: int64(i)})
+	}
+
+	after := func(createdAt time.Time, id int64, limit int32) ([]keysetRow, error) {
+		var res []keysetRow
+		for _, row := range rows {
+			if row.ID > id && len(res) < int(limit) {
+				res = append(res, row)
+			}
+		}
+		return res, nil
+	}
+
+	before := func(createdAt time.Time, id int64, limit int32) ([]keysetRow, error) {
+		var res []keysetRow
+		for i := len(rows) - 1; i >= 0; i-- {
+			if rows[i].ID < id && len(res) < int(limit) {
+				res = append(res, rows[i])
+			}
+		}
+		return res, nil
+	}
+
+	key := func(row keysetRow) (time.Time, int64) {
+		return row.CreatedAt, row.ID
+	}
+
+	ids := func(items []keysetRow) []int64 {
+		var res []int64
+		for _, item := range items {
+			res = append(res, item.ID)
+		}
+		return res
+	}
+
+	next := func(t *testing.T, value string) *pageCursor {
+		cursor, err := decodeCursor(value)
+		require.NoError(t, err)
+		return &cursor
+	}
+
+	// first page
+	page, err := fetchKeysetPage(nil, 5, after, before, key)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2, 3, 4, 5}, ids(page.Items))
+	require.NotEmpty(t, page.NextCursor)
+	require.Empty(t, page.PrevCursor)
+
+	// second page
+	page, err = fetchKeysetPage(next(t, page.NextCursor), 5, after, before, key)
+	require.NoError(t, err)
+	require.Equal(t, []int64{6, 7, 8, 9, 10}, ids(page.Items))
+	require.NotEmpty(t, page.NextCursor)
+	require.NotEmpty(t, page.PrevCursor)
+
+	secondPage := page
+
+	// last page
+	page, err = fetchKeysetPage(next(t, page.NextCursor), 5, after, before, key)
+	require.NoError(t, err)
+	require.Equal(t, []int64{11, 12}, ids(page.Items))
+	require.Empty(t, page.NextCursor)
+	require.NotEmpty(t, page.PrevCursor)
+
+	// back to the second page
+	page, err = fetchKeysetPage(next(t, page.PrevCursor), 5, after, before, key)
+	require.NoError(t, err)
+	require.Equal(t, ids(secondPage.Items), ids(page.Items))
+	require.NotEmpty(t, page.NextCursor)
+	require.NotEmpty(t, page.PrevCursor)
+
+	// back to the first page
+	page, err = fetchKeysetPage(next(t, page.PrevCursor), 5, after, before, key)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2, 3, 4, 5}, ids(page.Items))
+	require.NotEmpty(t, page.NextCursor)
+	require.Empty(t, page.PrevCursor)
+
+	// errors are returned as they are
+	failing := func(createdAt time.Time, id int64, limit int32) ([]keysetRow, error) {
+		return nil, errors.New("failed")
+	}
+
+	_, err = fetchKeysetPage(nil, 5, failing, before, key)
+	require.Error(t, err)
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/token"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// used when PASSWORD_RESET_DURATION is not configured
+const defaultPasswordResetDuration = time.Hour
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required,min=6"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
+}
+
+// changePassword sets a new password for the authenticated user, who must send their current password too
+// every token issued before the change is rejected afterwards, including the one of this request,
+// so the user logs in again with the new password
+func (server *Server) changePassword(ctx *gin.Context) {
+	var req changePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err := util.CheckPassword(req.CurrentPassword, user.HashedPassword); err != nil {
+		err := errors.New("current password is incorrect")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	hashedPassword, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	result, err := server.store.ChangePasswordTx(ctx, db.ChangePasswordTxParams{
+		Username:       user.Username,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.passwordChanged(result)
+
+	ctx.JSON(http.StatusOK, newUserResponse(result.User))
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// forgotPassword queues a single-use token for the user with the email, the token lets them set a new password
+// the token is mailed in the background, so the response is the same whether a user has the email or not
+// and it can't be used to find out who has an account
+func (server *Server) forgotPassword(ctx *gin.Context) {
+	var req forgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	passwordResetToken, err := server.store.QueuePasswordResetToken(ctx, db.QueuePasswordResetTokenParams{
+		ExpiresAt: time.Now().Add(durationOrDefault(server.config.PasswordResetDuration, defaultPasswordResetDuration)),
+		Email:     req.Email,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Status(http.StatusAccepted)
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	setAuditActor(ctx, passwordResetToken.Username)
+	setAuditResource(ctx, "password_reset_token_id", passwordResetToken.ID)
+
+	ctx.Status(http.StatusAccepted)
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// resetPassword sets a new password with a token mailed by forgotPassword, like changePassword
+func (server *Server) resetPassword(ctx *gin.Context) {
+	var req resetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hashedPassword, err := util.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	result, err := server.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		TokenHash:      util.HashSecret(req.Token),
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidPasswordResetToken) {
+			ctx.JSON(http.StatusBadRequest, errorCodeResponse(errCodeInvalidPasswordResetToken, err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	setAuditActor(ctx, result.User.Username)
+
+	server.passwordChanged(result)
+
+	ctx.JSON(http.StatusOK, newUserResponse(result.User))
+}
+
+// passwordChanged rejects the tokens issued before the password changed, the refresh tokens of the revoked sessions
+// and every access token of the user, without waiting for the cached answers of the denylist to expire
+func (server *Server) passwordChanged(result db.ChangePasswordTxResult) {
+	for _, session := range result.RevokedSessions {
+		server.denylist.Revoked(session.ID, session.ExpiresAt)
+	}
+
+	server.denylist.CredentialsChanged(result.User.Username, result.User.PasswordChangedAt)
+}
@@ -0,0 +1,460 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/aybarsacar/simplebank/db/dbtest"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/token"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// changePasswordTxMatcher matches the password changes of a user to the given password
+type changePasswordTxMatcher struct {
+	username string
+	password string
+}
+
+func (matcher changePasswordTxMatcher) Matches(x interface{}) bool {
+	args, ok := x.(db.ChangePasswordTxParams)
+	if !ok || args.Username != matcher.username {
+		return false
+	}
+
+	return util.CheckPassword(matcher.password, args.HashedPassword) == nil
+}
+
+func (matcher changePasswordTxMatcher) String() string {
+	return fmt.Sprintf("changes the password of %s to %s", matcher.username, matcher.password)
+}
+
+func randomUserWithPassword(t *testing.T) (db.User, string) {
+	password := util.RandomString(8)
+
+	hashedPassword, err := util.HashPassword(password)
+	require.NoError(t, err)
+
+	user := db.User{
+		Username:       util.RandomOwner(),
+		HashedPassword: hashedPassword,
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+		Role:           util.CustomerRole,
+	}
+
+	return user, password
+}
+
+func TestChangePasswordAPI(t *testing.T) {
+	user, password := randomUserWithPassword(t)
+	newPassword := util.RandomString(10)
+
+	changedUser := user
+	changedUser.PasswordChangedAt = time.Now()
+
+	session := db.Session{
+		ID:        uuid.New(),
+		Username:  user.Username,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"current_password": password, "new_password": newPassword},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuthorized(store, user.Username)
+
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().
+					ChangePasswordTx(gomock.Any(), changePasswordTxMatcher{username: user.Username, password: newPassword}).
+					Times(1).
+					Return(db.ChangePasswordTxResult{User: changedUser, RevokedSessions: []db.Session{session}}, nil)
+				store.EXPECT().
+					CreateAuditEvent(gomock.Any(), auditEventMatcher{action: auditActionChangePassword, actor: user.Username}).
+					Times(1).
+					Return(db.AuditEvent{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res userResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &res)
+				require.NoError(t, err)
+				require.Equal(t, user.Username, res.Username)
+				require.WithinDuration(t, changedUser.PasswordChangedAt, res.PasswordChangedAt, time.Second)
+			},
+		},
+		{
+			name: "IncorrectCurrentPassword",
+			body: gin.H{"current_password": "incorrect", "new_password": newPassword},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuthorized(store, user.Username)
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NewPasswordTooShort",
+			body: gin.H{"current_password": password, "new_password": "short"},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuthorized(store, user.Username)
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			body: gin.H{"current_password": password, "new_password": newPassword},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"current_password": password, "new_password": newPassword},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuthorized(store, user.Username)
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ChangePasswordTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(testCase.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPut, "/api/v1/users/me/password", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			testCase.setupAuth(t, request, server.tokenMaker)
+
+			server.router.ServeHTTP(recorder, request)
+
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestChangePasswordRejectsOldTokens(t *testing.T) {
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	user, password := randomUserWithPassword(t)
+
+	changedUser := user
+	changedUser.PasswordChangedAt = time.Now().Add(time.Second)
+
+	store := mockdb.NewMockStore(controller)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+	store.EXPECT().ChangePasswordTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ChangePasswordTxResult{User: changedUser}, nil)
+
+	// the token is only looked up by the first request, the second one is rejected by the password change the server cached
+	dbtest.ExpectAuthorized(store, user.Username)
+	dbtest.ExpectAuditEvent(store)
+
+	server := newTestServer(t, store)
+
+	accessToken, _, err := server.tokenMaker.CreateToken(user.Username, user.Role, time.Minute, token.TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	sendRequest := func() *httptest.ResponseRecorder {
+		data, err := json.Marshal(gin.H{"current_password": password, "new_password": util.RandomString(10)})
+		require.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPut, "/api/v1/users/me/password", bytes.NewReader(data))
+		require.NoError(t, err)
+		request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	recorder := sendRequest()
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// the access token was issued before the password changed
+	recorder = sendRequest()
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestForgotPasswordAPI(t *testing.T) {
+	user, _ := randomUserWithPassword(t)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					QueuePasswordResetToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, args db.QueuePasswordResetTokenParams) (db.PasswordResetToken, error) {
+						require.Equal(t, user.Email, args.Email)
+						require.WithinDuration(t, time.Now().Add(defaultPasswordResetDuration), args.ExpiresAt, time.Second)
+
+						return db.PasswordResetToken{
+							ID:        1,
+							Username:  user.Username,
+							Email:     args.Email,
+							ExpiresAt: args.ExpiresAt,
+						}, nil
+					})
+				store.EXPECT().
+					CreateAuditEvent(gomock.Any(), auditEventMatcher{action: auditActionRequestPasswordReset, actor: user.Username}).
+					Times(1).
+					Return(db.AuditEvent{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownEmail",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().
+					QueuePasswordResetToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.PasswordResetToken{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				// the same response as for a known email
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: gin.H{"email": "invalid"},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().QueuePasswordResetToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().
+					QueuePasswordResetToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.PasswordResetToken{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(testCase.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/api/v1/users/password/forgot", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestResetPasswordAPI(t *testing.T) {
+	user, _ := randomUserWithPassword(t)
+	resetToken, err := util.NewSecret()
+	require.NoError(t, err)
+
+	newPassword := util.RandomString(10)
+
+	changedUser := user
+	changedUser.PasswordChangedAt = time.Now()
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"token": resetToken, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, args db.ResetPasswordTxParams) (db.ChangePasswordTxResult, error) {
+						require.Equal(t, util.HashSecret(resetToken), args.TokenHash)
+						require.NoError(t, util.CheckPassword(newPassword, args.HashedPassword))
+
+						return db.ChangePasswordTxResult{User: changedUser}, nil
+					})
+				store.EXPECT().
+					CreateAuditEvent(gomock.Any(), auditEventMatcher{action: auditActionResetPassword, actor: user.Username}).
+					Times(1).
+					Return(db.AuditEvent{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res userResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &res)
+				require.NoError(t, err)
+				require.Equal(t, user.Username, res.Username)
+			},
+		},
+		{
+			name: "InvalidToken",
+			body: gin.H{"token": resetToken, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ChangePasswordTxResult{}, db.ErrInvalidPasswordResetToken)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errCodeInvalidPasswordResetToken)
+			},
+		},
+		{
+			name: "NewPasswordTooShort",
+			body: gin.H{"token": resetToken, "new_password": "short"},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{"token": resetToken, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().ResetPasswordTx(gomock.Any(), gomock.Any()).Times(1).Return(db.ChangePasswordTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(testCase.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/api/v1/users/password/reset", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
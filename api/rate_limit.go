@@ -0,0 +1,58 @@
+package api
+
+import (
+	"errors"
+	"github.com/aybarsacar/simplebank/ratelimit"
+	"github.com/aybarsacar/simplebank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"net/http"
+)
+
+const errCodeRateLimited = "rate_limited"
+
+// SetRateLimitStore replaces the in-memory store of the rate limits, e.g. with a store that is shared by all instances
+// it must be called before the server is started
+func (server *Server) SetRateLimitStore(store ratelimit.Store) {
+	server.rateLimiter = store
+}
+
+// rateLimitMiddleware throttles the routes that have a limit in the config
+// the authenticated requests are counted by username, so it must run after the authMiddleware on their routes,
+// the other requests are counted by client IP
+func (server *Server) rateLimitMiddleware() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		route := context.FullPath()
+
+		limit, ok := server.rateLimits.Limit(context.Request.Method, route)
+		if !ok {
+			context.Next()
+			return
+		}
+
+		username := ""
+		if payload, ok := context.Get(authorizationPayloadKey); ok {
+			username = payload.(*token.Payload).Username
+		}
+
+		key := ratelimit.Key(context.Request.Method, route, context.ClientIP(), username)
+
+		result, err := server.rateLimiter.Take(context, key, limit)
+		if err != nil {
+			// an unavailable store must not take the api down with it
+			zerolog.Ctx(context.Request.Context()).Error().Err(err).Msg("cannot check the rate limit")
+			context.Next()
+			return
+		}
+
+		ratelimit.SetHeaders(context.Writer.Header(), result)
+
+		if !result.Allowed {
+			err := errors.New("too many requests, retry later")
+			context.AbortWithStatusJSON(http.StatusTooManyRequests, errorCodeResponse(errCodeRateLimited, err))
+			return
+		}
+
+		context.Next()
+	}
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aybarsacar/simplebank/db/dbtest"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	"github.com/aybarsacar/simplebank/ratelimit"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store is unavailable")
+}
+
+func TestRateLimitPublicRoute(t *testing.T) {
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	store := mockdb.NewMockStore(controller)
+
+	// only the logins that get past the limit reach their handler and are audited
+	dbtest.ExpectAuditEvent(store).Times(3)
+
+	server := newTestServer(t, store)
+	server.rateLimits = ratelimit.Rules{"POST /api/v1/users/login": {Requests: 2, Period: time.Minute}}
+
+	login := func(clientIP string, forwardedFor string) *httptest.ResponseRecorder {
+		// the body is invalid, the limit is checked before the handler runs
+		request, err := http.NewRequest(http.MethodPost, "/api/v1/users/login", bytes.NewReader([]byte("{}")))
+		require.NoError(t, err)
+
+		request.RemoteAddr = clientIP + ":12345"
+		if forwardedFor != "" {
+			request.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	for i := 1; i >= 0; i-- {
+		recorder := login("10.0.0.1", "")
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+		require.Equal(t, "2", recorder.Header().Get("X-RateLimit-Limit"))
+		require.Equal(t, fmt.Sprint(i), recorder.Header().Get("X-RateLimit-Remaining"))
+	}
+
+	recorder := login("10.0.0.1", "")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "30", recorder.Header().Get("Retry-After"))
+	require.Contains(t, recorder.Body.String(), errCodeRateLimited)
+
+	// no proxy is trusted, so a client can't pick another IP with the header
+	recorder = login("10.0.0.1", "10.0.0.3")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+
+	// the clients are counted by IP
+	recorder = login("10.0.0.2", "")
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestRateLimitAuthenticatedRoute(t *testing.T) {
+	account := randomAccount(util.RandomOwner())
+	otherUser := util.RandomOwner()
+
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	store := mockdb.NewMockStore(controller)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(2).Return(account, nil)
+
+	// the limit is checked after the token, the credentials of the owner are cached by their second request
+	dbtest.ExpectAuthorized(store, account.Owner)
+	store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Times(1).Return(false, nil)
+	dbtest.ExpectAuthorized(store, otherUser)
+
+	server := newTestServer(t, store)
+	server.rateLimits = ratelimit.Rules{"GET /api/v1/accounts/:id": {Requests: 1, Period: time.Minute}}
+
+	getAccount := func(username string) int {
+		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/accounts/%d", account.ID), nil)
+		require.NoError(t, err)
+
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, util.CustomerRole, time.Minute)
+
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+
+		return recorder.Code
+	}
+
+	require.Equal(t, http.StatusOK, getAccount(account.Owner))
+	require.Equal(t, http.StatusTooManyRequests, getAccount(account.Owner))
+
+	// the users are counted by username, not by the IP they share, the other user isn't the owner of the account
+	require.Equal(t, http.StatusUnauthorized, getAccount(otherUser))
+}
+
+func TestRateLimitStoreUnavailable(t *testing.T) {
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	store := mockdb.NewMockStore(controller)
+	dbtest.ExpectAuditEvent(store).Times(2)
+
+	server := newTestServer(t, store)
+	server.rateLimits = ratelimit.Rules{"POST /api/v1/users/login": {Requests: 1, Period: time.Minute}}
+	server.SetRateLimitStore(failingRateLimitStore{})
+
+	for i := 0; i < 2; i++ {
+		request, err := http.NewRequest(http.MethodPost, "/api/v1/users/login", bytes.NewReader([]byte("{}")))
+		require.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		server.router.ServeHTTP(recorder, request)
+
+		// the requests go through without the limit
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+	}
+}
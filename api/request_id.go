@@ -0,0 +1,31 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	requestIDHeaderKey = "X-Request-ID"
+	requestIDKey       = "request_id"
+	// longer request ids sent by clients are replaced, so they can't flood the logs
+	maxRequestIDLength = 128
+)
+
+// requestIDMiddleware gives every request an id, so its audit events and logs can be correlated
+// the id sent by the client or a proxy is kept, otherwise a new one is generated,
+// and it is sent back in the response header
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		requestID := context.GetHeader(requestIDHeaderKey)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = uuid.NewString()
+		}
+
+		context.Set(requestIDKey, requestID)
+		context.Header(requestIDHeaderKey, requestID)
+		withRequestLogger(context, requestID)
+
+		context.Next()
+	}
+}
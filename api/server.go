@@ -1,13 +1,32 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/aybarsacar/simplebank/bank"
 	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/fx"
+	"github.com/aybarsacar/simplebank/metrics"
+	"github.com/aybarsacar/simplebank/ratelimit"
 	"github.com/aybarsacar/simplebank/token"
+	"github.com/aybarsacar/simplebank/tracing"
 	"github.com/aybarsacar/simplebank/util"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// used when the timeouts are not configured
+const (
+	defaultReadTimeout  = 10 * time.Second
+	defaultWriteTimeout = 30 * time.Second
+	defaultIdleTimeout  = 60 * time.Second
 )
 
 // Server serves all http requests for banking service
@@ -15,59 +34,217 @@ type Server struct {
 	config     util.Config
 	store      db.Store
 	tokenMaker token.Maker
-	router     *gin.Engine
+	denylist   *token.Denylist
+	// rates is nil when transfers between accounts of different currencies are not enabled
+	rates       fx.RateProvider
+	router      *gin.Engine
+	httpServer  *http.Server
+	rateLimits  ratelimit.Rules
+	rateLimiter ratelimit.Store
+	// migrations is nil until MarkReady is called
+	readinessMutex sync.RWMutex
+	migrations     MigrationSource
 }
 
 // NewServer constructor
-func NewServer(config util.Config, store db.Store) (*Server, error) {
+// the denylist must be the one of the gRPC server, so both apis agree on the revoked tokens
+func NewServer(config util.Config, store db.Store, denylist *token.Denylist) (*Server, error) {
 
 	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
 
+	rateLimits, err := ratelimit.ParseRules(config.RateLimits)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse rate limits: %w", err)
+	}
+
 	server := Server{
-		config:     config,
-		store:      store,
-		tokenMaker: tokenMaker,
+		config:      config,
+		store:       store,
+		tokenMaker:  tokenMaker,
+		denylist:    denylist,
+		rateLimits:  rateLimits,
+		rateLimiter: ratelimit.NewMemoryStore(),
+	}
+
+	if config.FXRatesFile != "" {
+		server.rates, err = fx.NewFileRateProvider(config.FXRatesFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create rate provider: %w", err)
+		}
+	}
+
+	// the currencies can be enabled and disabled at runtime, so they are kept in the database
+	if err := bank.LoadCurrencies(context.Background(), store); err != nil {
+		return nil, fmt.Errorf("cannot load currencies: %w", err)
 	}
 
 	// register custom validators
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		v.RegisterValidation("currency", validCurrency)
+		v.RegisterValidation("enabled_currency", validEnabledCurrency)
+		v.RegisterValidation("webhook_url", validWebhookURL)
 	}
 
 	server.setupRoutes()
 
+	// the client IPs of the rate limits and audit events are only taken from the X-Forwarded-For header of these proxies
+	if err := server.router.SetTrustedProxies(config.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("cannot set trusted proxies: %w", err)
+	}
+
+	server.httpServer = &http.Server{
+		Handler: server.router,
+		// the headers are read within the read timeout too, so slow clients can't hold connections open
+		ReadHeaderTimeout: durationOrDefault(config.HTTPReadTimeout, defaultReadTimeout),
+		ReadTimeout:       durationOrDefault(config.HTTPReadTimeout, defaultReadTimeout),
+		WriteTimeout:      durationOrDefault(config.HTTPWriteTimeout, defaultWriteTimeout),
+		IdleTimeout:       durationOrDefault(config.HTTPIdleTimeout, defaultIdleTimeout),
+	}
+
 	return &server, nil
 }
 
+func durationOrDefault(duration time.Duration, defaultDuration time.Duration) time.Duration {
+	if duration <= 0 {
+		return defaultDuration
+	}
+
+	return duration
+}
+
 func (server *Server) setupRoutes() {
-	router := gin.Default()
+	router := gin.New()
+	// lets the store find the logger of the request in the gin context
+	router.ContextWithFallback = true
+	// the span of the request comes first, so the request logger can carry its trace id
+	router.Use(otelgin.Middleware(tracing.ServiceName), requestIDMiddleware(), loggerMiddleware(), metricsMiddleware(), gin.Recovery())
 
-	router.POST("/api/v1/users", server.createUser)
-	router.POST("/api/v1/users/login", server.loginUser)
+	// probes of the orchestrator
+	router.GET("/healthz", server.healthz)
+	router.GET("/readyz", server.readyz)
+
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// the public routes are rate limited by client IP
+	publicRoutes := router.Group("/").Use(server.rateLimitMiddleware())
+
+	publicRoutes.POST("/api/v1/users", auditMiddleware(server.store, auditActionCreateUser), server.createUser)
+	publicRoutes.POST("/api/v1/users/login", auditMiddleware(server.store, auditActionLoginUser), server.loginUser)
+	publicRoutes.GET("/api/v1/users/verify_email", auditMiddleware(server.store, auditActionVerifyEmail), server.verifyEmail)
+	publicRoutes.POST("/api/v1/users/password/forgot", auditMiddleware(server.store, auditActionRequestPasswordReset), server.forgotPassword)
+	publicRoutes.POST("/api/v1/users/password/reset", auditMiddleware(server.store, auditActionResetPassword), server.resetPassword)
+	publicRoutes.POST("/api/v1/tokens/renew_access", server.renewAccessToken)
+	publicRoutes.GET("/api/v1/currencies", server.listCurrencies)
 
 	// create auth middleware, every request that needs to get JWT Payload and
 	// authenticate is added to this route now
-	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker))
+	// the authenticated routes are rate limited by username
+	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker, server.denylist), server.rateLimitMiddleware())
 
-	authRoutes.POST("/api/v1/accounts", server.createAccount)
+	authRoutes.POST("/api/v1/users/logout", auditMiddleware(server.store, auditActionLogoutUser), server.logoutUser)
+	authRoutes.PUT("/api/v1/users/me/password", auditMiddleware(server.store, auditActionChangePassword), server.changePassword)
+	authRoutes.POST("/api/v1/users/verify_email/resend", auditMiddleware(server.store, auditActionResendVerifyEmail), server.resendVerifyEmail)
+
+	// the users can't create accounts or make transfers before they verified their email
+	authRoutes.POST("/api/v1/accounts", auditMiddleware(server.store, auditActionCreateAccount), server.requireVerifiedEmail(), server.createAccount)
 	authRoutes.GET("/api/v1/accounts/:id", server.getAccount)
 	authRoutes.GET("/api/v1/accounts", server.listAccounts)
+	authRoutes.GET("/api/v1/accounts/:id/entries", server.listAccountEntries)
+	authRoutes.GET("/api/v1/accounts/:id/transfers", server.listAccountTransfers)
+
+	authRoutes.POST("/api/v1/transfers", auditMiddleware(server.store, auditActionCreateTransfer), server.requireVerifiedEmail(), server.createTransfer)
+	authRoutes.GET("/api/v1/transfers/:id", server.getTransfer)
 
-	authRoutes.POST("/api/v1/transfers", server.createTransfer)
+	authRoutes.POST("/api/v1/webhooks", auditMiddleware(server.store, auditActionCreateWebhook), server.createWebhookSubscription)
+	authRoutes.GET("/api/v1/webhooks", server.listWebhookSubscriptions)
+	authRoutes.DELETE("/api/v1/webhooks/:id", auditMiddleware(server.store, auditActionDeleteWebhook), server.deleteWebhookSubscription)
+	authRoutes.GET("/api/v1/webhooks/:id/deliveries", server.listWebhookDeliveries)
+	authRoutes.POST("/api/v1/webhooks/:id/deliveries/:delivery_id/replay", auditMiddleware(server.store, auditActionReplayWebhook), server.replayWebhookDelivery)
+
+	// staff routes, only the tellers and admins can call them
+	staffRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker, server.denylist), requireRole(util.TellerRole, util.AdminRole), server.rateLimitMiddleware())
+
+	staffRoutes.POST("/api/v1/transfers/:id/reverse", auditMiddleware(server.store, auditActionReverseTransfer), server.reverseTransfer)
+
+	// admin routes, only the users with the admin role can call them
+	adminRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker, server.denylist), requireRole(util.AdminRole), server.rateLimitMiddleware())
+
+	adminRoutes.GET("/api/v1/admin/audit_events", server.listAuditEvents)
+	adminRoutes.POST("/api/v1/admin/users/:username/revoke_sessions", auditMiddleware(server.store, auditActionRevokeUserSessions), server.revokeUserSessions)
+	adminRoutes.PUT("/api/v1/admin/users/:username/role", auditMiddleware(server.store, auditActionUpdateUserRole), server.updateUserRole)
+	adminRoutes.POST("/api/v1/admin/users/:username/unlock", auditMiddleware(server.store, auditActionUnlockUser), server.unlockUser)
+	adminRoutes.PATCH("/api/v1/admin/currencies/:code", auditMiddleware(server.store, auditActionUpdateCurrency), server.updateCurrency)
+	adminRoutes.POST("/api/v1/admin/accounts/:id/freeze", auditMiddleware(server.store, auditActionFreezeAccount), server.freezeAccount)
+	adminRoutes.POST("/api/v1/admin/accounts/:id/unfreeze", auditMiddleware(server.store, auditActionUnfreezeAccount), server.unfreezeAccount)
+	adminRoutes.POST("/api/v1/admin/accounts/:id/close", auditMiddleware(server.store, auditActionCloseAccount), server.closeAccount)
+
+	// privileged routes, called by the cash-in and cash-out integrations with a service token, or by admin users
+	privilegedRoutes := router.Group("/").Use(privilegedMiddleware(server.tokenMaker, server.denylist, server.config.ServiceTokens), server.rateLimitMiddleware())
+
+	privilegedRoutes.POST("/api/v1/accounts/:id/deposits", auditMiddleware(server.store, auditActionCreateDeposit), server.createDeposit)
+	privilegedRoutes.POST("/api/v1/accounts/:id/withdrawals", auditMiddleware(server.store, auditActionCreateWithdrawal), server.createWithdrawal)
 
 	server.router = router
 }
 
-// Start runs the HTTP server ona specific address
+// Start runs the HTTP server on a specific address until Shutdown is called
+// it returns nil once the server is shut down
 func (server *Server) Start(address string) error {
-	return server.router.Run(address)
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("cannot create listener: %w", err)
+	}
+
+	return server.serve(listener)
+}
+
+func (server *Server) serve(listener net.Listener) error {
+	err := server.httpServer.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// Shutdown stops accepting new requests and waits for the active ones to finish, e.g. the transfers in progress
+// if the context ends first, the remaining connections are left to be closed with the process and its error is returned
+func (server *Server) Shutdown(ctx context.Context) error {
+	return server.httpServer.Shutdown(ctx)
 }
 
+// stable, machine-readable error codes returned next to the error message
+// so clients don't have to match on the message text
+const (
+	errCodeInsufficientFunds          = "insufficient_funds"
+	errCodeIdempotencyKeyConflict     = "idempotency_key_conflict"
+	errCodeDuplicateExternalReference = "duplicate_external_reference"
+	errCodeInvalidConvertedAmount     = "invalid_converted_amount"
+	errCodeTransferAlreadyReversed    = "transfer_already_reversed"
+	errCodeTransferNotReversible      = "transfer_not_reversible"
+	errCodeAccountFrozen              = "account_frozen"
+	errCodeAccountClosed              = "account_closed"
+	errCodeAccountBalanceNotZero      = "account_balance_not_zero"
+	errCodeUserLocked                 = "user_locked"
+	errCodeInvalidPasswordResetToken  = "invalid_password_reset_token"
+	errCodeInvalidVerifyEmail         = "invalid_verify_email"
+	errCodeEmailNotVerified           = "email_not_verified"
+	errCodeEmailAlreadyVerified       = "email_already_verified"
+	errCodeCurrencyDisabled           = "currency_disabled"
+)
+
 func errorResponse(err error) gin.H {
 	return gin.H{
 		"error": err.Error(),
 	}
 }
+
+func errorCodeResponse(code string, err error) gin.H {
+	return gin.H{
+		"code":  code,
+		"error": err.Error(),
+	}
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServerTimeouts(t *testing.T) {
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(controller))
+
+	// the test server doesn't configure the timeouts
+	require.Equal(t, defaultReadTimeout, server.httpServer.ReadTimeout)
+	require.Equal(t, defaultReadTimeout, server.httpServer.ReadHeaderTimeout)
+	require.Equal(t, defaultWriteTimeout, server.httpServer.WriteTimeout)
+	require.Equal(t, defaultIdleTimeout, server.httpServer.IdleTimeout)
+
+	require.Equal(t, 5*time.Second, durationOrDefault(5*time.Second, defaultReadTimeout))
+}
+
+func TestServerShutdown(t *testing.T) {
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(controller))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	// stands for a request in progress, e.g. a transfer
+	server.router.GET("/slow", func(ctx *gin.Context) {
+		close(started)
+		<-release
+		ctx.Status(http.StatusOK)
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.serve(listener)
+	}()
+
+	url := fmt.Sprintf("http://%s", listener.Addr().String())
+
+	responded := make(chan int, 1)
+	go func() {
+		response, err := http.Get(url + "/slow")
+		if err != nil {
+			responded <- 0
+			return
+		}
+
+		response.Body.Close()
+		responded <- response.StatusCode
+	}()
+
+	<-started
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- server.Shutdown(context.Background())
+	}()
+
+	// the shutdown waits for the active request
+	select {
+	case <-shutdownErr:
+		require.Fail(t, "shutdown returned before the active request finished")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+
+	require.Equal(t, http.StatusOK, <-responded)
+	require.NoError(t, <-shutdownErr)
+	require.NoError(t, <-served)
+
+	// no new requests are accepted
+	_, err = http.Get(url + "/api/v1/currencies")
+	require.Error(t, err)
+}
+
+func TestServerShutdownTimeout(t *testing.T) {
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(controller))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	server.router.GET("/slow", func(ctx *gin.Context) {
+		close(started)
+		<-release
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go server.serve(listener)
+
+	go http.Get(fmt.Sprintf("http://%s/slow", listener.Addr().String()))
+
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err = server.Shutdown(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"fmt"
+	"github.com/aybarsacar/simplebank/db/dbtest"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTraceContextPropagation(t *testing.T) {
+	account := randomAccount(util.RandomOwner())
+
+	recorder := tracetest.NewSpanRecorder()
+
+	provider := otel.GetTracerProvider()
+	propagator := otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	store := mockdb.NewMockStore(controller)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+
+	logs := captureLogs(t)
+
+	server := newTestServer(t, store)
+
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/accounts/%d", account.ID), nil)
+	require.NoError(t, err)
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	request.Header.Set("traceparent", fmt.Sprintf("00-%s-00f067aa0ba902b7-01", traceID))
+	dbtest.ExpectAuthorized(store, account.Owner)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, account.Owner, util.CustomerRole, time.Minute)
+
+	server.router.ServeHTTP(httptest.NewRecorder(), request)
+
+	// the span of the handler continues the trace of the caller and is named after the route
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, "/api/v1/accounts/:id", spans[0].Name())
+	require.Equal(t, traceID, spans[0].SpanContext().TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+
+	accessLog := logLines(t, logs)["http request"]
+	require.Equal(t, traceID, accessLog["trace_id"])
+}
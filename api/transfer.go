@@ -1,20 +1,36 @@
 package api
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/aybarsacar/simplebank/bank"
 	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/fx"
 	"github.com/aybarsacar/simplebank/token"
+	"github.com/aybarsacar/simplebank/util"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"time"
+)
+
+const (
+	idempotencyKeyHeader    = "Idempotency-Key"
+	maxIdempotencyKeyLength = 255
 )
 
 type transferRequest struct {
-	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
-	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
-	Amount        int64  `json:"amount" binding:"required,gt=0"`
-	Currency      string `json:"currency" binding:"required,currency"`
+	FromAccountID int64 `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64 `json:"to_account_id" binding:"required,min=1"`
+	// Amount is in minor units, e.g. cents, clients can send the decimal AmountValue instead
+	Amount      int64  `json:"amount" binding:"omitempty,gt=0"`
+	AmountValue string `json:"amount_value,omitempty" binding:"omitempty,max=32"`
+	Currency    string `json:"currency" binding:"required,currency"`
+	// ToCurrency is the currency of the to account, it must be set to transfer between accounts of different currencies
+	ToCurrency string `json:"to_currency,omitempty" binding:"omitempty,currency"`
 }
 
 func (server *Server) createTransfer(ctx *gin.Context) {
@@ -26,13 +42,43 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 		return
 	}
 
+	// clients send the same key when they retry a request, so the transfer is only made once
+	idempotencyKey := ctx.GetHeader(idempotencyKeyHeader)
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		err := fmt.Errorf("%s header must be at most %d characters", idempotencyKeyHeader, maxIdempotencyKeyLength)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	amount, err := requestAmount(req.Amount, req.AmountValue, req.Currency)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	toCurrency := req.Currency
+	if req.ToCurrency != "" {
+		toCurrency = req.ToCurrency
+	}
+
+	// money can still leave an account in a disabled currency, but it can't go into one
+	if !util.IsSupportedCurrency(toCurrency) {
+		err := fmt.Errorf("currency %s is disabled", toCurrency)
+		ctx.JSON(http.StatusBadRequest, errorCodeResponse(errCodeCurrencyDisabled, err))
+		return
+	}
+
 	// insert new account into the database
 	args := db.TransferTxParams{
 		FromAccountID: req.FromAccountID,
 		ToAccountID:   req.ToAccountID,
-		Amount:        req.Amount,
+		Amount:        amount,
+		Currency:      req.Currency,
 	}
 
+	setAuditResource(ctx, "from_account_id", req.FromAccountID)
+	setAuditResource(ctx, "to_account_id", req.ToAccountID)
+
 	fromAccount, isValid := server.validAccount(ctx, req.FromAccountID, req.Currency)
 
 	if !isValid {
@@ -48,40 +94,404 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 		return
 	}
 
-	if _, isValid := server.validAccount(ctx, req.ToAccountID, req.Currency); !isValid {
+	if _, isValid := server.validAccount(ctx, req.ToAccountID, toCurrency); !isValid {
 		return
 	}
 
-	result, err := server.store.TransferTx(ctx, args)
+	// the zero rate means both accounts use the same currency
+	var rate fx.Rate
+
+	if toCurrency != req.Currency {
+		var isValid bool
+		if rate, isValid = server.exchangeRate(ctx, req.Currency, toCurrency); !isValid {
+			return
+		}
+	}
+
+	var result db.TransferTxResult
+
+	switch {
+	case idempotencyKey != "":
+		result, err = server.store.IdempotentTransferTx(ctx, db.IdempotentTransferTxParams{
+			TransferTxParams: args,
+			Rate:             rate,
+			Username:         authPayload.Username,
+			IdempotencyKey:   idempotencyKey,
+			RequestHash:      hashTransferRequest(req),
+		})
+	case !rate.IsZero():
+		result, err = server.store.CrossCurrencyTransferTx(ctx, db.CrossCurrencyTransferTxParams{
+			TransferTxParams: args,
+			Rate:             rate,
+		})
+	default:
+		result, err = server.store.TransferTx(ctx, args)
+	}
+
 	if err != nil {
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeInsufficientFunds, err))
+			return
+		}
+
+		if errors.Is(err, db.ErrAccountFrozen) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeAccountFrozen, err))
+			return
+		}
+
+		if errors.Is(err, db.ErrAccountClosed) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeAccountClosed, err))
+			return
+		}
+
+		if errors.Is(err, db.ErrAmountTooSmall) || errors.Is(err, fx.ErrAmountOverflow) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeInvalidConvertedAmount, err))
+			return
+		}
+
+		if errors.Is(err, db.ErrIdempotencyKeyConflict) {
+			ctx.JSON(http.StatusConflict, errorCodeResponse(errCodeIdempotencyKeyConflict, err))
+			return
+		}
+
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
+	setAuditResource(ctx, "transfer_id", result.Transfer.ID)
+
 	// account is successfully created - send account back to client
-	ctx.JSON(http.StatusOK, result)
+	ctx.JSON(http.StatusOK, transferTxResponse{
+		TransferTxResult: result,
+		AmountMoney:      util.Money{Amount: result.Transfer.Amount, Currency: req.Currency},
+		ToAmountMoney:    util.Money{Amount: result.Transfer.ToAmount, Currency: toCurrency},
+	})
+}
+
+// transferTxResponse is the result of a transfer with its amounts also given as decimal amounts
+type transferTxResponse struct {
+	db.TransferTxResult
+	AmountMoney   util.Money `json:"amount_money"`
+	ToAmountMoney util.Money `json:"to_amount_money"`
 }
 
 // account with a specific id exists and currency matches the input currency
 func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
+	account, err := bank.ValidAccount(ctx, server.store, accountID, currency)
+	if err != nil {
+		ctx.JSON(bankErrorStatus(err), errorResponse(err))
+		return account, false
+	}
 
-	account, err := server.store.GetAccount(ctx, accountID)
+	return account, true
+}
+
+// gets the rate to convert between the currencies of the accounts of a transfer
+func (server *Server) exchangeRate(ctx *gin.Context, from string, to string) (fx.Rate, bool) {
+	rate, err := bank.ExchangeRate(ctx, server.rates, from, to)
 	if err != nil {
+		ctx.JSON(bankErrorStatus(err), errorResponse(err))
+		return rate, false
+	}
 
+	return rate, true
+}
+
+// bankErrorStatus maps the errors of the shared checks of the bank package to the status codes of the HTTP api
+func bankErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, bank.ErrAccountNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, bank.ErrCurrencyMismatch),
+		errors.Is(err, bank.ErrCrossCurrencyDisabled),
+		errors.Is(err, fx.ErrUnsupportedCurrency):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// fingerprint of the request body, used to detect an idempotency key reused with a different transfer
+func hashTransferRequest(req transferRequest) string {
+	// marshalling a struct always produces the fields in the same order
+	data, _ := json.Marshal(req)
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+const (
+	transferDirectionSent     = "sent"
+	transferDirectionReceived = "received"
+)
+
+// transferResponse is a transfer seen from the point of view of the caller
+type transferResponse struct {
+	db.Transfer
+	// Direction is empty when a staff user reads a transfer between accounts of other users
+	Direction string `json:"direction,omitempty"`
+}
+
+type getTransferRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) getTransfer(ctx *gin.Context) {
+	var req getTransferRequest
+
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	transfer, err := server.store.GetTransfer(ctx, req.ID)
+	if err != nil {
 		if err == sql.ErrNoRows {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
-			return account, false
+			return
 		}
 
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return account, false
+		return
+	}
+
+	// get the token payload from the middleware
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	// the user has to own one side of the transfer
+	fromAccount, err := server.store.GetAccount(ctx, transfer.FromAccountID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if fromAccount.Owner == authPayload.Username {
+		ctx.JSON(http.StatusOK, transferResponse{Transfer: transfer, Direction: transferDirectionSent})
+		return
+	}
+
+	toAccount, err := server.store.GetAccount(ctx, transfer.ToAccountID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 
-	if account.Currency != currency {
-		err := fmt.Errorf("account [%d] currency mismatch: %s vs %s", account.ID, account.Currency, currency)
+	if toAccount.Owner == authPayload.Username {
+		ctx.JSON(http.StatusOK, transferResponse{Transfer: transfer, Direction: transferDirectionReceived})
+		return
+	}
+
+	if util.IsStaffRole(authPayload.Role) {
+		ctx.JSON(http.StatusOK, transferResponse{Transfer: transfer})
+		return
+	}
+
+	err = errors.New("transfer does not belong to the user")
+	ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+}
+
+type reverseTransferRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// reverseTransfer moves the money of a transfer back to the account it was sent from
+// it is called by the staff, e.g. when a customer sent money to the wrong account
+func (server *Server) reverseTransfer(ctx *gin.Context) {
+	var req reverseTransferRequest
+
+	if err := ctx.ShouldBindUri(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
-		return account, false
+		return
 	}
 
-	return account, true
+	setAuditResource(ctx, "transfer_id", req.ID)
+
+	result, err := server.store.ReverseTransferTx(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		if errors.Is(err, db.ErrTransferAlreadyReversed) {
+			ctx.JSON(http.StatusConflict, errorCodeResponse(errCodeTransferAlreadyReversed, err))
+			return
+		}
+
+		if errors.Is(err, db.ErrTransferNotReversible) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeTransferNotReversible, err))
+			return
+		}
+
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeInsufficientFunds, err))
+			return
+		}
+
+		if errors.Is(err, db.ErrAccountFrozen) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeAccountFrozen, err))
+			return
+		}
+
+		if errors.Is(err, db.ErrAccountClosed) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorCodeResponse(errCodeAccountClosed, err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	setAuditResource(ctx, "reversal_transfer_id", result.Transfer.ID)
+
+	ctx.JSON(http.StatusOK, transferTxResponse{
+		TransferTxResult: result,
+		AmountMoney:      util.Money{Amount: result.Transfer.Amount, Currency: result.FromAccount.Currency},
+		ToAmountMoney:    util.Money{Amount: result.Transfer.ToAmount, Currency: result.ToAccount.Currency},
+	})
+}
+
+type listAccountTransfersURI struct {
+	AccountID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type listAccountTransfersQuery struct {
+	// PageID selects offset pagination, without it the list is paginated with cursors
+	PageID         int32  `form:"page_id" binding:"omitempty,min=1"`
+	PageSize       int32  `form:"page_size" binding:"required,min=5,max=20"`
+	Cursor         string `form:"cursor"`
+	CounterpartyID int64  `form:"counterparty_id" binding:"omitempty,min=1"`
+	MinAmount      int64  `form:"min_amount" binding:"omitempty,gt=0"`
+	MaxAmount      int64  `form:"max_amount" binding:"omitempty,gt=0"`
+}
+
+type listAccountTransfersResponse struct {
+	Transfers  []transferResponse `json:"transfers"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+	PrevCursor string             `json:"prev_cursor,omitempty"`
+}
+
+func (server *Server) listAccountTransfers(ctx *gin.Context) {
+	var uri listAccountTransfersURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req listAccountTransfersQuery
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.MinAmount > 0 && req.MaxAmount > 0 && req.MinAmount > req.MaxAmount {
+		err := errors.New("min_amount must not be greater than max_amount")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	cursor, err := parsePagination(req.PageID, req.Cursor)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, err := server.store.GetAccount(ctx, uri.AccountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// get the token payload from the middleware
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	if !canReadAccount(authPayload, account) {
+		err := errors.New("account does not belong to the user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	counterpartyID := sql.NullInt64{Int64: req.CounterpartyID, Valid: req.CounterpartyID > 0}
+	minAmount := sql.NullInt64{Int64: req.MinAmount, Valid: req.MinAmount > 0}
+	maxAmount := sql.NullInt64{Int64: req.MaxAmount, Valid: req.MaxAmount > 0}
+
+	if req.PageID > 0 {
+		args := db.ListAccountTransfersParams{
+			AccountID:      account.ID,
+			CounterpartyID: counterpartyID,
+			MinAmount:      minAmount,
+			MaxAmount:      maxAmount,
+			PageLimit:      req.PageSize,
+			PageOffset:     (req.PageID - 1) * req.PageSize,
+		}
+
+		transfers, err := server.store.ListAccountTransfers(ctx, args)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusOK, newTransferResponses(account.ID, transfers))
+		return
+	}
+
+	page, err := fetchKeysetPage(
+		cursor,
+		req.PageSize,
+		func(createdAt time.Time, id int64, limit int32) ([]db.Transfer, error) {
+			return server.store.ListAccountTransfersAfter(ctx, db.ListAccountTransfersAfterParams{
+				AccountID:       account.ID,
+				CounterpartyID:  counterpartyID,
+				MinAmount:       minAmount,
+				MaxAmount:       maxAmount,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				PageLimit:       limit,
+			})
+		},
+		func(createdAt time.Time, id int64, limit int32) ([]db.Transfer, error) {
+			return server.store.ListAccountTransfersBefore(ctx, db.ListAccountTransfersBeforeParams{
+				AccountID:       account.ID,
+				CounterpartyID:  counterpartyID,
+				MinAmount:       minAmount,
+				MaxAmount:       maxAmount,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				PageLimit:       limit,
+			})
+		},
+		func(transfer db.Transfer) (time.Time, int64) {
+			return transfer.CreatedAt, transfer.ID
+		},
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, listAccountTransfersResponse{
+		Transfers:  newTransferResponses(account.ID, page.Items),
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	})
+}
+
+// newTransferResponses tells for each transfer if the account sent or received it
+func newTransferResponses(accountID int64, transfers []db.Transfer) []transferResponse {
+	res := make([]transferResponse, 0, len(transfers))
+	for _, transfer := range transfers {
+		direction := transferDirectionReceived
+		if transfer.FromAccountID == accountID {
+			direction = transferDirectionSent
+		}
+
+		res = append(res, transferResponse{Transfer: transfer, Direction: direction})
+	}
+
+	return res
 }
@@ -2,11 +2,14 @@ package api
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/token"
 	"github.com/aybarsacar/simplebank/util"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/lib/pq"
-	"log"
 	"net/http"
 	"time"
 )
@@ -24,18 +27,30 @@ type userResponse struct {
 	Username          string    `json:"username"`
 	FullName          string    `json:"full_name"`
 	Email             string    `json:"email"`
+	Role              string    `json:"role"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	// only set while the user is locked out after too many failed logins
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
 }
 
 func newUserResponse(user db.User) userResponse {
-	return userResponse{
+	res := userResponse{
 		Username:          user.Username,
 		FullName:          user.FullName,
 		Email:             user.Email,
+		Role:              user.Role,
+		IsEmailVerified:   user.IsEmailVerified,
 		PasswordChangedAt: user.PasswordChangedAt,
 		CreatedAt:         user.CreatedAt,
 	}
+
+	if util.LockedOut(user.LockedUntil) {
+		res.LockedUntil = &user.LockedUntil.Time
+	}
+
+	return res
 }
 
 func (server *Server) createUser(ctx *gin.Context) {
@@ -47,6 +62,8 @@ func (server *Server) createUser(ctx *gin.Context) {
 		return
 	}
 
+	setAuditActor(ctx, req.Username)
+
 	hashedPassword, err := util.HashPassword(req.Password)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
@@ -61,10 +78,10 @@ func (server *Server) createUser(ctx *gin.Context) {
 		Email:          req.Email,
 	}
 
-	user, err := server.store.CreateUser(ctx, args)
+	// the email is verified with the link of a mail the verify email sender sends after the user is created
+	result, err := server.store.CreateUserTx(ctx, args)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok {
-			log.Println(pqErr.Code.Name())
 			switch pqErr.Code.Name() {
 			case "unique_violation": // if the user with the same username or email exists
 				ctx.JSON(http.StatusForbidden, errorResponse(err))
@@ -77,7 +94,7 @@ func (server *Server) createUser(ctx *gin.Context) {
 	}
 
 	// user is successfully created - send the user back to client
-	response := newUserResponse(user)
+	response := newUserResponse(result.User)
 
 	ctx.JSON(http.StatusOK, response)
 }
@@ -88,8 +105,12 @@ type loginUserRequest struct {
 }
 
 type loginUserResponse struct {
-	AccessToken string       `json:"access_token"`
-	User        userResponse `json:"user"`
+	SessionID             uuid.UUID    `json:"session_id"`
+	AccessToken           string       `json:"access_token"`
+	AccessTokenExpiresAt  time.Time    `json:"access_token_expires_at"`
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+	User                  userResponse `json:"user"`
 }
 
 func (server *Server) loginUser(ctx *gin.Context) {
@@ -99,6 +120,8 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		return
 	}
 
+	setAuditActor(ctx, req.Username)
+
 	user, err := server.store.GetUser(ctx, req.Username)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -110,25 +133,150 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		return
 	}
 
+	// a locked user can't log in even with the correct password, so the password can't be guessed while locked
+	if util.LockedOut(user.LockedUntil) {
+		ctx.JSON(http.StatusLocked, errorCodeResponse(errCodeUserLocked, errUserLocked(user)))
+		return
+	}
+
 	// check if hte password is correct
 	err = util.CheckPassword(req.Password, user.HashedPassword)
 	if err != nil {
-		// wrong password is provided
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		server.failLogin(ctx, user, err)
 		return
 	}
 
+	// a successful login resets the failed attempts
+	if user.FailedLoginAttempts > 0 || user.LockedUntil.Valid {
+		user, err = server.store.UnlockUser(ctx, user.Username)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
 	// correct credentials log the user in
-	accessToken, err := server.tokenMaker.CreateToken(user.Username, server.config.AccessTokenDuration)
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, server.config.AccessTokenDuration, token.TokenTypeAccessToken)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// the long-lived refresh token is used to get new access tokens without logging in again
+	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, server.config.RefreshTokenDuration, token.TokenTypeRefreshToken)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
+	session, err := server.store.CreateSession(ctx, db.CreateSessionParams{
+		ID:           refreshPayload.ID,
+		Username:     user.Username,
+		RefreshToken: refreshToken,
+		UserAgent:    ctx.Request.UserAgent(),
+		ClientIp:     ctx.ClientIP(),
+		IsBlocked:    false,
+		ExpiresAt:    refreshPayload.ExpiresAt,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	setAuditResource(ctx, "session_id", session.ID)
+
 	res := loginUserResponse{
-		AccessToken: accessToken,
-		User:        newUserResponse(user),
+		SessionID:             session.ID,
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessPayload.ExpiresAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshPayload.ExpiresAt,
+		User:                  newUserResponse(user),
 	}
 
 	ctx.JSON(http.StatusOK, res)
 }
+
+// failLogin counts the failed login of the user, the user is locked out once there are too many
+// the login that locks the user out gets the error of a locked user and is recorded in the audit trail
+func (server *Server) failLogin(ctx *gin.Context, user db.User, passwordErr error) {
+	lockout := server.config.LoginLockout()
+
+	user, err := server.store.RecordFailedLogin(ctx, db.RecordFailedLoginParams{
+		MaxAttempts:       int32(lockout.MaxAttempts),
+		LockoutSeconds:    lockout.Duration.Seconds(),
+		MaxLockoutSeconds: lockout.MaxDuration.Seconds(),
+		Username:          user.Username,
+	})
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !util.LockedOut(user.LockedUntil) {
+		// wrong password is provided
+		ctx.JSON(http.StatusUnauthorized, errorResponse(passwordErr))
+		return
+	}
+
+	ctx.JSON(http.StatusLocked, errorCodeResponse(errCodeUserLocked, errUserLocked(user)))
+
+	setAuditResource(ctx, "locked_until", user.LockedUntil.Time)
+	recordAuditEvent(ctx, server.store, auditActionLockUser, auditOutcomeSuccess, http.StatusLocked)
+}
+
+func errUserLocked(user db.User) error {
+	return fmt.Errorf("user is locked until %s after too many failed logins", user.LockedUntil.Time.UTC().Format(time.RFC3339))
+}
+
+type logoutUserRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// logoutUser revokes the access token of the request and the session of the refresh token
+func (server *Server) logoutUser(ctx *gin.Context) {
+	var req logoutUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// get the token payload from the middleware
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	refreshPayload, err := server.tokenMaker.VerifyToken(req.RefreshToken, token.TokenTypeRefreshToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	setAuditResource(ctx, "session_id", refreshPayload.ID)
+
+	if refreshPayload.Username != authPayload.Username {
+		err := errors.New("refresh token does not belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	err = server.store.LogoutTx(ctx, db.LogoutTxParams{
+		Username:             authPayload.Username,
+		AccessTokenID:        authPayload.ID,
+		AccessTokenExpiresAt: authPayload.ExpiresAt,
+		SessionID:            uuid.NullUUID{UUID: refreshPayload.ID, Valid: true},
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.denylist.Revoked(authPayload.ID, authPayload.ExpiresAt)
+	server.denylist.Revoked(refreshPayload.ID, refreshPayload.ExpiresAt)
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -2,15 +2,36 @@ package api
 
 import (
 	"github.com/aybarsacar/simplebank/util"
+	"github.com/aybarsacar/simplebank/webhook"
 	"github.com/go-playground/validator/v10"
 )
 
 var validCurrency validator.Func = func(fieldLevel validator.FieldLevel) bool {
 
 	if currency, ok := fieldLevel.Field().Interface().(string); ok {
-		// check currency is supported or not
+		// check the currency is in the registry, the money in the accounts of a disabled currency can still be moved out
+		return util.IsKnownCurrency(currency)
+	}
+
+	return false
+}
+
+var validEnabledCurrency validator.Func = func(fieldLevel validator.FieldLevel) bool {
+
+	if currency, ok := fieldLevel.Field().Interface().(string); ok {
+		// check the currency is in the registry and enabled, e.g. for new accounts
 		return util.IsSupportedCurrency(currency)
 	}
 
 	return false
 }
+
+var validWebhookURL validator.Func = func(fieldLevel validator.FieldLevel) bool {
+
+	if rawURL, ok := fieldLevel.Field().Interface().(string); ok {
+		// only https urls, the addresses they resolve to are checked when the deliveries are sent
+		return webhook.ValidateURL(rawURL) == nil
+	}
+
+	return false
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/token"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type verifyEmailRequest struct {
+	ID   int64  `form:"id" binding:"required,min=1"`
+	Code string `form:"code" binding:"required"`
+}
+
+// verifyEmail marks the email of a user as verified with the id and the secret code of the link in the verification mail
+func (server *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	setAuditResource(ctx, "verify_email_id", req.ID)
+
+	result, err := server.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
+		ID:             req.ID,
+		SecretCodeHash: util.HashSecret(req.Code),
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidVerifyEmail) {
+			ctx.JSON(http.StatusBadRequest, errorCodeResponse(errCodeInvalidVerifyEmail, err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	setAuditActor(ctx, result.User.Username)
+
+	ctx.JSON(http.StatusOK, newUserResponse(result.User))
+}
+
+// resendVerifyEmail queues another verification mail for the authenticated user, e.g. when the first one expired
+// the links of the earlier mails can still be used until they expire
+func (server *Server) resendVerifyEmail(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.IsEmailVerified {
+		err := errors.New("email is already verified")
+		ctx.JSON(http.StatusConflict, errorCodeResponse(errCodeEmailAlreadyVerified, err))
+		return
+	}
+
+	verifyEmail, err := server.store.CreateVerifyEmail(ctx, db.CreateVerifyEmailParams{
+		Username: user.Username,
+		Email:    user.Email,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	setAuditResource(ctx, "verify_email_id", verifyEmail.ID)
+
+	ctx.Status(http.StatusAccepted)
+}
+
+// requireVerifiedEmail only lets through the users that verified their email
+// it must run after the authMiddleware, so the token payload is in the context
+func (server *Server) requireVerifiedEmail() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+		verified, err := server.store.IsUserEmailVerified(ctx, payload.Username)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		if !verified {
+			err := errors.New("email must be verified first, follow the link in the verification mail")
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorCodeResponse(errCodeEmailNotVerified, err))
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,268 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/token"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+type webhookSubscriptionResponse struct {
+	ID        int64     `json:"id"`
+	Url       string    `json:"url"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+	// Secret is only returned when the subscription is created
+	Secret string `json:"secret,omitempty"`
+}
+
+func newWebhookSubscriptionResponse(subscription db.WebhookSubscription) webhookSubscriptionResponse {
+	return webhookSubscriptionResponse{
+		ID:        subscription.ID,
+		Url:       subscription.Url,
+		Events:    subscription.Events,
+		CreatedAt: subscription.CreatedAt,
+	}
+}
+
+type webhookDeliveryResponse struct {
+	ID             int64      `json:"id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int32      `json:"attempts"`
+	LastError      string     `json:"last_error,omitempty"`
+	ResponseStatus *int32     `json:"response_status,omitempty"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func newWebhookDeliveryResponse(delivery db.WebhookDelivery) webhookDeliveryResponse {
+	res := webhookDeliveryResponse{
+		ID:            delivery.ID,
+		EventType:     delivery.EventType,
+		Status:        delivery.Status,
+		Attempts:      delivery.Attempts,
+		LastError:     delivery.LastError,
+		NextAttemptAt: delivery.NextAttemptAt,
+		CreatedAt:     delivery.CreatedAt,
+	}
+
+	if delivery.ResponseStatus.Valid {
+		res.ResponseStatus = &delivery.ResponseStatus.Int32
+	}
+
+	if delivery.DeliveredAt.Valid {
+		res.DeliveredAt = &delivery.DeliveredAt.Time
+	}
+
+	return res
+}
+
+type createWebhookSubscriptionRequest struct {
+	Url    string   `json:"url" binding:"required,webhook_url,max=2048"`
+	Events []string `json:"events" binding:"required,min=1,dive,oneof=account.credited account.debited"`
+	// Secret signs the deliveries, a random one is generated when it is empty
+	Secret string `json:"secret" binding:"omitempty,min=16,max=256"`
+}
+
+// createWebhookSubscription subscribes the user to the events of their accounts
+func (server *Server) createWebhookSubscription(ctx *gin.Context) {
+	var req createWebhookSubscriptionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		secret, err = util.NewSecret()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	subscription, err := server.store.CreateWebhookSubscription(ctx, db.CreateWebhookSubscriptionParams{
+		Owner:  authPayload.Username,
+		Url:    req.Url,
+		Events: req.Events,
+		Secret: secret,
+	})
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	setAuditResource(ctx, "subscription_id", subscription.ID)
+
+	res := newWebhookSubscriptionResponse(subscription)
+	res.Secret = subscription.Secret
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (server *Server) listWebhookSubscriptions(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	subscriptions, err := server.store.ListWebhookSubscriptions(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	res := make([]webhookSubscriptionResponse, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		res = append(res, newWebhookSubscriptionResponse(subscription))
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+type webhookSubscriptionURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// deleteWebhookSubscription removes the subscription together with its delivery log
+func (server *Server) deleteWebhookSubscription(ctx *gin.Context) {
+	var uri webhookSubscriptionURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	setAuditResource(ctx, "subscription_id", uri.ID)
+
+	subscription, ok := server.getOwnWebhookSubscription(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	if err := server.store.DeleteWebhookSubscription(ctx, subscription.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type listWebhookDeliveriesRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=100"`
+}
+
+// listWebhookDeliveries returns the delivery log of a subscription, the newest first
+func (server *Server) listWebhookDeliveries(ctx *gin.Context) {
+	var uri webhookSubscriptionURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req listWebhookDeliveriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	subscription, ok := server.getOwnWebhookSubscription(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	deliveries, err := server.store.ListWebhookDeliveries(ctx, db.ListWebhookDeliveriesParams{
+		SubscriptionID: subscription.ID,
+		PageLimit:      req.PageSize,
+		PageOffset:     (req.PageID - 1) * req.PageSize,
+	})
+
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	res := make([]webhookDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		res = append(res, newWebhookDeliveryResponse(delivery))
+	}
+
+	ctx.JSON(http.StatusOK, res)
+}
+
+type replayWebhookDeliveryURI struct {
+	ID         int64 `uri:"id" binding:"required,min=1"`
+	DeliveryID int64 `uri:"delivery_id" binding:"required,min=1"`
+}
+
+// replayWebhookDelivery sends a delivery again, e.g. one that failed or that the subscriber lost
+// it is sent with the same id, so the subscriber can tell it apart from a new event
+func (server *Server) replayWebhookDelivery(ctx *gin.Context) {
+	var uri replayWebhookDeliveryURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	setAuditResource(ctx, "subscription_id", uri.ID)
+	setAuditResource(ctx, "delivery_id", uri.DeliveryID)
+
+	subscription, ok := server.getOwnWebhookSubscription(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	delivery, err := server.store.GetWebhookDelivery(ctx, uri.DeliveryID)
+	if err == nil && delivery.SubscriptionID != subscription.ID {
+		err = sql.ErrNoRows
+	}
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	delivery, err = server.store.ReplayWebhookDelivery(ctx, delivery.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newWebhookDeliveryResponse(delivery))
+}
+
+// getOwnWebhookSubscription gets a subscription of the user, it writes the error response when it returns false
+func (server *Server) getOwnWebhookSubscription(ctx *gin.Context, id int64) (db.WebhookSubscription, bool) {
+	subscription, err := server.store.GetWebhookSubscription(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return subscription, false
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return subscription, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	if subscription.Owner != authPayload.Username {
+		err := errors.New("webhook subscription does not belong to the user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return subscription, false
+	}
+
+	return subscription, true
+}
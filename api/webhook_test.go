@@ -0,0 +1,286 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/aybarsacar/simplebank/db/dbtest"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/aybarsacar/simplebank/webhook"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCreateWebhookSubscriptionAPI(t *testing.T) {
+	username := util.RandomOwner()
+
+	subscription := db.WebhookSubscription{
+		ID:        util.RandomInt(1, 1000),
+		Owner:     username,
+		Url:       "https://example.com/webhooks",
+		Events:    []string{webhook.EventAccountCredited},
+		Secret:    util.RandomString(32),
+		CreatedAt: time.Now(),
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"url":    subscription.Url,
+				"events": subscription.Events,
+				"secret": subscription.Secret,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				args := db.CreateWebhookSubscriptionParams{
+					Owner:  username,
+					Url:    subscription.Url,
+					Events: subscription.Events,
+					Secret: subscription.Secret,
+				}
+
+				store.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Eq(args)).Times(1).Return(subscription, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res webhookSubscriptionResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &res)
+				require.NoError(t, err)
+				require.Equal(t, subscription.ID, res.ID)
+				require.Equal(t, subscription.Secret, res.Secret)
+			},
+		},
+		{
+			name: "GeneratedSecret",
+			body: gin.H{
+				"url":    subscription.Url,
+				"events": subscription.Events,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateWebhookSubscription(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, args db.CreateWebhookSubscriptionParams) (db.WebhookSubscription, error) {
+						require.Len(t, args.Secret, 64)
+						return subscription, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidEvent",
+			body: gin.H{
+				"url":    subscription.Url,
+				"events": []string{"account.created"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidURL",
+			body: gin.H{
+				"url":    "not a url",
+				"events": subscription.Events,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InsecureURL",
+			body: gin.H{
+				"url":    "http://example.com/webhooks",
+				"events": subscription.Events,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(testCase.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/api/v1/webhooks", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			dbtest.ExpectAuthorized(store, username)
+			dbtest.ExpectAuditEvent(store)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, username, util.CustomerRole, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestReplayWebhookDeliveryAPI(t *testing.T) {
+	username := util.RandomOwner()
+
+	subscription := db.WebhookSubscription{
+		ID:     util.RandomInt(1, 1000),
+		Owner:  username,
+		Url:    "https://example.com/webhooks",
+		Events: []string{webhook.EventAccountDebited},
+	}
+
+	delivery := db.WebhookDelivery{
+		ID:             util.RandomInt(1, 1000),
+		SubscriptionID: subscription.ID,
+		EventType:      webhook.EventAccountDebited,
+		Status:         webhook.DeliveryStatusFailed,
+		Attempts:       10,
+		ResponseStatus: sql.NullInt32{Int32: http.StatusInternalServerError, Valid: true},
+	}
+
+	replayed := delivery
+	replayed.Status = webhook.DeliveryStatusPending
+
+	testCases := []struct {
+		name          string
+		username      string
+		deliveryID    int64
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "OK",
+			username:   username,
+			deliveryID: delivery.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).Times(1).Return(subscription, nil)
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).Times(1).Return(delivery, nil)
+				store.EXPECT().ReplayWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).Times(1).Return(replayed, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var res webhookDeliveryResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &res)
+				require.NoError(t, err)
+				require.Equal(t, webhook.DeliveryStatusPending, res.Status)
+				require.Equal(t, int32(http.StatusInternalServerError), *res.ResponseStatus)
+			},
+		},
+		{
+			name:       "UnauthorizedUser",
+			username:   util.RandomOwner(),
+			deliveryID: delivery.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).Times(1).Return(subscription, nil)
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ReplayWebhookDelivery(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:       "DeliveryOfAnotherSubscription",
+			username:   username,
+			deliveryID: delivery.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				other := delivery
+				other.SubscriptionID = subscription.ID + 1
+
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).Times(1).Return(subscription, nil)
+				store.EXPECT().GetWebhookDelivery(gomock.Any(), gomock.Eq(delivery.ID)).Times(1).Return(other, nil)
+				store.EXPECT().ReplayWebhookDelivery(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:       "SubscriptionNotFound",
+			username:   username,
+			deliveryID: delivery.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Any()).Times(1).Return(db.WebhookSubscription{}, sql.ErrNoRows)
+				store.EXPECT().ReplayWebhookDelivery(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:       "InvalidDeliveryID",
+			username:   username,
+			deliveryID: 0,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/api/v1/webhooks/%d/deliveries/%d/replay", subscription.ID, testCase.deliveryID)
+
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			dbtest.ExpectAuthorized(store, testCase.username)
+			dbtest.ExpectAuditEvent(store)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, testCase.username, util.CustomerRole, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+
+			testCase.checkResponse(t, recorder)
+		})
+	}
+}
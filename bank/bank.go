@@ -0,0 +1,83 @@
+package bank
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/fx"
+	"github.com/aybarsacar/simplebank/util"
+)
+
+// the checks the HTTP and the gRPC api share, each api maps the errors to its own status codes
+var (
+	ErrAccountNotFound       = errors.New("account not found")
+	ErrCurrencyMismatch      = errors.New("account currency mismatch")
+	ErrCrossCurrencyDisabled = errors.New("transfers between accounts of different currencies are not enabled")
+)
+
+// LoadCurrencies fills the currency registry from the database
+// each process keeps its own copy, a currency toggled on another server is seen after the next refresh
+func LoadCurrencies(ctx context.Context, store db.Store) error {
+	currencies, err := store.ListCurrencies(ctx)
+	if err != nil {
+		return err
+	}
+
+	registry := make([]util.Currency, 0, len(currencies))
+	for _, currency := range currencies {
+		registry = append(registry, RegistryCurrency(currency))
+	}
+
+	util.Currencies.Load(registry)
+
+	return nil
+}
+
+// RegistryCurrency converts a currency of the database to the currency kept in the registry
+func RegistryCurrency(currency db.Currency) util.Currency {
+	return util.Currency{
+		Code:       currency.Code,
+		MinorUnits: int(currency.MinorUnits),
+		Symbol:     currency.Symbol,
+		Enabled:    currency.Enabled,
+	}
+}
+
+// ValidAccount checks the account exists and its currency matches the currency of the request
+func ValidAccount(ctx context.Context, store db.Store, accountID int64, currency string) (db.Account, error) {
+	account, err := store.GetAccount(ctx, accountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return account, fmt.Errorf("%w: [%d]", ErrAccountNotFound, accountID)
+		}
+
+		return account, fmt.Errorf("failed to get account: %w", err)
+	}
+
+	if account.Currency != currency {
+		return account, fmt.Errorf("%w: [%d] %s vs %s", ErrCurrencyMismatch, account.ID, account.Currency, currency)
+	}
+
+	return account, nil
+}
+
+// ExchangeRate gets the rate to convert between the currencies of the accounts of a transfer,
+// the rates are nil when the transfers between currencies are not enabled
+func ExchangeRate(ctx context.Context, rates fx.RateProvider, from string, to string) (fx.Rate, error) {
+	if rates == nil {
+		return fx.Rate{}, ErrCrossCurrencyDisabled
+	}
+
+	rate, err := rates.Rate(ctx, from, to)
+	if err != nil {
+		if errors.Is(err, fx.ErrUnsupportedCurrency) {
+			return rate, err
+		}
+
+		return rate, fmt.Errorf("failed to get exchange rate: %w", err)
+	}
+
+	return rate, nil
+}
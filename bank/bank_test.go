@@ -0,0 +1,102 @@
+package bank
+
+import (
+	"context"
+	"database/sql"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/fx"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestValidAccount(t *testing.T) {
+	account := db.Account{
+		ID:       util.RandomInt(1, 1000),
+		Owner:    util.RandomOwner(),
+		Currency: util.USD,
+	}
+
+	testCases := []struct {
+		name       string
+		currency   string
+		buildStubs func(store *mockdb.MockStore)
+		checkError func(t *testing.T, err error)
+	}{
+		{
+			name:     "OK",
+			currency: util.USD,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:     "NotFound",
+			currency: util.USD,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, ErrAccountNotFound)
+			},
+		},
+		{
+			name:     "CurrencyMismatch",
+			currency: util.EUR,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, ErrCurrencyMismatch)
+			},
+		},
+		{
+			name:     "InternalError",
+			currency: util.USD,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(db.Account{}, sql.ErrConnDone)
+			},
+			checkError: func(t *testing.T, err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+				require.NotErrorIs(t, err, ErrAccountNotFound)
+			},
+		},
+	}
+
+	for i := range testCases {
+		testCase := testCases[i]
+
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			testCase.buildStubs(store)
+
+			_, err := ValidAccount(context.Background(), store, account.ID, testCase.currency)
+			testCase.checkError(t, err)
+		})
+	}
+}
+
+func TestExchangeRate(t *testing.T) {
+	rates, err := fx.NewStaticRateProvider(util.USD, map[string]string{util.EUR: "0.9"})
+	require.NoError(t, err)
+
+	rate, err := ExchangeRate(context.Background(), rates, util.USD, util.EUR)
+	require.NoError(t, err)
+	require.False(t, rate.IsZero())
+
+	_, err = ExchangeRate(context.Background(), rates, util.USD, util.JPY)
+	require.ErrorIs(t, err, fx.ErrUnsupportedCurrency)
+
+	// a server without rates only moves money between accounts of the same currency
+	_, err = ExchangeRate(context.Background(), nil, util.USD, util.EUR)
+	require.ErrorIs(t, err, ErrCrossCurrencyDisabled)
+}
@@ -0,0 +1,69 @@
+package dbtest
+
+import (
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"time"
+)
+
+// the helpers in this file set the expectations on the mock store that the tests of the HTTP and the gRPC servers share
+
+// DefaultCurrencies are the currencies the database is seeded with
+func DefaultCurrencies() []db.Currency {
+	currencies := make([]db.Currency, 0, len(util.DefaultCurrencies))
+	for _, currency := range util.DefaultCurrencies {
+		currencies = append(currencies, db.Currency{
+			Code:       currency.Code,
+			MinorUnits: int32(currency.MinorUnits),
+			Symbol:     currency.Symbol,
+			Enabled:    currency.Enabled,
+		})
+	}
+
+	return currencies
+}
+
+// ExpectLoadCurrencies expects the default currencies to be loaded once, like a new server does
+func ExpectLoadCurrencies(store *mockdb.MockStore) *gomock.Call {
+	return store.
+		EXPECT().
+		ListCurrencies(gomock.Any()).
+		Times(1).
+		Return(DefaultCurrencies(), nil)
+}
+
+// ExpectAuthorized expects the token of one request of the user to be checked against the denylist,
+// the token is not revoked and the password and the role of the user never changed
+func ExpectAuthorized(store *mockdb.MockStore, username string) {
+	store.
+		EXPECT().
+		IsTokenRevoked(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(false, nil)
+
+	store.
+		EXPECT().
+		GetUserCredentialsChangedAt(gomock.Any(), gomock.Eq(username)).
+		Times(1).
+		Return(time.Time{}, nil)
+}
+
+// ExpectVerifiedEmail expects the email of the user to be checked once, it is verified
+func ExpectVerifiedEmail(store *mockdb.MockStore, username string) *gomock.Call {
+	return store.
+		EXPECT().
+		IsUserEmailVerified(gomock.Any(), gomock.Eq(username)).
+		Times(1).
+		Return(true, nil)
+}
+
+// ExpectAuditEvent expects one audit event to be written
+func ExpectAuditEvent(store *mockdb.MockStore) *gomock.Call {
+	return store.
+		EXPECT().
+		CreateAuditEvent(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(db.AuditEvent{}, nil)
+}
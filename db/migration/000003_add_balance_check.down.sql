@@ -0,0 +1,2 @@
+ALTER TABLE IF EXISTS "accounts"
+    DROP CONSTRAINT IF EXISTS "balance_non_negative";
@@ -0,0 +1,4 @@
+-- NOT VALID so the migration doesn't fail on the negative balances the overdraft bug left behind,
+-- the new balances are checked right away and 000020 validates the existing ones after writing them off
+ALTER TABLE "accounts"
+    ADD CONSTRAINT "balance_non_negative" CHECK ("balance" >= 0) NOT VALID;
@@ -0,0 +1,16 @@
+CREATE TABLE "revoked_tokens"
+(
+    "id"         uuid PRIMARY KEY,
+    "username"   varchar     NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "revoked_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "revoked_tokens"
+    ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "sessions" ("username");
+
+COMMENT ON COLUMN "revoked_tokens"."id" IS 'id of the revoked token payload, for refresh tokens this is the session id';
+
+COMMENT ON COLUMN "revoked_tokens"."expires_at" IS 'the row can be removed once the token has expired';
@@ -0,0 +1,21 @@
+CREATE INDEX ON "accounts" ("owner", "created_at", "id");
+
+CREATE INDEX ON "entries" ("account_id", "created_at", "id");
+
+CREATE INDEX ON "transfers" ("from_account_id", "created_at", "id");
+
+CREATE INDEX ON "transfers" ("to_account_id", "created_at", "id");
+
+-- every entry with the balance of the account right after it
+-- the balance is anchored on the current balance, so accounts created with an opening balance are correct too
+CREATE VIEW "account_statements" AS
+SELECT "entries"."id",
+       "entries"."account_id",
+       "entries"."amount",
+       "entries"."created_at",
+       ("accounts"."balance" -
+        SUM("entries"."amount")
+        OVER (PARTITION BY "entries"."account_id" ORDER BY "entries"."created_at" DESC, "entries"."id" DESC) +
+        "entries"."amount")::bigint AS "balance_after"
+FROM "entries"
+         JOIN "accounts" ON "accounts"."id" = "entries"."account_id";
@@ -0,0 +1,37 @@
+CREATE TABLE "external_transactions"
+(
+    "id"                 bigserial PRIMARY KEY,
+    "account_id"         bigint      NOT NULL,
+    "entry_id"           bigint      NOT NULL,
+    "kind"               varchar     NOT NULL,
+    "amount"             bigint      NOT NULL,
+    "source"             varchar     NOT NULL,
+    "external_reference" varchar     NOT NULL,
+    "created_at"         timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "external_transactions"
+    ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "external_transactions"
+    ADD FOREIGN KEY ("entry_id") REFERENCES "entries" ("id");
+
+ALTER TABLE "external_transactions"
+    ADD CONSTRAINT "external_transactions_kind_check" CHECK ("kind" IN ('deposit', 'withdrawal'));
+
+ALTER TABLE "external_transactions"
+    ADD CONSTRAINT "external_transactions_amount_check" CHECK ("amount" > 0);
+
+-- an integration can post the same reference only once
+ALTER TABLE "external_transactions"
+    ADD CONSTRAINT "source_external_reference_key" UNIQUE ("source", "external_reference");
+
+CREATE INDEX ON "external_transactions" ("account_id");
+
+COMMENT ON COLUMN "external_transactions"."kind" IS 'deposit or withdrawal';
+
+COMMENT ON COLUMN "external_transactions"."amount" IS 'must be positive, the kind tells the direction';
+
+COMMENT ON COLUMN "external_transactions"."source" IS 'the integration that moved the money, e.g. card_processor';
+
+COMMENT ON COLUMN "external_transactions"."external_reference" IS 'id of the transaction in the source system';
@@ -0,0 +1,5 @@
+ALTER TABLE "transfers"
+    DROP COLUMN IF EXISTS "exchange_rate";
+
+ALTER TABLE "transfers"
+    DROP COLUMN IF EXISTS "to_amount";
@@ -0,0 +1,16 @@
+ALTER TABLE "transfers"
+    ADD COLUMN "to_amount" bigint;
+
+ALTER TABLE "transfers"
+    ADD COLUMN "exchange_rate" numeric NOT NULL DEFAULT 1;
+
+-- transfers made so far were between accounts of the same currency
+UPDATE "transfers"
+SET "to_amount" = "amount";
+
+ALTER TABLE "transfers"
+    ALTER COLUMN "to_amount" SET NOT NULL;
+
+COMMENT ON COLUMN "transfers"."to_amount" IS 'amount credited to the to account, in its currency';
+
+COMMENT ON COLUMN "transfers"."exchange_rate" IS 'to_amount = amount * exchange_rate, rounded down';
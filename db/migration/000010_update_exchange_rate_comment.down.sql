@@ -0,0 +1 @@
+COMMENT ON COLUMN "transfers"."exchange_rate" IS 'to_amount = amount * exchange_rate, rounded down';
@@ -0,0 +1,2 @@
+-- the rate is the price of one unit of the from currency, the amounts are in minor units of different decimal places
+COMMENT ON COLUMN "transfers"."exchange_rate" IS 'units of the to currency for one unit of the from currency, to_amount is rounded down';
@@ -0,0 +1,24 @@
+CREATE TABLE "currencies"
+(
+    "code"        varchar(3) PRIMARY KEY,
+    "minor_units" int         NOT NULL,
+    "symbol"      varchar     NOT NULL,
+    "enabled"     boolean     NOT NULL DEFAULT true,
+    "updated_at"  timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "currencies"."code" IS 'ISO 4217 code';
+
+COMMENT ON COLUMN "currencies"."minor_units" IS 'number of decimal places, e.g. 2 for cents';
+
+COMMENT ON COLUMN "currencies"."enabled" IS 'only enabled currencies can be used for new accounts and transfers';
+
+INSERT INTO "currencies" ("code", "minor_units", "symbol")
+VALUES ('AUD', 2, 'A$'),
+       ('CAD', 2, 'C$'),
+       ('EUR', 2, '€'),
+       ('JPY', 0, '¥'),
+       ('USD', 2, '$');
+
+ALTER TABLE "accounts"
+    ADD FOREIGN KEY ("currency") REFERENCES "currencies" ("code");
@@ -0,0 +1,19 @@
+ALTER TABLE "users"
+    ADD COLUMN "role" varchar NOT NULL DEFAULT 'customer';
+
+ALTER TABLE "users"
+    ADD CONSTRAINT "users_role_check" CHECK ("role" IN ('customer', 'teller', 'admin'));
+
+COMMENT ON COLUMN "users"."role" IS 'customer, teller or admin, tellers and admins are bank staff';
+
+-- a transfer can be reversed by the bank staff only once
+ALTER TABLE "transfers"
+    ADD COLUMN "reverses_transfer_id" bigint;
+
+ALTER TABLE "transfers"
+    ADD CONSTRAINT "transfers_reverses_transfer_id_key" UNIQUE ("reverses_transfer_id");
+
+ALTER TABLE "transfers"
+    ADD FOREIGN KEY ("reverses_transfer_id") REFERENCES "transfers" ("id");
+
+COMMENT ON COLUMN "transfers"."reverses_transfer_id" IS 'the transfer this one reverses';
@@ -0,0 +1,5 @@
+ALTER TABLE "accounts"
+    DROP COLUMN IF EXISTS "status_reason";
+
+ALTER TABLE "accounts"
+    DROP COLUMN IF EXISTS "status";
@@ -0,0 +1,12 @@
+ALTER TABLE "accounts"
+    ADD COLUMN "status" varchar NOT NULL DEFAULT 'active';
+
+ALTER TABLE "accounts"
+    ADD CONSTRAINT "accounts_status_check" CHECK ("status" IN ('active', 'frozen', 'closed'));
+
+ALTER TABLE "accounts"
+    ADD COLUMN "status_reason" varchar NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN "accounts"."status" IS 'frozen accounts can only be credited, closed accounts can not be debited or credited';
+
+COMMENT ON COLUMN "accounts"."status_reason" IS 'why the status was last changed';
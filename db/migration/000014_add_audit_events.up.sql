@@ -0,0 +1,36 @@
+CREATE TABLE "audit_events"
+(
+    "id"           bigserial PRIMARY KEY,
+    "actor"        varchar     NOT NULL,
+    "action"       varchar     NOT NULL,
+    "resource_ids" jsonb       NOT NULL DEFAULT '{}',
+    "client_ip"    varchar     NOT NULL,
+    "request_id"   varchar     NOT NULL,
+    "outcome"      varchar     NOT NULL,
+    "status_code"  int         NOT NULL,
+    "created_at"   timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "audit_events" ("actor", "created_at");
+
+CREATE INDEX ON "audit_events" ("action", "created_at");
+
+COMMENT ON COLUMN "audit_events"."actor" IS 'username of the user that made the request, empty when it is not known';
+
+COMMENT ON COLUMN "audit_events"."resource_ids" IS 'ids of the resources the request created or used, e.g. {"account_id": 1}';
+
+COMMENT ON COLUMN "audit_events"."outcome" IS 'success or failure';
+
+-- audit events are written once and never changed
+CREATE FUNCTION "reject_audit_event_change"() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'audit events can not be changed';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "audit_events_immutable"
+    BEFORE UPDATE OR DELETE
+    ON "audit_events"
+    FOR EACH ROW
+EXECUTE FUNCTION "reject_audit_event_change"();
@@ -0,0 +1,17 @@
+CREATE TABLE "outbox"
+(
+    "id"              bigserial PRIMARY KEY,
+    "event_type"      varchar     NOT NULL,
+    "payload"         jsonb       NOT NULL,
+    "attempts"        int         NOT NULL DEFAULT 0,
+    "last_error"      varchar     NOT NULL DEFAULT '',
+    "next_attempt_at" timestamptz NOT NULL DEFAULT (now()),
+    "delivered_at"    timestamptz,
+    "created_at"      timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "outbox" ("next_attempt_at") WHERE "delivered_at" IS NULL;
+
+COMMENT ON COLUMN "outbox"."event_type" IS 'e.g. transfer.completed';
+
+COMMENT ON COLUMN "outbox"."next_attempt_at" IS 'the event is not delivered before, it is pushed back while a dispatcher delivers it and after a failed attempt';
@@ -0,0 +1,57 @@
+CREATE TABLE "webhook_subscriptions"
+(
+    "id"         bigserial PRIMARY KEY,
+    "owner"      varchar     NOT NULL,
+    "url"        varchar     NOT NULL,
+    "events"     varchar[]   NOT NULL,
+    "secret"     varchar     NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "webhook_subscriptions"
+    ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+CREATE INDEX ON "webhook_subscriptions" ("owner");
+
+COMMENT ON COLUMN "webhook_subscriptions"."events" IS 'e.g. account.credited, account.debited';
+
+COMMENT ON COLUMN "webhook_subscriptions"."secret" IS 'key of the HMAC signature of the deliveries';
+
+CREATE TABLE "webhook_deliveries"
+(
+    "id"              bigserial PRIMARY KEY,
+    "subscription_id" bigint      NOT NULL,
+    "outbox_id"       bigint      NOT NULL,
+    "entry_id"        bigint      NOT NULL,
+    "event_type"      varchar     NOT NULL,
+    "payload"         jsonb       NOT NULL,
+    "status"          varchar     NOT NULL DEFAULT 'pending',
+    "attempts"        int         NOT NULL DEFAULT 0,
+    "last_error"      varchar     NOT NULL DEFAULT '',
+    "response_status" int,
+    "next_attempt_at" timestamptz NOT NULL DEFAULT (now()),
+    "delivered_at"    timestamptz,
+    "created_at"      timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "webhook_deliveries"
+    ADD FOREIGN KEY ("subscription_id") REFERENCES "webhook_subscriptions" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "webhook_deliveries"
+    ADD FOREIGN KEY ("outbox_id") REFERENCES "outbox" ("id");
+
+ALTER TABLE "webhook_deliveries"
+    ADD FOREIGN KEY ("entry_id") REFERENCES "entries" ("id");
+
+ALTER TABLE "webhook_deliveries"
+    ADD CONSTRAINT "webhook_deliveries_status_check" CHECK ("status" IN ('pending', 'delivered', 'failed'));
+
+-- the outbox delivers its events at least once, an event creates a delivery only once
+ALTER TABLE "webhook_deliveries"
+    ADD CONSTRAINT "subscription_outbox_entry_key" UNIQUE ("subscription_id", "outbox_id", "entry_id");
+
+CREATE INDEX ON "webhook_deliveries" ("next_attempt_at") WHERE "status" = 'pending';
+
+COMMENT ON COLUMN "webhook_deliveries"."status" IS 'pending until the subscriber accepts it, failed after the last attempt';
+
+COMMENT ON COLUMN "webhook_deliveries"."response_status" IS 'http status of the last attempt, null when no response was received';
@@ -0,0 +1,5 @@
+ALTER TABLE "users"
+    DROP COLUMN IF EXISTS "locked_until";
+
+ALTER TABLE "users"
+    DROP COLUMN IF EXISTS "failed_login_attempts";
@@ -0,0 +1,8 @@
+ALTER TABLE "users"
+    ADD COLUMN "failed_login_attempts" int NOT NULL DEFAULT 0;
+
+ALTER TABLE "users"
+    ADD COLUMN "locked_until" timestamptz;
+
+COMMENT ON COLUMN "users"."failed_login_attempts" IS 'failed logins since the last successful one, the lockouts grow with it';
+COMMENT ON COLUMN "users"."locked_until" IS 'the user can not log in before this time after too many failed logins';
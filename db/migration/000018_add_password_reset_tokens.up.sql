@@ -0,0 +1,18 @@
+CREATE TABLE "password_reset_tokens"
+(
+    "id"         bigserial PRIMARY KEY,
+    "username"   varchar        NOT NULL,
+    "token_hash" varchar UNIQUE NOT NULL,
+    "expires_at" timestamptz    NOT NULL,
+    "used_at"    timestamptz,
+    "created_at" timestamptz    NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "password_reset_tokens"
+    ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "password_reset_tokens" ("username");
+
+COMMENT ON COLUMN "password_reset_tokens"."token_hash" IS 'sha256 of the token sent to the user, the token itself is not stored';
+
+COMMENT ON COLUMN "password_reset_tokens"."used_at" IS 'a token can be used once, the unused tokens of a user are used up when the password changes';
@@ -0,0 +1,32 @@
+ALTER TABLE "users"
+    ADD COLUMN "is_email_verified" bool NOT NULL DEFAULT false;
+
+-- the users created before the verification was added keep using their accounts
+UPDATE "users"
+SET "is_email_verified" = true;
+
+CREATE TABLE "verify_emails"
+(
+    "id"               bigserial PRIMARY KEY,
+    "username"         varchar     NOT NULL,
+    "email"            varchar     NOT NULL,
+    "secret_code_hash" varchar     NOT NULL DEFAULT '',
+    "is_used"          bool        NOT NULL DEFAULT false,
+    "attempts"         int         NOT NULL DEFAULT 0,
+    "last_error"       varchar     NOT NULL DEFAULT '',
+    "next_attempt_at"  timestamptz NOT NULL DEFAULT (now()),
+    "sent_at"          timestamptz,
+    "created_at"       timestamptz NOT NULL DEFAULT (now()),
+    "expired_at"       timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "verify_emails"
+    ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "verify_emails" ("next_attempt_at") WHERE "sent_at" IS NULL;
+
+COMMENT ON COLUMN "verify_emails"."secret_code_hash" IS 'sha256 of the secret code in the mail, it is set once the mail is sent';
+
+COMMENT ON COLUMN "verify_emails"."next_attempt_at" IS 'the mail is not sent before, it is pushed back while a sender sends it and after a failed attempt';
+
+COMMENT ON COLUMN "verify_emails"."expired_at" IS 'the secret code can not be used after this time, it is set once the mail is sent';
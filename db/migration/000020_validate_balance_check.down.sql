@@ -0,0 +1,6 @@
+-- the written off balances are not restored, only the validation is undone
+ALTER TABLE "accounts"
+    DROP CONSTRAINT IF EXISTS "balance_non_negative";
+
+ALTER TABLE "accounts"
+    ADD CONSTRAINT "balance_non_negative" CHECK ("balance" >= 0) NOT VALID;
@@ -0,0 +1,12 @@
+-- write off the negative balances the overdraft bug left behind, with an entry so the balance still matches the entries
+INSERT INTO "entries" ("account_id", "amount")
+SELECT "id", -"balance"
+FROM "accounts"
+WHERE "balance" < 0;
+
+UPDATE "accounts"
+SET "balance" = 0
+WHERE "balance" < 0;
+
+ALTER TABLE "accounts"
+    VALIDATE CONSTRAINT "balance_non_negative";
@@ -0,0 +1,4 @@
+ALTER TABLE "users"
+    ADD COLUMN "role_changed_at" timestamptz NOT NULL DEFAULT ('0001-01-01 00:00:00Z');
+
+COMMENT ON COLUMN "users"."role_changed_at" IS 'the access tokens issued before carry the old role, so they are rejected';
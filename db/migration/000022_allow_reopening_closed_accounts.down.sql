@@ -0,0 +1,5 @@
+-- fails if an owner reopened an account in a currency, as the closed one is unique again
+DROP INDEX IF EXISTS "accounts_owner_currency_key";
+
+ALTER TABLE "accounts"
+    ADD CONSTRAINT "owner_currency_key" UNIQUE ("owner", "currency");
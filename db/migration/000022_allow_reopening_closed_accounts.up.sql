@@ -0,0 +1,5 @@
+-- a closed account no longer blocks a new account of its owner in the same currency
+ALTER TABLE "accounts"
+    DROP CONSTRAINT IF EXISTS "owner_currency_key";
+
+CREATE UNIQUE INDEX "accounts_owner_currency_key" ON "accounts" ("owner", "currency") WHERE "status" <> 'closed';
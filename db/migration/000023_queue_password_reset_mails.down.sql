@@ -0,0 +1,17 @@
+-- the tokens that were never mailed can't be used
+DELETE
+FROM "password_reset_tokens"
+WHERE "token_hash" = '';
+
+DROP INDEX IF EXISTS "password_reset_tokens_token_hash_key";
+
+ALTER TABLE "password_reset_tokens"
+    ALTER COLUMN "token_hash" DROP DEFAULT,
+    ADD CONSTRAINT "password_reset_tokens_token_hash_key" UNIQUE ("token_hash"),
+    DROP COLUMN IF EXISTS "email",
+    DROP COLUMN IF EXISTS "attempts",
+    DROP COLUMN IF EXISTS "last_error",
+    DROP COLUMN IF EXISTS "next_attempt_at",
+    DROP COLUMN IF EXISTS "sent_at";
+
+COMMENT ON COLUMN "password_reset_tokens"."token_hash" IS 'sha256 of the token sent to the user, the token itself is not stored';
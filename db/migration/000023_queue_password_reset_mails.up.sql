@@ -0,0 +1,24 @@
+ALTER TABLE "password_reset_tokens"
+    ADD COLUMN "email"           varchar     NOT NULL DEFAULT '',
+    ADD COLUMN "attempts"        int         NOT NULL DEFAULT 0,
+    ADD COLUMN "last_error"      varchar     NOT NULL DEFAULT '',
+    ADD COLUMN "next_attempt_at" timestamptz NOT NULL DEFAULT (now()),
+    ADD COLUMN "sent_at"         timestamptz;
+
+-- the tokens created before the mails were queued were mailed with the request
+UPDATE "password_reset_tokens"
+SET "sent_at"  = "created_at",
+    "attempts" = 1;
+
+-- the tokens that are not mailed yet have no hash, so only the hashes that are set must be unique
+ALTER TABLE "password_reset_tokens"
+    DROP CONSTRAINT "password_reset_tokens_token_hash_key",
+    ALTER COLUMN "token_hash" SET DEFAULT '';
+
+CREATE UNIQUE INDEX "password_reset_tokens_token_hash_key" ON "password_reset_tokens" ("token_hash") WHERE "token_hash" <> '';
+
+CREATE INDEX ON "password_reset_tokens" ("next_attempt_at") WHERE "sent_at" IS NULL;
+
+COMMENT ON COLUMN "password_reset_tokens"."token_hash" IS 'sha256 of the token sent to the user, the token itself is not stored, it is set once the mail is sent';
+
+COMMENT ON COLUMN "password_reset_tokens"."next_attempt_at" IS 'the mail is not sent before, it is pushed back while a sender sends it and after a failed attempt';
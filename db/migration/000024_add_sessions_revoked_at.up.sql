@@ -0,0 +1,4 @@
+ALTER TABLE "users"
+    ADD COLUMN "sessions_revoked_at" timestamptz NOT NULL DEFAULT ('0001-01-01 00:00:00Z');
+
+COMMENT ON COLUMN "users"."sessions_revoked_at" IS 'an admin revoked the sessions of the user, the access tokens issued before are rejected';
@@ -0,0 +1,16 @@
+-- every entry with the balance of the account right after it
+-- the balance is anchored on the current balance, so accounts created with an opening balance are correct too
+CREATE VIEW "account_statements" AS
+SELECT "entries"."id",
+       "entries"."account_id",
+       "entries"."amount",
+       "entries"."created_at",
+       ("accounts"."balance" -
+        SUM("entries"."amount")
+        OVER (PARTITION BY "entries"."account_id" ORDER BY "entries"."created_at" DESC, "entries"."id" DESC) +
+        "entries"."amount")::bigint AS "balance_after"
+FROM "entries"
+         JOIN "accounts" ON "accounts"."id" = "entries"."account_id";
+
+ALTER TABLE "entries"
+    DROP COLUMN "balance_after";
@@ -0,0 +1,17 @@
+ALTER TABLE "entries"
+    ADD COLUMN "balance_after" bigint;
+
+-- the entries made before are filled in by the window of the view, it is anchored on the current balance,
+-- so the accounts created with an opening balance are correct too
+UPDATE "entries"
+SET "balance_after" = "account_statements"."balance_after"
+FROM "account_statements"
+WHERE "account_statements"."id" = "entries"."id";
+
+ALTER TABLE "entries"
+    ALTER COLUMN "balance_after" SET NOT NULL;
+
+COMMENT ON COLUMN "entries"."balance_after" IS 'balance of the account right after the entry, written in the transaction that updated the balance';
+
+-- the statements read the stored balance, the window summed every entry of the account on each page
+DROP VIEW "account_statements";
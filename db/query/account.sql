@@ -21,6 +21,22 @@ WHERE owner = $1
 ORDER BY id LIMIT $2
 OFFSET $3;
 
+-- name: ListAccountsAfter :many
+SELECT *
+FROM accounts
+WHERE owner = sqlc.arg(owner)
+  AND (created_at, id) > (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_limit);
+
+-- name: ListAccountsBefore :many
+SELECT *
+FROM accounts
+WHERE owner = sqlc.arg(owner)
+  AND (created_at, id) < (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
 -- name: UpdateAccount :one
 UPDATE accounts
 set balance = $2
@@ -31,6 +47,12 @@ UPDATE accounts
 set balance = balance + sqlc.arg(amount)
 WHERE id = sqlc.arg(id) RETURNING *;
 
+-- name: UpdateAccountStatus :one
+UPDATE accounts
+set status        = sqlc.arg(status),
+    status_reason = sqlc.arg(status_reason)
+WHERE id = sqlc.arg(id) RETURNING *;
+
 -- name: DeleteAccount :exec
 DELETE
 FROM accounts
@@ -0,0 +1,17 @@
+-- name: GetCurrency :one
+SELECT *
+FROM currencies
+WHERE code = $1
+LIMIT 1;
+
+-- name: ListCurrencies :many
+SELECT *
+FROM currencies
+ORDER BY code;
+
+-- name: UpdateCurrencyEnabled :one
+UPDATE currencies
+SET enabled    = sqlc.arg(enabled),
+    updated_at = now()
+WHERE code = sqlc.arg(code)
+RETURNING *;
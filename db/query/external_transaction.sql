@@ -0,0 +1,15 @@
+-- name: CreateExternalTransaction :one
+INSERT INTO external_transactions (account_id,
+                                   entry_id,
+                                   kind,
+                                   amount,
+                                   source,
+                                   external_reference)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetExternalTransaction :one
+SELECT *
+FROM external_transactions
+WHERE id = $1
+LIMIT 1;
@@ -0,0 +1,31 @@
+-- name: CreateOutboxEvent :one
+INSERT INTO outbox (event_type,
+                    payload)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: ClaimOutboxEvents :many
+-- pushes next_attempt_at of the claimed events to lease_until, so other dispatchers skip them,
+-- an event whose dispatcher stopped before it was delivered is claimed again after the lease
+UPDATE outbox
+SET next_attempt_at = sqlc.arg(lease_until)
+WHERE id IN (SELECT id
+             FROM outbox
+             WHERE delivered_at IS NULL
+               AND next_attempt_at <= now()
+             ORDER BY id
+             LIMIT sqlc.arg(batch_size) FOR UPDATE SKIP LOCKED)
+RETURNING *;
+
+-- name: MarkOutboxEventDelivered :exec
+UPDATE outbox
+SET delivered_at = now(),
+    attempts     = attempts + 1
+WHERE id = $1;
+
+-- name: MarkOutboxEventFailed :exec
+UPDATE outbox
+SET attempts        = attempts + 1,
+    last_error      = sqlc.arg(last_error),
+    next_attempt_at = sqlc.arg(next_attempt_at)
+WHERE id = sqlc.arg(id);
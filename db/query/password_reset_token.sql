@@ -0,0 +1,58 @@
+-- name: CreatePasswordResetToken :one
+INSERT INTO password_reset_tokens (username,
+                                   token_hash,
+                                   expires_at)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetPasswordResetTokenForUpdate :one
+SELECT *
+FROM password_reset_tokens
+WHERE token_hash = $1 LIMIT 1
+FOR NO KEY
+UPDATE;
+
+-- name: UseUserPasswordResetTokens :exec
+UPDATE password_reset_tokens
+SET used_at = now()
+WHERE username = $1
+  AND used_at IS NULL;
+
+-- name: QueuePasswordResetToken :one
+-- creates a token for the user with the email, its mail is sent in the background, there is no row when no user has the email
+INSERT INTO password_reset_tokens (username,
+                                   email,
+                                   expires_at)
+SELECT username, email, sqlc.arg(expires_at)
+FROM users
+WHERE email = sqlc.arg(email)
+RETURNING *;
+
+-- name: ClaimPasswordResetTokens :many
+-- pushes next_attempt_at of the claimed tokens to lease_until, so other senders skip them
+-- the tokens that expired or were used up before their mail was sent are not mailed anymore
+UPDATE password_reset_tokens
+SET next_attempt_at = sqlc.arg(lease_until)
+WHERE id IN (SELECT id
+             FROM password_reset_tokens
+             WHERE sent_at IS NULL
+               AND used_at IS NULL
+               AND expires_at > now()
+               AND next_attempt_at <= now()
+             ORDER BY id
+             LIMIT sqlc.arg(batch_size) FOR UPDATE SKIP LOCKED)
+RETURNING *;
+
+-- name: MarkPasswordResetTokenSent :exec
+UPDATE password_reset_tokens
+SET sent_at    = now(),
+    attempts   = attempts + 1,
+    token_hash = sqlc.arg(token_hash)
+WHERE id = sqlc.arg(id);
+
+-- name: MarkPasswordResetTokenFailed :exec
+UPDATE password_reset_tokens
+SET attempts        = attempts + 1,
+    last_error      = sqlc.arg(last_error),
+    next_attempt_at = sqlc.arg(next_attempt_at)
+WHERE id = sqlc.arg(id);
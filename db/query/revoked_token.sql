@@ -0,0 +1,11 @@
+-- name: RevokeToken :exec
+INSERT INTO revoked_tokens (id,
+                            username,
+                            expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (id) DO NOTHING;
+
+-- name: IsTokenRevoked :one
+SELECT EXISTS(SELECT 1
+              FROM revoked_tokens
+              WHERE id = $1);
@@ -0,0 +1,30 @@
+-- name: CreateSession :one
+INSERT INTO sessions (id,
+                      username,
+                      refresh_token,
+                      user_agent,
+                      client_ip,
+                      is_blocked,
+                      expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetSession :one
+SELECT *
+FROM sessions
+WHERE id = $1
+LIMIT 1;
+
+-- name: BlockSession :one
+UPDATE sessions
+set is_blocked = true
+WHERE id = $1
+RETURNING *;
+
+-- name: BlockUserSessions :many
+UPDATE sessions
+set is_blocked = true
+WHERE username = $1
+  AND is_blocked = false
+  AND expires_at > now()
+RETURNING *;
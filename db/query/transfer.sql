@@ -1,8 +1,11 @@
 -- name: CreateTransfer :one
 INSERT INTO transfers (from_account_id,
                        to_account_id,
-                       amount)
-VALUES ($1, $2, $3)
+                       amount,
+                       to_amount,
+                       exchange_rate,
+                       reverses_transfer_id)
+VALUES ($1, $2, $3, $4, $5, $6)
 RETURNING *;
 
 -- name: GetTransfer :one
@@ -17,4 +20,48 @@ FROM transfers
 WHERE from_account_id = $1
    OR to_account_id = $2
 ORDER BY id
-LIMIT $3 OFFSET $4;
\ No newline at end of file
+LIMIT $3 OFFSET $4;
+
+-- name: ListAccountTransfers :many
+SELECT *
+FROM transfers
+WHERE (from_account_id = sqlc.arg(account_id) OR to_account_id = sqlc.arg(account_id))
+  AND (sqlc.narg(counterparty_id)::bigint IS NULL
+    OR from_account_id = sqlc.narg(counterparty_id)
+    OR to_account_id = sqlc.narg(counterparty_id))
+  AND (sqlc.narg(min_amount)::bigint IS NULL
+    OR CASE WHEN from_account_id = sqlc.arg(account_id) THEN amount ELSE to_amount END >= sqlc.narg(min_amount))
+  AND (sqlc.narg(max_amount)::bigint IS NULL
+    OR CASE WHEN from_account_id = sqlc.arg(account_id) THEN amount ELSE to_amount END <= sqlc.narg(max_amount))
+ORDER BY id
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: ListAccountTransfersAfter :many
+SELECT *
+FROM transfers
+WHERE (from_account_id = sqlc.arg(account_id) OR to_account_id = sqlc.arg(account_id))
+  AND (sqlc.narg(counterparty_id)::bigint IS NULL
+    OR from_account_id = sqlc.narg(counterparty_id)
+    OR to_account_id = sqlc.narg(counterparty_id))
+  AND (sqlc.narg(min_amount)::bigint IS NULL
+    OR CASE WHEN from_account_id = sqlc.arg(account_id) THEN amount ELSE to_amount END >= sqlc.narg(min_amount))
+  AND (sqlc.narg(max_amount)::bigint IS NULL
+    OR CASE WHEN from_account_id = sqlc.arg(account_id) THEN amount ELSE to_amount END <= sqlc.narg(max_amount))
+  AND (created_at, id) > (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at, id
+LIMIT sqlc.arg(page_limit);
+
+-- name: ListAccountTransfersBefore :many
+SELECT *
+FROM transfers
+WHERE (from_account_id = sqlc.arg(account_id) OR to_account_id = sqlc.arg(account_id))
+  AND (sqlc.narg(counterparty_id)::bigint IS NULL
+    OR from_account_id = sqlc.narg(counterparty_id)
+    OR to_account_id = sqlc.narg(counterparty_id))
+  AND (sqlc.narg(min_amount)::bigint IS NULL
+    OR CASE WHEN from_account_id = sqlc.arg(account_id) THEN amount ELSE to_amount END >= sqlc.narg(min_amount))
+  AND (sqlc.narg(max_amount)::bigint IS NULL
+    OR CASE WHEN from_account_id = sqlc.arg(account_id) THEN amount ELSE to_amount END <= sqlc.narg(max_amount))
+  AND (created_at, id) < (sqlc.arg(cursor_created_at)::timestamptz, sqlc.arg(cursor_id)::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
@@ -7,4 +7,79 @@ RETURNING *;
 SELECT *
 FROM users
 WHERE username = $1
-LIMIT 1;
\ No newline at end of file
+LIMIT 1;
+
+-- name: UpdateUserRole :one
+UPDATE users
+SET role            = sqlc.arg(role),
+    role_changed_at = now()
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: UpdateUserSessionsRevokedAt :one
+-- the access tokens issued before are rejected, like after a password or a role change
+UPDATE users
+SET sessions_revoked_at = now()
+WHERE username = $1
+RETURNING *;
+
+-- name: RecordFailedLogin :one
+-- the user is locked out from the max_attempts-th failed login on, and every failure after a lockout doubles it,
+-- up to max_lockout_seconds
+UPDATE users
+SET failed_login_attempts = failed_login_attempts + 1,
+    locked_until          = CASE
+                                WHEN failed_login_attempts + 1 >= sqlc.arg(max_attempts)::int
+                                    THEN now() + make_interval(secs => LEAST(
+                                        sqlc.arg(lockout_seconds)::float8 *
+                                        power(2, LEAST(failed_login_attempts + 1 - sqlc.arg(max_attempts)::int, 32)),
+                                        sqlc.arg(max_lockout_seconds)::float8
+                                    ))
+                                ELSE locked_until
+        END
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: UnlockUser :one
+UPDATE users
+SET failed_login_attempts = 0,
+    locked_until          = NULL
+WHERE username = $1
+RETURNING *;
+
+-- name: GetUserByEmail :one
+SELECT *
+FROM users
+WHERE email = $1
+LIMIT 1;
+
+-- name: GetUserCredentialsChangedAt :one
+-- the tokens issued before the password or the role of the user last changed, or before their sessions were revoked, are revoked
+SELECT GREATEST(password_changed_at, role_changed_at, sessions_revoked_at)::timestamptz AS changed_at
+FROM users
+WHERE username = $1
+LIMIT 1;
+
+-- name: UpdateUserPassword :one
+-- the user proved they own the account, so a lockout after failed logins ends too
+UPDATE users
+SET hashed_password       = sqlc.arg(hashed_password),
+    password_changed_at   = now(),
+    failed_login_attempts = 0,
+    locked_until          = NULL
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: IsUserEmailVerified :one
+SELECT is_email_verified
+FROM users
+WHERE username = $1
+LIMIT 1;
+
+-- name: VerifyUserEmail :one
+-- the email must still be the one the code was sent to
+UPDATE users
+SET is_email_verified = true
+WHERE username = sqlc.arg(username)
+  AND email = sqlc.arg(email)
+RETURNING *;
@@ -0,0 +1,45 @@
+-- name: CreateVerifyEmail :one
+INSERT INTO verify_emails (username,
+                           email)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: ClaimVerifyEmails :many
+-- pushes next_attempt_at of the claimed mails to lease_until, so other senders skip them
+UPDATE verify_emails
+SET next_attempt_at = sqlc.arg(lease_until)
+WHERE id IN (SELECT id
+             FROM verify_emails
+             WHERE sent_at IS NULL
+               AND next_attempt_at <= now()
+             ORDER BY id
+             LIMIT sqlc.arg(batch_size) FOR UPDATE SKIP LOCKED)
+RETURNING *;
+
+-- name: MarkVerifyEmailSent :exec
+UPDATE verify_emails
+SET sent_at          = now(),
+    attempts         = attempts + 1,
+    secret_code_hash = sqlc.arg(secret_code_hash),
+    expired_at       = sqlc.arg(expired_at)
+WHERE id = sqlc.arg(id);
+
+-- name: MarkVerifyEmailFailed :exec
+UPDATE verify_emails
+SET attempts        = attempts + 1,
+    last_error      = sqlc.arg(last_error),
+    next_attempt_at = sqlc.arg(next_attempt_at)
+WHERE id = sqlc.arg(id);
+
+-- name: GetVerifyEmailForUpdate :one
+SELECT *
+FROM verify_emails
+WHERE id = $1 LIMIT 1
+FOR NO KEY
+UPDATE;
+
+-- name: UseVerifyEmail :one
+UPDATE verify_emails
+SET is_used = true
+WHERE id = $1
+RETURNING *;
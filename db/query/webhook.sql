@@ -0,0 +1,91 @@
+-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (owner,
+                                   url,
+                                   events,
+                                   secret)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetWebhookSubscription :one
+SELECT *
+FROM webhook_subscriptions
+WHERE id = $1
+LIMIT 1;
+
+-- name: ListWebhookSubscriptions :many
+SELECT *
+FROM webhook_subscriptions
+WHERE owner = $1
+ORDER BY id;
+
+-- name: ListWebhookSubscriptionsByEvent :many
+SELECT *
+FROM webhook_subscriptions
+WHERE owner = sqlc.arg(owner)
+  AND sqlc.arg(event_type)::varchar = ANY (events)
+ORDER BY id;
+
+-- name: DeleteWebhookSubscription :exec
+DELETE
+FROM webhook_subscriptions
+WHERE id = $1;
+
+-- name: CreateWebhookDelivery :one
+-- returns no rows when the delivery of the event was already created
+INSERT INTO webhook_deliveries (subscription_id,
+                                outbox_id,
+                                entry_id,
+                                event_type,
+                                payload)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT ON CONSTRAINT subscription_outbox_entry_key DO NOTHING
+RETURNING *;
+
+-- name: GetWebhookDelivery :one
+SELECT *
+FROM webhook_deliveries
+WHERE id = $1
+LIMIT 1;
+
+-- name: ListWebhookDeliveries :many
+SELECT *
+FROM webhook_deliveries
+WHERE subscription_id = sqlc.arg(subscription_id)
+ORDER BY id DESC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: ClaimWebhookDeliveries :many
+-- pushes next_attempt_at of the claimed deliveries to lease_until, so other senders skip them
+UPDATE webhook_deliveries
+SET next_attempt_at = sqlc.arg(lease_until)
+WHERE id IN (SELECT id
+             FROM webhook_deliveries
+             WHERE status = 'pending'
+               AND next_attempt_at <= now()
+             ORDER BY id
+             LIMIT sqlc.arg(batch_size) FOR UPDATE SKIP LOCKED)
+RETURNING *;
+
+-- name: MarkWebhookDeliveryDelivered :exec
+UPDATE webhook_deliveries
+SET status          = 'delivered',
+    attempts        = attempts + 1,
+    response_status = sqlc.arg(response_status),
+    delivered_at    = now()
+WHERE id = sqlc.arg(id);
+
+-- name: MarkWebhookDeliveryFailed :exec
+UPDATE webhook_deliveries
+SET status          = sqlc.arg(status),
+    attempts        = attempts + 1,
+    last_error      = sqlc.arg(last_error),
+    response_status = sqlc.narg(response_status),
+    next_attempt_at = sqlc.arg(next_attempt_at)
+WHERE id = sqlc.arg(id);
+
+-- name: ReplayWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status          = 'pending',
+    next_attempt_at = now()
+WHERE id = $1
+RETURNING *;
@@ -89,12 +89,64 @@ func TestQueries_ListAccounts(t *testing.T) {
 	}
 }
 
+func TestQueries_ListAccountsAfterAndBefore(t *testing.T) {
+	user := createRandomUser(t)
+
+	var accounts []Account
+	for _, currency := range []string{util.USD, util.EUR, util.CAD, util.AUD} {
+		account, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+			Owner:    user.Username,
+			Currency: currency,
+		})
+		require.NoError(t, err)
+
+		accounts = append(accounts, account)
+	}
+
+	// the first page starts before any account
+	page, err := testQueries.ListAccountsAfter(context.Background(), ListAccountsAfterParams{
+		Owner:     user.Username,
+		PageLimit: 2,
+	})
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	require.Equal(t, accounts[0].ID, page[0].ID)
+	require.Equal(t, accounts[1].ID, page[1].ID)
+
+	page, err = testQueries.ListAccountsAfter(context.Background(), ListAccountsAfterParams{
+		Owner:           user.Username,
+		CursorCreatedAt: page[1].CreatedAt,
+		CursorID:        page[1].ID,
+		PageLimit:       5,
+	})
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	require.Equal(t, accounts[2].ID, page[0].ID)
+	require.Equal(t, accounts[3].ID, page[1].ID)
+
+	// rows before the cursor come newest first
+	page, err = testQueries.ListAccountsBefore(context.Background(), ListAccountsBeforeParams{
+		Owner:           user.Username,
+		CursorCreatedAt: accounts[2].CreatedAt,
+		CursorID:        accounts[2].ID,
+		PageLimit:       5,
+	})
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	require.Equal(t, accounts[1].ID, page[0].ID)
+	require.Equal(t, accounts[0].ID, page[1].ID)
+}
+
 func createRandomAccount(t *testing.T) Account {
+	return createRandomAccountWithBalance(t, util.RandomMoney())
+}
+
+func createRandomAccountWithBalance(t *testing.T, balance int64) Account {
 	user := createRandomUser(t)
 
 	args := CreateAccountParams{
 		Owner:    user.Username,
-		Balance:  util.RandomMoney(),
+		Balance:  balance,
 		Currency: util.RandomCurrency(),
 	}
 
@@ -107,6 +159,7 @@ func createRandomAccount(t *testing.T) Account {
 	require.Equal(t, args.Owner, account.Owner)
 	require.Equal(t, args.Balance, account.Balance)
 	require.Equal(t, args.Currency, account.Currency)
+	require.Equal(t, AccountStatusActive, account.Status)
 
 	require.NotZero(t, account.ID)
 	require.NotZero(t, account.CreatedAt)
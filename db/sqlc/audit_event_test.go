@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func createRandomAuditEvent(t *testing.T, actor string) AuditEvent {
+	args := CreateAuditEventParams{
+		Actor:       actor,
+		Action:      "account.create",
+		ResourceIds: json.RawMessage(`{"account_id": 1}`),
+		ClientIp:    "10.0.0.1",
+		RequestID:   util.RandomString(16),
+		Outcome:     "success",
+		StatusCode:  200,
+	}
+
+	event, err := testQueries.CreateAuditEvent(context.Background(), args)
+	require.NoError(t, err)
+
+	require.NotZero(t, event.ID)
+	require.Equal(t, args.Actor, event.Actor)
+	require.Equal(t, args.Action, event.Action)
+	require.JSONEq(t, string(args.ResourceIds), string(event.ResourceIds))
+	require.Equal(t, args.RequestID, event.RequestID)
+	require.NotZero(t, event.CreatedAt)
+
+	return event
+}
+
+func TestQueries_ListAuditEvents(t *testing.T) {
+	actor := util.RandomOwner()
+
+	first := createRandomAuditEvent(t, actor)
+	second := createRandomAuditEvent(t, actor)
+	createRandomAuditEvent(t, util.RandomOwner())
+
+	events, err := testQueries.ListAuditEvents(context.Background(), ListAuditEventsParams{
+		Actor:      sql.NullString{String: actor, Valid: true},
+		PageLimit:  10,
+		PageOffset: 0,
+	})
+	require.NoError(t, err)
+
+	// the newest first
+	require.Len(t, events, 2)
+	require.Equal(t, second.ID, events[0].ID)
+	require.Equal(t, first.ID, events[1].ID)
+}
+
+func TestQueries_AuditEventsAreImmutable(t *testing.T) {
+	event := createRandomAuditEvent(t, util.RandomOwner())
+
+	_, err := testDB.ExecContext(context.Background(), "UPDATE audit_events SET outcome = 'failure' WHERE id = $1", event.ID)
+	require.Error(t, err)
+
+	_, err = testDB.ExecContext(context.Background(), "DELETE FROM audit_events WHERE id = $1", event.ID)
+	require.Error(t, err)
+}
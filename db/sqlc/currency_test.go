@@ -0,0 +1,44 @@
+package db
+
+import (
+	"context"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestQueries_ListCurrencies(t *testing.T) {
+	currencies, err := testQueries.ListCurrencies(context.Background())
+	require.NoError(t, err)
+
+	codes := make(map[string]bool)
+	for _, currency := range currencies {
+		codes[currency.Code] = true
+	}
+
+	// every currency used by the tests is seeded by the migration
+	for _, code := range []string{util.USD, util.EUR, util.CAD, util.AUD, util.JPY} {
+		require.True(t, codes[code], code)
+	}
+}
+
+func TestQueries_UpdateCurrencyEnabled(t *testing.T) {
+	currency, err := testQueries.UpdateCurrencyEnabled(context.Background(), UpdateCurrencyEnabledParams{
+		Enabled: false,
+		Code:    util.JPY,
+	})
+	require.NoError(t, err)
+	require.False(t, currency.Enabled)
+	require.Equal(t, int32(0), currency.MinorUnits)
+
+	currency, err = testQueries.UpdateCurrencyEnabled(context.Background(), UpdateCurrencyEnabledParams{
+		Enabled: true,
+		Code:    util.JPY,
+	})
+	require.NoError(t, err)
+	require.True(t, currency.Enabled)
+
+	got, err := testQueries.GetCurrency(context.Background(), util.JPY)
+	require.NoError(t, err)
+	require.True(t, got.Enabled)
+}
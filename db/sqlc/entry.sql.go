@@ -7,34 +7,39 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 const createEntry = `-- name: CreateEntry :one
 INSERT INTO entries (account_id,
-                     amount)
-VALUES ($1, $2)
-RETURNING id, account_id, amount, created_at
+                     amount,
+                     balance_after)
+VALUES ($1, $2, $3)
+RETURNING id, account_id, amount, created_at, balance_after
 `
 
 type CreateEntryParams struct {
-	AccountID int64 `json:"account_id"`
-	Amount    int64 `json:"amount"`
+	AccountID    int64 `json:"account_id"`
+	Amount       int64 `json:"amount"`
+	BalanceAfter int64 `json:"balance_after"`
 }
 
 func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
-	row := q.db.QueryRowContext(ctx, createEntry, arg.AccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createEntry, arg.AccountID, arg.Amount, arg.BalanceAfter)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.BalanceAfter,
 	)
 	return i, err
 }
 
 const getEntry = `-- name: GetEntry :one
-SELECT id, account_id, amount, created_at
+SELECT id, account_id, amount, created_at, balance_after
 FROM entries
 WHERE id = $1
 LIMIT 1
@@ -48,12 +53,193 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.BalanceAfter,
 	)
 	return i, err
 }
 
+const listAccountStatement = `-- name: ListAccountStatement :many
+SELECT id, account_id, amount, created_at, balance_after
+FROM entries
+WHERE account_id = $1
+  AND ($2::timestamptz IS NULL OR created_at >= $2)
+  AND ($3::timestamptz IS NULL OR created_at < $3)
+  AND ($4::varchar IS NULL
+    OR ($4 = 'credit' AND amount > 0)
+    OR ($4 = 'debit' AND amount < 0))
+ORDER BY created_at, id
+LIMIT $5 OFFSET $6
+`
+
+type ListAccountStatementParams struct {
+	AccountID  int64          `json:"account_id"`
+	FromTime   sql.NullTime   `json:"from_time"`
+	ToTime     sql.NullTime   `json:"to_time"`
+	Direction  sql.NullString `json:"direction"`
+	PageLimit  int32          `json:"page_limit"`
+	PageOffset int32          `json:"page_offset"`
+}
+
+func (q *Queries) ListAccountStatement(ctx context.Context, arg ListAccountStatementParams) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountStatement,
+		arg.AccountID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.Direction,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Entry
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.BalanceAfter,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAccountStatementAfter = `-- name: ListAccountStatementAfter :many
+SELECT id, account_id, amount, created_at, balance_after
+FROM entries
+WHERE account_id = $1
+  AND ($2::timestamptz IS NULL OR created_at >= $2)
+  AND ($3::timestamptz IS NULL OR created_at < $3)
+  AND ($4::varchar IS NULL
+    OR ($4 = 'credit' AND amount > 0)
+    OR ($4 = 'debit' AND amount < 0))
+  AND (created_at, id) > ($5::timestamptz, $6::bigint)
+ORDER BY created_at, id
+LIMIT $7
+`
+
+type ListAccountStatementAfterParams struct {
+	AccountID       int64          `json:"account_id"`
+	FromTime        sql.NullTime   `json:"from_time"`
+	ToTime          sql.NullTime   `json:"to_time"`
+	Direction       sql.NullString `json:"direction"`
+	CursorCreatedAt time.Time      `json:"cursor_created_at"`
+	CursorID        int64          `json:"cursor_id"`
+	PageLimit       int32          `json:"page_limit"`
+}
+
+func (q *Queries) ListAccountStatementAfter(ctx context.Context, arg ListAccountStatementAfterParams) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountStatementAfter,
+		arg.AccountID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.Direction,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Entry
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.BalanceAfter,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAccountStatementBefore = `-- name: ListAccountStatementBefore :many
+SELECT id, account_id, amount, created_at, balance_after
+FROM entries
+WHERE account_id = $1
+  AND ($2::timestamptz IS NULL OR created_at >= $2)
+  AND ($3::timestamptz IS NULL OR created_at < $3)
+  AND ($4::varchar IS NULL
+    OR ($4 = 'credit' AND amount > 0)
+    OR ($4 = 'debit' AND amount < 0))
+  AND (created_at, id) < ($5::timestamptz, $6::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT $7
+`
+
+type ListAccountStatementBeforeParams struct {
+	AccountID       int64          `json:"account_id"`
+	FromTime        sql.NullTime   `json:"from_time"`
+	ToTime          sql.NullTime   `json:"to_time"`
+	Direction       sql.NullString `json:"direction"`
+	CursorCreatedAt time.Time      `json:"cursor_created_at"`
+	CursorID        int64          `json:"cursor_id"`
+	PageLimit       int32          `json:"page_limit"`
+}
+
+func (q *Queries) ListAccountStatementBefore(ctx context.Context, arg ListAccountStatementBeforeParams) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountStatementBefore,
+		arg.AccountID,
+		arg.FromTime,
+		arg.ToTime,
+		arg.Direction,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Entry
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.BalanceAfter,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listEntries = `-- name: ListEntries :many
-SELECT id, account_id, amount, created_at
+SELECT id, account_id, amount, created_at, balance_after
 FROM entries
 WHERE account_id = $1
 ORDER BY id
@@ -80,6 +266,7 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.BalanceAfter,
 		); err != nil {
 			return nil, err
 		}
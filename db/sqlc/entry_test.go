@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestQueries_ListAccountStatement(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop())
+
+	account1 := createRandomAccountWithBalance(t, 100)
+	account2 := createRandomAccountWithBalance(t, 100)
+
+	// 100 -> 70 -> 90
+	result, err := store.TransferTx(context.Background(), TransferTxParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 30})
+	require.NoError(t, err)
+
+	// the entries store the balances of the accounts right after them
+	require.Equal(t, int64(70), result.FromEntry.BalanceAfter)
+	require.Equal(t, int64(130), result.ToEntry.BalanceAfter)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{FromAccountID: account2.ID, ToAccountID: account1.ID, Amount: 20})
+	require.NoError(t, err)
+
+	rows, err := testQueries.ListAccountStatement(context.Background(), ListAccountStatementParams{
+		AccountID: account1.ID,
+		PageLimit: 5,
+	})
+
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+
+	require.Equal(t, int64(-30), rows[0].Amount)
+	require.Equal(t, int64(70), rows[0].BalanceAfter)
+	require.Equal(t, int64(20), rows[1].Amount)
+	require.Equal(t, int64(90), rows[1].BalanceAfter)
+
+	// filtering keeps the running balance of the whole history
+	rows, err = testQueries.ListAccountStatement(context.Background(), ListAccountStatementParams{
+		AccountID: account1.ID,
+		Direction: sql.NullString{String: "credit", Valid: true},
+		PageLimit: 5,
+	})
+
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, int64(90), rows[0].BalanceAfter)
+
+	// the next page starts after the cursor of the last line
+	rows, err = testQueries.ListAccountStatementAfter(context.Background(), ListAccountStatementAfterParams{
+		AccountID:       account1.ID,
+		CursorCreatedAt: result.FromEntry.CreatedAt,
+		CursorID:        result.FromEntry.ID,
+		PageLimit:       5,
+	})
+
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, int64(20), rows[0].Amount)
+	require.Equal(t, int64(90), rows[0].BalanceAfter)
+}
@@ -5,7 +5,11 @@
 package db
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Account struct {
@@ -14,6 +18,36 @@ type Account struct {
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// frozen accounts can only be credited, closed accounts can not be debited or credited
+	Status string `json:"status"`
+	// why the status was last changed
+	StatusReason string `json:"status_reason"`
+}
+
+type AuditEvent struct {
+	ID int64 `json:"id"`
+	// username of the user that made the request, empty when it is not known
+	Actor  string `json:"actor"`
+	Action string `json:"action"`
+	// ids of the resources the request created or used, e.g. {"account_id": 1}
+	ResourceIds json.RawMessage `json:"resource_ids"`
+	ClientIp    string          `json:"client_ip"`
+	RequestID   string          `json:"request_id"`
+	// success or failure
+	Outcome    string    `json:"outcome"`
+	StatusCode int32     `json:"status_code"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type Currency struct {
+	// ISO 4217 code
+	Code string `json:"code"`
+	// number of decimal places, e.g. 2 for cents
+	MinorUnits int32  `json:"minor_units"`
+	Symbol     string `json:"symbol"`
+	// only enabled currencies can be used for new accounts and transfers
+	Enabled   bool      `json:"enabled"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type Entry struct {
@@ -22,6 +56,83 @@ type Entry struct {
 	// can be negative or positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// balance of the account right after the entry, written in the transaction that updated the balance
+	BalanceAfter int64 `json:"balance_after"`
+}
+
+type ExternalTransaction struct {
+	ID        int64 `json:"id"`
+	AccountID int64 `json:"account_id"`
+	EntryID   int64 `json:"entry_id"`
+	// deposit or withdrawal
+	Kind string `json:"kind"`
+	// must be positive, the kind tells the direction
+	Amount int64 `json:"amount"`
+	// the integration that moved the money, e.g. card_processor
+	Source string `json:"source"`
+	// id of the transaction in the source system
+	ExternalReference string    `json:"external_reference"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type IdempotencyKey struct {
+	Username       string `json:"username"`
+	IdempotencyKey string `json:"idempotency_key"`
+	// sha256 of the request body the key was first used with
+	RequestHash string `json:"request_hash"`
+	// the TransferTxResult returned when the key was first used
+	Response  json.RawMessage `json:"response"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type Outbox struct {
+	ID int64 `json:"id"`
+	// e.g. transfer.completed
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"payload"`
+	Attempts  int32           `json:"attempts"`
+	LastError string          `json:"last_error"`
+	// the event is not delivered before, it is pushed back while a dispatcher delivers it and after a failed attempt
+	NextAttemptAt time.Time    `json:"next_attempt_at"`
+	DeliveredAt   sql.NullTime `json:"delivered_at"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+type PasswordResetToken struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// sha256 of the token sent to the user, the token itself is not stored, it is set once the mail is sent
+	TokenHash string    `json:"token_hash"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// a token can be used once, the unused tokens of a user are used up when the password changes
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+	Email     string       `json:"email"`
+	Attempts  int32        `json:"attempts"`
+	LastError string       `json:"last_error"`
+	// the mail is not sent before, it is pushed back while a sender sends it and after a failed attempt
+	NextAttemptAt time.Time    `json:"next_attempt_at"`
+	SentAt        sql.NullTime `json:"sent_at"`
+}
+
+type RevokedToken struct {
+	// id of the revoked token payload, for refresh tokens this is the session id
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	// the row can be removed once the token has expired
+	ExpiresAt time.Time `json:"expires_at"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+type Session struct {
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"username"`
+	RefreshToken string    `json:"refresh_token"`
+	UserAgent    string    `json:"user_agent"`
+	ClientIp     string    `json:"client_ip"`
+	IsBlocked    bool      `json:"is_blocked"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
 type Transfer struct {
@@ -31,6 +142,12 @@ type Transfer struct {
 	// must be positive
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// amount credited to the to account, in its currency
+	ToAmount int64 `json:"to_amount"`
+	// units of the to currency for one unit of the from currency, to_amount is rounded down
+	ExchangeRate string `json:"exchange_rate"`
+	// the transfer this one reverses
+	ReversesTransferID sql.NullInt64 `json:"reverses_transfer_id"`
 }
 
 type User struct {
@@ -40,4 +157,61 @@ type User struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	// customer, teller or admin, tellers and admins are bank staff
+	Role string `json:"role"`
+	// failed logins since the last successful one, the lockouts grow with it
+	FailedLoginAttempts int32 `json:"failed_login_attempts"`
+	// the user can not log in before this time after too many failed logins
+	LockedUntil     sql.NullTime `json:"locked_until"`
+	IsEmailVerified bool         `json:"is_email_verified"`
+	// the access tokens issued before carry the old role, so they are rejected
+	RoleChangedAt time.Time `json:"role_changed_at"`
+	// an admin revoked the sessions of the user, the access tokens issued before are rejected
+	SessionsRevokedAt time.Time `json:"sessions_revoked_at"`
+}
+
+type VerifyEmail struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	// sha256 of the secret code in the mail, it is set once the mail is sent
+	SecretCodeHash string `json:"secret_code_hash"`
+	IsUsed         bool   `json:"is_used"`
+	Attempts       int32  `json:"attempts"`
+	LastError      string `json:"last_error"`
+	// the mail is not sent before, it is pushed back while a sender sends it and after a failed attempt
+	NextAttemptAt time.Time    `json:"next_attempt_at"`
+	SentAt        sql.NullTime `json:"sent_at"`
+	CreatedAt     time.Time    `json:"created_at"`
+	// the secret code can not be used after this time, it is set once the mail is sent
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	SubscriptionID int64           `json:"subscription_id"`
+	OutboxID       int64           `json:"outbox_id"`
+	EntryID        int64           `json:"entry_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	// pending until the subscriber accepts it, failed after the last attempt
+	Status    string `json:"status"`
+	Attempts  int32  `json:"attempts"`
+	LastError string `json:"last_error"`
+	// http status of the last attempt, null when no response was received
+	ResponseStatus sql.NullInt32 `json:"response_status"`
+	NextAttemptAt  time.Time     `json:"next_attempt_at"`
+	DeliveredAt    sql.NullTime  `json:"delivered_at"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+type WebhookSubscription struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+	Url   string `json:"url"`
+	// e.g. account.credited, account.debited
+	Events []string `json:"events"`
+	// key of the HMAC signature of the deliveries
+	Secret    string    `json:"secret"`
+	CreatedAt time.Time `json:"created_at"`
 }
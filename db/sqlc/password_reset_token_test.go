@@ -0,0 +1,203 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestQueries_CreatePasswordResetToken(t *testing.T) {
+	createRandomPasswordResetToken(t, createRandomUser(t), time.Hour)
+}
+
+func TestQueries_QueuePasswordResetToken(t *testing.T) {
+	user := createRandomUser(t)
+	expiresAt := time.Now().Add(time.Hour)
+
+	resetToken, err := testQueries.QueuePasswordResetToken(context.Background(), QueuePasswordResetTokenParams{
+		ExpiresAt: expiresAt,
+		Email:     user.Email,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Username, resetToken.Username)
+	require.Equal(t, user.Email, resetToken.Email)
+	require.Empty(t, resetToken.TokenHash)
+	require.False(t, resetToken.SentAt.Valid)
+	require.WithinDuration(t, expiresAt, resetToken.ExpiresAt, time.Second)
+
+	// no user has the email
+	_, err = testQueries.QueuePasswordResetToken(context.Background(), QueuePasswordResetTokenParams{
+		ExpiresAt: expiresAt,
+		Email:     util.RandomEmail(),
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	claimArgs := ClaimPasswordResetTokensParams{
+		LeaseUntil: time.Now().Add(time.Minute),
+		BatchSize:  1000,
+	}
+
+	claimed, err := testQueries.ClaimPasswordResetTokens(context.Background(), claimArgs)
+	require.NoError(t, err)
+	require.Contains(t, passwordResetTokenIDs(claimed), resetToken.ID)
+
+	// the token is leased to the first sender
+	claimed, err = testQueries.ClaimPasswordResetTokens(context.Background(), claimArgs)
+	require.NoError(t, err)
+	require.NotContains(t, passwordResetTokenIDs(claimed), resetToken.ID)
+
+	secret, err := util.NewSecret()
+	require.NoError(t, err)
+
+	err = testQueries.MarkPasswordResetTokenSent(context.Background(), MarkPasswordResetTokenSentParams{
+		TokenHash: util.HashSecret(secret),
+		ID:        resetToken.ID,
+	})
+	require.NoError(t, err)
+
+	// the token in the mail can be used
+	store := NewStore(testDB, zerolog.Nop())
+
+	result, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash:      util.HashSecret(secret),
+		HashedPassword: user.HashedPassword,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Username, result.User.Username)
+}
+
+func TestStore_ResetPasswordTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop())
+
+	user := createRandomUser(t)
+	resetToken := createRandomPasswordResetToken(t, user, time.Hour)
+	otherToken := createRandomPasswordResetToken(t, user, time.Hour)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(8))
+	require.NoError(t, err)
+
+	args := ResetPasswordTxParams{
+		TokenHash:      resetToken.TokenHash,
+		HashedPassword: hashedPassword,
+	}
+
+	result, err := store.ResetPasswordTx(context.Background(), args)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, result.User.Username)
+	require.Equal(t, hashedPassword, result.User.HashedPassword)
+	require.WithinDuration(t, time.Now(), result.User.PasswordChangedAt, 5*time.Second)
+
+	changedAt, err := testQueries.GetUserCredentialsChangedAt(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.WithinDuration(t, result.User.PasswordChangedAt, changedAt, time.Millisecond)
+
+	// the token can only be used once, and the other tokens of the user are used up too
+	_, err = store.ResetPasswordTx(context.Background(), args)
+	require.ErrorIs(t, err, ErrInvalidPasswordResetToken)
+
+	_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash:      otherToken.TokenHash,
+		HashedPassword: hashedPassword,
+	})
+	require.ErrorIs(t, err, ErrInvalidPasswordResetToken)
+}
+
+func TestStore_ResetPasswordTx_InvalidToken(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop())
+
+	user := createRandomUser(t)
+	expiredToken := createRandomPasswordResetToken(t, user, -time.Minute)
+
+	testCases := []struct {
+		name      string
+		tokenHash string
+	}{
+		{
+			name:      "NotFound",
+			tokenHash: util.HashSecret(util.RandomString(32)),
+		},
+		{
+			name:      "Expired",
+			tokenHash: expiredToken.TokenHash,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+				TokenHash:      testCase.tokenHash,
+				HashedPassword: user.HashedPassword,
+			})
+			require.ErrorIs(t, err, ErrInvalidPasswordResetToken)
+		})
+	}
+}
+
+func TestStore_ChangePasswordTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop())
+
+	session := createRandomSession(t)
+	resetToken := createRandomPasswordResetToken(t, User{Username: session.Username}, time.Hour)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(8))
+	require.NoError(t, err)
+
+	result, err := store.ChangePasswordTx(context.Background(), ChangePasswordTxParams{
+		Username:       session.Username,
+		HashedPassword: hashedPassword,
+	})
+	require.NoError(t, err)
+	require.Equal(t, hashedPassword, result.User.HashedPassword)
+	require.False(t, result.User.PasswordChangedAt.IsZero())
+
+	// the sessions of the old password are revoked
+	require.Len(t, result.RevokedSessions, 1)
+	require.Equal(t, session.ID, result.RevokedSessions[0].ID)
+
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// a reset token issued before the change can't undo it
+	_, err = store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		TokenHash:      resetToken.TokenHash,
+		HashedPassword: hashedPassword,
+	})
+	require.ErrorIs(t, err, ErrInvalidPasswordResetToken)
+}
+
+func createRandomPasswordResetToken(t *testing.T, user User, duration time.Duration) PasswordResetToken {
+	secret, err := util.NewSecret()
+	require.NoError(t, err)
+
+	args := CreatePasswordResetTokenParams{
+		Username:  user.Username,
+		TokenHash: util.HashSecret(secret),
+		ExpiresAt: time.Now().Add(duration),
+	}
+
+	resetToken, err := testQueries.CreatePasswordResetToken(context.Background(), args)
+	require.NoError(t, err)
+
+	require.NotZero(t, resetToken.ID)
+	require.Equal(t, args.Username, resetToken.Username)
+	require.Equal(t, args.TokenHash, resetToken.TokenHash)
+	require.WithinDuration(t, args.ExpiresAt, resetToken.ExpiresAt, time.Second)
+	require.False(t, resetToken.UsedAt.Valid)
+	require.NotZero(t, resetToken.CreatedAt)
+
+	return resetToken
+}
+
+func passwordResetTokenIDs(resetTokens []PasswordResetToken) []int64 {
+	ids := make([]int64, 0, len(resetTokens))
+	for _, resetToken := range resetTokens {
+		ids = append(ids, resetToken.ID)
+	}
+
+	return ids
+}
@@ -6,24 +6,104 @@ package db
 
 import (
 	"context"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type Querier interface {
 	AddToAccountBalance(ctx context.Context, arg AddToAccountBalanceParams) (Account, error)
+	BlockSession(ctx context.Context, id uuid.UUID) (Session, error)
+	BlockUserSessions(ctx context.Context, username string) ([]Session, error)
+	// pushes next_attempt_at of the claimed events to lease_until, so other dispatchers skip them,
+	// an event whose dispatcher stopped before it was delivered is claimed again after the lease
+	ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]Outbox, error)
+	// pushes next_attempt_at of the claimed tokens to lease_until, so other senders skip them
+	// the tokens that expired or were used up before their mail was sent are not mailed anymore
+	ClaimPasswordResetTokens(ctx context.Context, arg ClaimPasswordResetTokensParams) ([]PasswordResetToken, error)
+	// pushes next_attempt_at of the claimed mails to lease_until, so other senders skip them
+	ClaimVerifyEmails(ctx context.Context, arg ClaimVerifyEmailsParams) ([]VerifyEmail, error)
+	// pushes next_attempt_at of the claimed deliveries to lease_until, so other senders skip them
+	ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]WebhookDelivery, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (AuditEvent, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateExternalTransaction(ctx context.Context, arg CreateExternalTransactionParams) (ExternalTransaction, error)
+	CreateIdempotencyKey(ctx context.Context, arg CreateIdempotencyKeyParams) (IdempotencyKey, error)
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (Outbox, error)
+	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) (PasswordResetToken, error)
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error)
+	// returns no rows when the delivery of the event was already created
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDelivery, error)
+	CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (WebhookSubscription, error)
 	DeleteAccount(ctx context.Context, id int64) error
+	DeleteWebhookSubscription(ctx context.Context, id int64) error
 	GetAccount(ctx context.Context, id int64) (Account, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	GetCurrency(ctx context.Context, code string) (Currency, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetExternalTransaction(ctx context.Context, id int64) (ExternalTransaction, error)
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error)
+	GetPasswordResetTokenForUpdate(ctx context.Context, tokenHash string) (PasswordResetToken, error)
+	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	// the tokens issued before the password or the role of the user last changed are revoked
+	GetUserCredentialsChangedAt(ctx context.Context, username string) (time.Time, error)
+	GetVerifyEmailForUpdate(ctx context.Context, id int64) (VerifyEmail, error)
+	GetWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error)
+	GetWebhookSubscription(ctx context.Context, id int64) (WebhookSubscription, error)
+	IsTokenRevoked(ctx context.Context, id uuid.UUID) (bool, error)
+	IsUserEmailVerified(ctx context.Context, username string) (bool, error)
+	ListAccountStatement(ctx context.Context, arg ListAccountStatementParams) ([]Entry, error)
+	ListAccountStatementAfter(ctx context.Context, arg ListAccountStatementAfterParams) ([]Entry, error)
+	ListAccountStatementBefore(ctx context.Context, arg ListAccountStatementBeforeParams) ([]Entry, error)
+	ListAccountTransfers(ctx context.Context, arg ListAccountTransfersParams) ([]Transfer, error)
+	ListAccountTransfersAfter(ctx context.Context, arg ListAccountTransfersAfterParams) ([]Transfer, error)
+	ListAccountTransfersBefore(ctx context.Context, arg ListAccountTransfersBeforeParams) ([]Transfer, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	ListAccountsAfter(ctx context.Context, arg ListAccountsAfterParams) ([]Account, error)
+	ListAccountsBefore(ctx context.Context, arg ListAccountsBeforeParams) ([]Account, error)
+	ListAuditEvents(ctx context.Context, arg ListAuditEventsParams) ([]AuditEvent, error)
+	ListCurrencies(ctx context.Context) ([]Currency, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListWebhookSubscriptions(ctx context.Context, owner string) ([]WebhookSubscription, error)
+	ListWebhookSubscriptionsByEvent(ctx context.Context, arg ListWebhookSubscriptionsByEventParams) ([]WebhookSubscription, error)
+	MarkOutboxEventDelivered(ctx context.Context, id int64) error
+	MarkOutboxEventFailed(ctx context.Context, arg MarkOutboxEventFailedParams) error
+	MarkPasswordResetTokenFailed(ctx context.Context, arg MarkPasswordResetTokenFailedParams) error
+	MarkPasswordResetTokenSent(ctx context.Context, arg MarkPasswordResetTokenSentParams) error
+	MarkVerifyEmailFailed(ctx context.Context, arg MarkVerifyEmailFailedParams) error
+	MarkVerifyEmailSent(ctx context.Context, arg MarkVerifyEmailSentParams) error
+	MarkWebhookDeliveryDelivered(ctx context.Context, arg MarkWebhookDeliveryDeliveredParams) error
+	MarkWebhookDeliveryFailed(ctx context.Context, arg MarkWebhookDeliveryFailedParams) error
+	// creates a token for the user with the email, its mail is sent in the background, there is no row when no user has the email
+	QueuePasswordResetToken(ctx context.Context, arg QueuePasswordResetTokenParams) (PasswordResetToken, error)
+	// the user is locked out from the max_attempts-th failed login on, and every failure after a lockout doubles it,
+	// up to max_lockout_seconds
+	RecordFailedLogin(ctx context.Context, arg RecordFailedLoginParams) (User, error)
+	ReplayWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error)
+	RevokeToken(ctx context.Context, arg RevokeTokenParams) error
+	UnlockUser(ctx context.Context, username string) (User, error)
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	UpdateAccountStatus(ctx context.Context, arg UpdateAccountStatusParams) (Account, error)
+	UpdateCurrencyEnabled(ctx context.Context, arg UpdateCurrencyEnabledParams) (Currency, error)
+	UpdateIdempotencyKeyResponse(ctx context.Context, arg UpdateIdempotencyKeyResponseParams) (IdempotencyKey, error)
+	// the user proved they own the account, so a lockout after failed logins ends too
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	// the access tokens issued before are rejected, like after a password or a role change
+	UpdateUserSessionsRevokedAt(ctx context.Context, username string) (User, error)
+	UseUserPasswordResetTokens(ctx context.Context, username string) error
+	UseVerifyEmail(ctx context.Context, id int64) (VerifyEmail, error)
+	// the email must still be the one the code was sent to
+	VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestQueries_CreateSession(t *testing.T) {
+	createRandomSession(t)
+}
+
+func TestQueries_GetSession(t *testing.T) {
+	session1 := createRandomSession(t)
+
+	session2, err := testQueries.GetSession(context.Background(), session1.ID)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, session2)
+
+	require.Equal(t, session1.ID, session2.ID)
+	require.Equal(t, session1.Username, session2.Username)
+	require.Equal(t, session1.RefreshToken, session2.RefreshToken)
+	require.Equal(t, session1.IsBlocked, session2.IsBlocked)
+	require.WithinDuration(t, session1.ExpiresAt, session2.ExpiresAt, time.Second)
+}
+
+func createRandomSession(t *testing.T) Session {
+	user := createRandomUser(t)
+
+	args := CreateSessionParams{
+		ID:           uuid.New(),
+		Username:     user.Username,
+		RefreshToken: util.RandomString(32),
+		UserAgent:    util.RandomString(10),
+		ClientIp:     "127.0.0.1",
+		IsBlocked:    false,
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}
+
+	session, err := testQueries.CreateSession(context.Background(), args)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, session)
+
+	require.Equal(t, args.ID, session.ID)
+	require.Equal(t, args.Username, session.Username)
+	require.Equal(t, args.RefreshToken, session.RefreshToken)
+	require.False(t, session.IsBlocked)
+	require.NotZero(t, session.CreatedAt)
+
+	return session
+}
+
+func TestQueries_RevokeToken(t *testing.T) {
+	session := createRandomSession(t)
+
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	args := RevokeTokenParams{
+		ID:        session.ID,
+		Username:  session.Username,
+		ExpiresAt: session.ExpiresAt,
+	}
+
+	err = testQueries.RevokeToken(context.Background(), args)
+	require.NoError(t, err)
+
+	// revoking twice is not an error
+	err = testQueries.RevokeToken(context.Background(), args)
+	require.NoError(t, err)
+
+	revoked, err = testQueries.IsTokenRevoked(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func TestStore_RevokeUserSessionsTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop())
+
+	session1 := createRandomSession(t)
+
+	result, err := store.RevokeUserSessionsTx(context.Background(), session1.Username)
+	require.NoError(t, err)
+	require.Len(t, result.RevokedSessions, 1)
+	require.Equal(t, session1.ID, result.RevokedSessions[0].ID)
+	require.True(t, result.RevokedSessions[0].IsBlocked)
+	require.WithinDuration(t, time.Now(), result.User.SessionsRevokedAt, 5*time.Second)
+
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), session1.ID)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	// the access tokens issued before are revoked too
+	changedAt, err := testQueries.GetUserCredentialsChangedAt(context.Background(), session1.Username)
+	require.NoError(t, err)
+	require.WithinDuration(t, result.User.SessionsRevokedAt, changedAt, time.Millisecond)
+
+	// there is nothing left to revoke
+	result, err = store.RevokeUserSessionsTx(context.Background(), session1.Username)
+	require.NoError(t, err)
+	require.Empty(t, result.RevokedSessions)
+
+	_, err = store.RevokeUserSessionsTx(context.Background(), util.RandomOwner())
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -2,8 +2,87 @@ package db
 
 import (
 	"context"
+	"crypto/subtle"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/aybarsacar/simplebank/fx"
+	"github.com/aybarsacar/simplebank/metrics"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+// Different types of error returned by the transactions of the store
+var (
+	// ErrInsufficientFunds is returned when a transfer would leave the sender with a negative balance
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrIdempotencyKeyConflict is returned when an idempotency key is reused with a different request
+	ErrIdempotencyKeyConflict = errors.New("idempotency key was already used with a different request")
+	// ErrAmountTooSmall is returned when the converted amount of a cross-currency transfer rounds down to zero
+	ErrAmountTooSmall = errors.New("amount is too small to be converted")
+	// ErrDuplicateExternalReference is returned when a source posts the same external reference twice
+	ErrDuplicateExternalReference = errors.New("external reference was already posted by the source")
+	// ErrTransferAlreadyReversed is returned when a transfer that was already reversed is reversed again
+	ErrTransferAlreadyReversed = errors.New("transfer was already reversed")
+	// ErrTransferNotReversible is returned when a reversal is reversed
+	ErrTransferNotReversible = errors.New("a reversal can not be reversed")
+	// ErrAccountFrozen is returned when money would leave a frozen account
+	ErrAccountFrozen = errors.New("account is frozen")
+	// ErrAccountClosed is returned when money would enter or leave a closed account, or a closed account would be reopened
+	ErrAccountClosed = errors.New("account is closed")
+	// ErrAccountBalanceNotZero is returned when an account that still holds money would be closed
+	ErrAccountBalanceNotZero = errors.New("account balance must be zero to close it")
+	// ErrInvalidPasswordResetToken is returned when a password reset token doesn't exist, was already used or has expired
+	ErrInvalidPasswordResetToken = errors.New("password reset token is invalid or has expired")
+	// ErrInvalidVerifyEmail is returned when an email verification doesn't exist, was already used, has expired
+	// or its secret code doesn't match
+	ErrInvalidVerifyEmail = errors.New("email verification is invalid or has expired")
+)
+
+const (
+	// name of the CHECK constraint that keeps accounts.balance from going below zero
+	balanceNonNegativeConstraint = "balance_non_negative"
+	// name of the UNIQUE constraint on the source and external reference of external transactions
+	sourceExternalReferenceConstraint = "source_external_reference_key"
+	// name of the UNIQUE constraint that lets a transfer be reversed only once
+	reversesTransferIDConstraint = "transfers_reverses_transfer_id_key"
+)
+
+// statuses of accounts
+const (
+	AccountStatusActive = "active"
+	AccountStatusFrozen = "frozen"
+	AccountStatusClosed = "closed"
+)
+
+// types of the events written to the outbox
+const (
+	EventTransferCompleted            = "transfer.completed"
+	EventExternalTransactionCompleted = "external_transaction.completed"
+)
+
+// TransferCompletedEvent is the payload of the transfer.completed events
+type TransferCompletedEvent struct {
+	Transfer  Transfer `json:"transfer"`
+	FromEntry Entry    `json:"from_entry"`
+	ToEntry   Entry    `json:"to_entry"`
+}
+
+// ExternalTransactionCompletedEvent is the payload of the external_transaction.completed events
+type ExternalTransactionCompletedEvent struct {
+	ExternalTransaction ExternalTransaction `json:"external_transaction"`
+	Entry               Entry               `json:"entry"`
+}
+
+// kinds of external transactions
+const (
+	ExternalTransactionDeposit    = "deposit"
+	ExternalTransactionWithdrawal = "withdrawal"
 )
 
 // Store provides the signature, so we don't depend on concrete implementation
@@ -11,6 +90,20 @@ import (
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, args TransferTxParams) (TransferTxResult, error)
+	CrossCurrencyTransferTx(ctx context.Context, args CrossCurrencyTransferTxParams) (TransferTxResult, error)
+	IdempotentTransferTx(ctx context.Context, args IdempotentTransferTxParams) (TransferTxResult, error)
+	CreateUserTx(ctx context.Context, args CreateUserParams) (CreateUserTxResult, error)
+	VerifyEmailTx(ctx context.Context, args VerifyEmailTxParams) (VerifyEmailTxResult, error)
+	LogoutTx(ctx context.Context, args LogoutTxParams) error
+	RevokeUserSessionsTx(ctx context.Context, username string) (RevokeUserSessionsTxResult, error)
+	UpdateUserRoleTx(ctx context.Context, args UpdateUserRoleParams) (UpdateUserRoleTxResult, error)
+	ChangePasswordTx(ctx context.Context, args ChangePasswordTxParams) (ChangePasswordTxResult, error)
+	ResetPasswordTx(ctx context.Context, args ResetPasswordTxParams) (ChangePasswordTxResult, error)
+	DepositTx(ctx context.Context, args ExternalTxParams) (ExternalTxResult, error)
+	WithdrawTx(ctx context.Context, args ExternalTxParams) (ExternalTxResult, error)
+	ReverseTransferTx(ctx context.Context, transferID int64) (TransferTxResult, error)
+	UpdateAccountStatusTx(ctx context.Context, args UpdateAccountStatusTxParams) (Account, error)
+	Ping(ctx context.Context) error
 }
 
 // SQLStore provides all functions to execute db queries and transactions
@@ -19,20 +112,30 @@ type SQLStore struct {
 	*Queries
 
 	db *sql.DB
+	// logger is used when the context doesn't carry the logger of a request
+	logger zerolog.Logger
 }
 
 // NewStore constructor
-func NewStore(db *sql.DB) Store {
+func NewStore(db *sql.DB, logger zerolog.Logger) Store {
 	return &SQLStore{
 		db:      db,
-		Queries: New(db),
+		Queries: New(tracedDBTX{db: db}),
+		logger:  logger,
 	}
 }
 
+// Ping checks the database can be reached, e.g. for the readiness probe
+func (s *SQLStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
 type TransferTxParams struct {
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
 	Amount        int64 `json:"amount"`
+	// Currency is the currency of the from account, which the amount is in, it only labels the metrics
+	Currency string `json:"currency"`
 }
 
 type TransferTxResult struct {
@@ -50,70 +153,769 @@ func (s *SQLStore) TransferTx(ctx context.Context, args TransferTxParams) (Trans
 	var result TransferTxResult
 
 	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = transfer(ctx, q, args, fx.Rate{})
+		return err
+	})
+
+	metrics.ObserveTransfer(metrics.TransferKindSameCurrency, transferOutcome(err), args.Currency, args.Amount)
+
+	return result, err
+}
+
+type CrossCurrencyTransferTxParams struct {
+	TransferTxParams
+	// Rate converts the amount, in the currency of the from account, to the currency of the to account
+	Rate fx.Rate `json:"-"`
+}
+
+// CrossCurrencyTransferTx performs a money transfer between accounts of different currencies
+// The from account is debited the amount in its own currency and the to account is credited the converted amount,
+// the transfer records the rate and both amounts
+func (s *SQLStore) CrossCurrencyTransferTx(ctx context.Context, args CrossCurrencyTransferTxParams) (TransferTxResult, error) {
+
+	var result TransferTxResult
 
+	err := s.execTx(ctx, func(q *Queries) error {
 		var err error
+		result, err = transfer(ctx, q, args.TransferTxParams, args.Rate)
+		return err
+	})
+
+	metrics.ObserveTransfer(metrics.TransferKindCrossCurrency, transferOutcome(err), args.Currency, args.Amount)
+
+	return result, err
+}
+
+type IdempotentTransferTxParams struct {
+	TransferTxParams
+	// Rate is only set for transfers between accounts of different currencies
+	Rate           fx.Rate `json:"-"`
+	Username       string  `json:"username"`
+	IdempotencyKey string  `json:"idempotency_key"`
+	RequestHash    string  `json:"request_hash"`
+}
+
+// IdempotentTransferTx performs a money transfer at most once per username and idempotency key
+// The key is claimed in the same database transaction as the transfer, so a replay of the same request
+// returns the result of the original transfer instead of moving the money again
+func (s *SQLStore) IdempotentTransferTx(ctx context.Context, args IdempotentTransferTxParams) (TransferTxResult, error) {
+
+	var result TransferTxResult
+	replayed := false
+
+	err := s.execTx(ctx, func(q *Queries) error {
 
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
-			FromAccountID: args.FromAccountID,
-			ToAccountID:   args.ToAccountID,
-			Amount:        args.Amount,
+		// a concurrent request with the same key blocks here until the first one commits or rolls back
+		_, err := q.CreateIdempotencyKey(ctx, CreateIdempotencyKeyParams{
+			Username:       args.Username,
+			IdempotencyKey: args.IdempotencyKey,
+			RequestHash:    args.RequestHash,
 		})
 
+		if err == sql.ErrNoRows {
+			// the key is already used, replay the stored result
+			key, err := q.GetIdempotencyKey(ctx, GetIdempotencyKeyParams{
+				Username:       args.Username,
+				IdempotencyKey: args.IdempotencyKey,
+			})
+
+			if err != nil {
+				return err
+			}
+
+			if key.RequestHash != args.RequestHash {
+				return ErrIdempotencyKeyConflict
+			}
+
+			replayed = true
+
+			return json.Unmarshal(key.Response, &result)
+		}
+
 		if err != nil {
 			return err
 		}
 
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: args.FromAccountID,
-			Amount:    -args.Amount,
+		result, err = transfer(ctx, q, args.TransferTxParams, args.Rate)
+		if err != nil {
+			return err
+		}
+
+		response, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.UpdateIdempotencyKeyResponse(ctx, UpdateIdempotencyKeyResponseParams{
+			Username:       args.Username,
+			IdempotencyKey: args.IdempotencyKey,
+			Response:       response,
 		})
 
+		return err
+	})
+
+	kind := metrics.TransferKindSameCurrency
+	if !args.Rate.IsZero() {
+		kind = metrics.TransferKindCrossCurrency
+	}
+
+	outcome := transferOutcome(err)
+	if err == nil && replayed {
+		// the money was moved by the original request
+		outcome = metrics.TransferOutcomeReplayed
+	}
+
+	metrics.ObserveTransfer(kind, outcome, args.Currency, args.Amount)
+
+	return result, err
+}
+
+// transferOutcome labels the transfers in the metrics, the expected failures by their error
+func transferOutcome(err error) string {
+	switch {
+	case err == nil:
+		return metrics.TransferOutcomeSuccess
+	case errors.Is(err, ErrInsufficientFunds):
+		return "insufficient_funds"
+	case errors.Is(err, ErrAccountFrozen):
+		return "account_frozen"
+	case errors.Is(err, ErrAccountClosed):
+		return "account_closed"
+	case errors.Is(err, ErrAmountTooSmall):
+		return "amount_too_small"
+	case errors.Is(err, ErrIdempotencyKeyConflict):
+		return "idempotency_key_conflict"
+	default:
+		return metrics.TransferOutcomeError
+	}
+}
+
+// transfer runs the statements of a money transfer with the given queries
+// the rate is the zero value for transfers between accounts of the same currency
+// the caller is responsible for running it inside a database transaction
+func transfer(ctx context.Context, q *Queries, args TransferTxParams, rate fx.Rate) (TransferTxResult, error) {
+
+	var result TransferTxResult
+	var err error
+
+	toAmount := args.Amount
+	exchangeRate := "1"
+
+	if !rate.IsZero() {
+		toAmount, err = rate.Convert(args.Amount)
+		if err != nil {
+			return result, err
+		}
+
+		if toAmount <= 0 {
+			return result, ErrAmountTooSmall
+		}
+
+		exchangeRate = rate.String()
+	}
+
+	return moveMoney(ctx, q, CreateTransferParams{
+		FromAccountID: args.FromAccountID,
+		ToAccountID:   args.ToAccountID,
+		Amount:        args.Amount,
+		ToAmount:      toAmount,
+		ExchangeRate:  exchangeRate,
+	})
+}
+
+// ReverseTransferTx moves the money of a transfer back to the account it was sent from
+// The to account is debited the amount it received and the from account is credited the amount it sent,
+// so a cross-currency reversal does not lose money to rounding. A transfer can only be reversed once
+func (s *SQLStore) ReverseTransferTx(ctx context.Context, transferID int64) (TransferTxResult, error) {
+
+	var result TransferTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		original, err := q.GetTransfer(ctx, transferID)
 		if err != nil {
 			return err
 		}
 
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: args.ToAccountID,
-			Amount:    +args.Amount,
+		if original.ReversesTransferID.Valid {
+			return ErrTransferNotReversible
+		}
+
+		// transfers between accounts of the same currency record a rate of 1
+		exchangeRate := "1"
+		if original.ExchangeRate != exchangeRate {
+			rate, err := fx.NewRate("", "", original.ExchangeRate)
+			if err != nil {
+				return err
+			}
+
+			exchangeRate = rate.Inverse().String()
+		}
+
+		result, err = moveMoney(ctx, q, CreateTransferParams{
+			FromAccountID:      original.ToAccountID,
+			ToAccountID:        original.FromAccountID,
+			Amount:             original.ToAmount,
+			ToAmount:           original.Amount,
+			ExchangeRate:       exchangeRate,
+			ReversesTransferID: sql.NullInt64{Int64: original.ID, Valid: true},
+		})
+
+		return err
+	})
+
+	return result, err
+}
+
+// moveMoney records the transfer and its entries and updates the balances of both accounts
+// the from account is debited the amount and the to account is credited the to amount
+func moveMoney(ctx context.Context, q *Queries, args CreateTransferParams) (TransferTxResult, error) {
+
+	var result TransferTxResult
+	var err error
+
+	result.Transfer, err = q.CreateTransfer(ctx, args)
+
+	if isUniqueViolation(err, reversesTransferIDConstraint) {
+		return result, ErrTransferAlreadyReversed
+	}
+
+	if err != nil {
+		return result, err
+	}
+
+	// always update the account with the smaller id first to avoid deadlocks
+	if args.FromAccountID < args.ToAccountID {
+		// decrement the from accounts balance by the amount
+		result.FromAccount, result.ToAccount, err = addMoney(ctx, q, args.FromAccountID, -args.Amount, args.ToAccountID, args.ToAmount)
+	} else {
+		// decrement the from accounts balance by the amount
+		result.ToAccount, result.FromAccount, err = addMoney(ctx, q, args.ToAccountID, args.ToAmount, args.FromAccountID, -args.Amount)
+	}
+
+	if isInsufficientFunds(err) {
+		return result, ErrInsufficientFunds
+	}
+
+	if err != nil {
+		return result, err
+	}
+
+	// the updates lock both rows, so the statuses can't change before the transaction commits
+	if err = canDebit(result.FromAccount); err != nil {
+		return result, err
+	}
+
+	if err = canCredit(result.ToAccount); err != nil {
+		return result, err
+	}
+
+	// the entries are written after the balances, so they store the balances the updates returned,
+	// the updates hold the locks of the rows until the commit, so no other transfer can come in between
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:    args.FromAccountID,
+		Amount:       -args.Amount,
+		BalanceAfter: result.FromAccount.Balance,
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:    args.ToAccountID,
+		Amount:       +args.ToAmount,
+		BalanceAfter: result.ToAccount.Balance,
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	// the event is written in the same transaction, so it exists if and only if the transfer commits
+	err = writeOutboxEvent(ctx, q, EventTransferCompleted, TransferCompletedEvent{
+		Transfer:  result.Transfer,
+		FromEntry: result.FromEntry,
+		ToEntry:   result.ToEntry,
+	})
+
+	return result, err
+}
+
+func writeOutboxEvent(ctx context.Context, q *Queries, eventType string, event any) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateOutboxEvent(ctx, CreateOutboxEventParams{
+		EventType: eventType,
+		Payload:   payload,
+	})
+
+	return err
+}
+
+type ExternalTxParams struct {
+	AccountID         int64  `json:"account_id"`
+	Amount            int64  `json:"amount"`
+	Source            string `json:"source"`
+	ExternalReference string `json:"external_reference"`
+}
+
+type ExternalTxResult struct {
+	ExternalTransaction ExternalTransaction `json:"external_transaction"`
+	Account             Account             `json:"account"`
+	Entry               Entry               `json:"entry"`
+}
+
+// DepositTx adds money that comes from outside the bank to an account
+// It creates the entry, records the external reference and updates the account balance in a single database transaction
+func (s *SQLStore) DepositTx(ctx context.Context, args ExternalTxParams) (ExternalTxResult, error) {
+	return s.externalTx(ctx, ExternalTransactionDeposit, args.Amount, args)
+}
+
+// WithdrawTx takes money out of an account to outside the bank
+// It creates the entry, records the external reference and updates the account balance in a single database transaction
+func (s *SQLStore) WithdrawTx(ctx context.Context, args ExternalTxParams) (ExternalTxResult, error) {
+	return s.externalTx(ctx, ExternalTransactionWithdrawal, -args.Amount, args)
+}
+
+func (s *SQLStore) externalTx(ctx context.Context, kind string, entryAmount int64, args ExternalTxParams) (ExternalTxResult, error) {
+
+	var result ExternalTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.Account, err = q.AddToAccountBalance(ctx, AddToAccountBalanceParams{
+			ID:     args.AccountID,
+			Amount: entryAmount,
 		})
 
-		// always update the account with the smaller id first to avoid deadlocks
-		if args.FromAccountID < args.ToAccountID {
-			// decrement the from accounts balance by the amount
-			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, args.FromAccountID, -args.Amount, args.ToAccountID, args.Amount)
+		if isInsufficientFunds(err) {
+			return ErrInsufficientFunds
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if kind == ExternalTransactionWithdrawal {
+			err = canDebit(result.Account)
 		} else {
-			// decrement the from accounts balance by the amount
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, args.ToAccountID, args.Amount, args.FromAccountID, -args.Amount)
+			err = canCredit(result.Account)
+		}
+
+		if err != nil {
+			return err
 		}
 
-		return nil
+		// the entry stores the balance the update returned, the row stays locked until the commit
+		result.Entry, err = q.CreateEntry(ctx, CreateEntryParams{
+			AccountID:    args.AccountID,
+			Amount:       entryAmount,
+			BalanceAfter: result.Account.Balance,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		result.ExternalTransaction, err = q.CreateExternalTransaction(ctx, CreateExternalTransactionParams{
+			AccountID:         args.AccountID,
+			EntryID:           result.Entry.ID,
+			Kind:              kind,
+			Amount:            args.Amount,
+			Source:            args.Source,
+			ExternalReference: args.ExternalReference,
+		})
+
+		if isUniqueViolation(err, sourceExternalReferenceConstraint) {
+			return ErrDuplicateExternalReference
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return writeOutboxEvent(ctx, q, EventExternalTransactionCompleted, ExternalTransactionCompletedEvent{
+			ExternalTransaction: result.ExternalTransaction,
+			Entry:               result.Entry,
+		})
 	})
 
 	return result, err
 }
 
+type UpdateAccountStatusTxParams struct {
+	AccountID int64  `json:"account_id"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason"`
+}
+
+// UpdateAccountStatusTx freezes, unfreezes or closes an account
+// A closed account can't change its status anymore and only an account with a zero balance can be closed,
+// the account row is kept so its entries and transfers can still be read
+func (s *SQLStore) UpdateAccountStatusTx(ctx context.Context, args UpdateAccountStatusTxParams) (Account, error) {
+
+	var account Account
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		// lock the account, so no transfer changes its balance before the status is updated
+		current, err := q.GetAccountForUpdate(ctx, args.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if current.Status == AccountStatusClosed {
+			return ErrAccountClosed
+		}
+
+		if args.Status == AccountStatusClosed && current.Balance != 0 {
+			return ErrAccountBalanceNotZero
+		}
+
+		account, err = q.UpdateAccountStatus(ctx, UpdateAccountStatusParams{
+			Status:       args.Status,
+			StatusReason: args.Reason,
+			ID:           args.AccountID,
+		})
+
+		return err
+	})
+
+	return account, err
+}
+
+type CreateUserTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+// CreateUserTx creates the user and the verification of their email,
+// whose mail is sent by a background sender once the transaction is committed
+func (s *SQLStore) CreateUserTx(ctx context.Context, args CreateUserParams) (CreateUserTxResult, error) {
+
+	var result CreateUserTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.User, err = q.CreateUser(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		result.VerifyEmail, err = q.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+			Username: result.User.Username,
+			Email:    result.User.Email,
+		})
+
+		return err
+	})
+
+	return result, err
+}
+
+type VerifyEmailTxParams struct {
+	ID int64 `json:"id"`
+	// sha256 of the secret code the user sent
+	SecretCodeHash string `json:"secret_code_hash"`
+}
+
+type VerifyEmailTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+// VerifyEmailTx marks the email of the user as verified with the secret code of a verification,
+// the code can only be used once and only before it expires, otherwise ErrInvalidVerifyEmail is returned
+func (s *SQLStore) VerifyEmailTx(ctx context.Context, args VerifyEmailTxParams) (VerifyEmailTxResult, error) {
+
+	var result VerifyEmailTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		// lock the verification, so its code can only be used by one of the requests that send it at the same time
+		verifyEmail, err := q.GetVerifyEmailForUpdate(ctx, args.ID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrInvalidVerifyEmail
+			}
+
+			return err
+		}
+
+		// the hash is empty until the mail is sent
+		if verifyEmail.IsUsed ||
+			verifyEmail.SecretCodeHash == "" ||
+			subtle.ConstantTimeCompare([]byte(verifyEmail.SecretCodeHash), []byte(args.SecretCodeHash)) != 1 ||
+			!time.Now().Before(verifyEmail.ExpiredAt) {
+			return ErrInvalidVerifyEmail
+		}
+
+		result.VerifyEmail, err = q.UseVerifyEmail(ctx, verifyEmail.ID)
+		if err != nil {
+			return err
+		}
+
+		result.User, err = q.VerifyUserEmail(ctx, VerifyUserEmailParams{
+			Username: verifyEmail.Username,
+			Email:    verifyEmail.Email,
+		})
+
+		// the user changed their email after the code was sent
+		if err == sql.ErrNoRows {
+			return ErrInvalidVerifyEmail
+		}
+
+		return err
+	})
+
+	return result, err
+}
+
+type LogoutTxParams struct {
+	Username             string        `json:"username"`
+	AccessTokenID        uuid.UUID     `json:"access_token_id"`
+	AccessTokenExpiresAt time.Time     `json:"access_token_expires_at"`
+	SessionID            uuid.NullUUID `json:"session_id"`
+}
+
+// LogoutTx revokes the access token of the user and, if given, blocks and revokes the session of the refresh token
+func (s *SQLStore) LogoutTx(ctx context.Context, args LogoutTxParams) error {
+	return s.execTx(ctx, func(q *Queries) error {
+
+		err := q.RevokeToken(ctx, RevokeTokenParams{
+			ID:        args.AccessTokenID,
+			Username:  args.Username,
+			ExpiresAt: args.AccessTokenExpiresAt,
+		})
+
+		if err != nil || !args.SessionID.Valid {
+			return err
+		}
+
+		session, err := q.BlockSession(ctx, args.SessionID.UUID)
+		if err != nil {
+			return err
+		}
+
+		return q.RevokeToken(ctx, RevokeTokenParams{
+			ID:        session.ID,
+			Username:  session.Username,
+			ExpiresAt: session.ExpiresAt,
+		})
+	})
+}
+
+type RevokeUserSessionsTxResult struct {
+	User User `json:"user"`
+	// the sessions that were active, their refresh tokens can't be used anymore
+	RevokedSessions []Session `json:"revoked_sessions"`
+}
+
+// RevokeUserSessionsTx blocks every active session of the user and adds them to the revoked tokens,
+// the access tokens issued before are rejected too, by the sessions_revoked_at of the user
+func (s *SQLStore) RevokeUserSessionsTx(ctx context.Context, username string) (RevokeUserSessionsTxResult, error) {
+
+	var result RevokeUserSessionsTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.User, err = q.UpdateUserSessionsRevokedAt(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		result.RevokedSessions, err = revokeUserSessions(ctx, q, username)
+		return err
+	})
+
+	return result, err
+}
+
+type UpdateUserRoleTxResult struct {
+	User User `json:"user"`
+	// the sessions of the old role, their refresh tokens can't be used anymore
+	RevokedSessions []Session `json:"revoked_sessions"`
+}
+
+// UpdateUserRoleTx changes the role of the user and revokes their sessions,
+// so the refresh tokens that carry the old role can't be used to get new access tokens
+func (s *SQLStore) UpdateUserRoleTx(ctx context.Context, args UpdateUserRoleParams) (UpdateUserRoleTxResult, error) {
+
+	var result UpdateUserRoleTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.User, err = q.UpdateUserRole(ctx, args)
+		if err != nil {
+			return err
+		}
+
+		result.RevokedSessions, err = revokeUserSessions(ctx, q, args.Username)
+		return err
+	})
+
+	return result, err
+}
+
+type ChangePasswordTxParams struct {
+	Username       string `json:"username"`
+	HashedPassword string `json:"hashed_password"`
+}
+
+type ChangePasswordTxResult struct {
+	User User `json:"user"`
+	// the sessions of the old password, their refresh tokens can't be used anymore
+	RevokedSessions []Session `json:"revoked_sessions"`
+}
+
+// ChangePasswordTx sets the password of the user and revokes their sessions,
+// the password reset tokens the user didn't use yet are used up, so they can't undo the change
+func (s *SQLStore) ChangePasswordTx(ctx context.Context, args ChangePasswordTxParams) (ChangePasswordTxResult, error) {
+
+	var result ChangePasswordTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = changePassword(ctx, q, args)
+		return err
+	})
+
+	return result, err
+}
+
+type ResetPasswordTxParams struct {
+	// sha256 of the token sent to the user
+	TokenHash      string `json:"token_hash"`
+	HashedPassword string `json:"hashed_password"`
+}
+
+// ResetPasswordTx changes the password of the user a password reset token was issued to, like ChangePasswordTx
+// it returns ErrInvalidPasswordResetToken when the token doesn't exist, was already used or has expired
+func (s *SQLStore) ResetPasswordTx(ctx context.Context, args ResetPasswordTxParams) (ChangePasswordTxResult, error) {
+
+	var result ChangePasswordTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		// lock the token, so it can only be used by one of the requests that send it at the same time
+		resetToken, err := q.GetPasswordResetTokenForUpdate(ctx, args.TokenHash)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrInvalidPasswordResetToken
+			}
+
+			return err
+		}
+
+		if resetToken.UsedAt.Valid || !time.Now().Before(resetToken.ExpiresAt) {
+			return ErrInvalidPasswordResetToken
+		}
+
+		result, err = changePassword(ctx, q, ChangePasswordTxParams{
+			Username:       resetToken.Username,
+			HashedPassword: args.HashedPassword,
+		})
+
+		return err
+	})
+
+	return result, err
+}
+
+func changePassword(ctx context.Context, q *Queries, args ChangePasswordTxParams) (result ChangePasswordTxResult, err error) {
+	result.User, err = q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+		HashedPassword: args.HashedPassword,
+		Username:       args.Username,
+	})
+
+	if err != nil {
+		return
+	}
+
+	if err = q.UseUserPasswordResetTokens(ctx, args.Username); err != nil {
+		return
+	}
+
+	result.RevokedSessions, err = revokeUserSessions(ctx, q, args.Username)
+	return
+}
+
+// blocks every active session of the user and adds them to the revoked tokens
+func revokeUserSessions(ctx context.Context, q *Queries, username string) ([]Session, error) {
+	sessions, err := q.BlockUserSessions(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		err = q.RevokeToken(ctx, RevokeTokenParams{
+			ID:        session.ID,
+			Username:  session.Username,
+			ExpiresAt: session.ExpiresAt,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sessions, nil
+}
+
 // executes a function within a database transaction
-func (s *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
+func (s *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) (err error) {
+	logger := s.loggerFor(ctx)
+
+	ctx, span := tracer.Start(ctx, "db.execTx", trace.WithAttributes(semconv.DBSystemPostgreSQL))
+	defer func() {
+		recordError(span, err)
+		span.End()
+	}()
+
 	tx, err := s.db.BeginTx(ctx, nil)
 
 	if err != nil {
+		logger.Error().Err(err).Msg("cannot begin db transaction")
 		return err
 	}
 
-	q := New(tx)
+	q := New(tracedDBTX{db: tx, txSpan: span})
 	err = fn(q)
 
 	if err != nil {
 		// rollback
 		if rbErr := tx.Rollback(); rbErr != nil {
+			logger.Error().Err(err).AnErr("rollback_error", rbErr).Msg("cannot roll back db transaction")
 			return fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
 		}
 
+		logger.Warn().Err(err).Msg("db transaction rolled back")
 		return err
 	}
 
 	// all operations are successful
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		logger.Error().Err(err).Msg("cannot commit db transaction")
+		return err
+	}
+
+	return nil
+}
+
+// loggerFor returns the logger of the request in the context, which carries its request id,
+// or the logger of the store, e.g. for the background workers
+func (s *SQLStore) loggerFor(ctx context.Context) *zerolog.Logger {
+	if logger := zerolog.Ctx(ctx); logger.GetLevel() != zerolog.Disabled {
+		return logger
+	}
+
+	return &s.logger
 }
 
 func addMoney(
@@ -141,3 +943,41 @@ func addMoney(
 
 	return
 }
+
+// only active accounts can be debited
+func canDebit(account Account) error {
+	switch account.Status {
+	case AccountStatusFrozen:
+		return ErrAccountFrozen
+	case AccountStatusClosed:
+		return ErrAccountClosed
+	}
+
+	return nil
+}
+
+// frozen accounts can still be credited, e.g. incoming salary, closed accounts can't
+func canCredit(account Account) error {
+	if account.Status == AccountStatusClosed {
+		return ErrAccountClosed
+	}
+
+	return nil
+}
+
+// the database rejects the balance update with a check violation when the account would be overdrawn
+func isInsufficientFunds(err error) bool {
+	if pqErr, ok := err.(*pq.Error); ok {
+		return pqErr.Code.Name() == "check_violation" && pqErr.Constraint == balanceNonNegativeConstraint
+	}
+
+	return false
+}
+
+func isUniqueViolation(err error, constraint string) bool {
+	if pqErr, ok := err.(*pq.Error); ok {
+		return pqErr.Code.Name() == "unique_violation" && pqErr.Constraint == constraint
+	}
+
+	return false
+}
@@ -2,20 +2,26 @@ package db
 
 import (
 	"context"
+	"encoding/json"
+	"github.com/aybarsacar/simplebank/fx"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/require"
 	"testing"
 )
 
 func TestStore_TransferTx(t *testing.T) {
-	store := NewStore(testDB)
-
-	sender := createRandomAccount(t)
-	receiver := createRandomAccount(t)
+	store := NewStore(testDB, zerolog.Nop())
 
 	// run n concurrent transfer transactions
 	n := 10
 	amount := int64(10)
 
+	// the sender needs enough money to cover every transfer
+	sender := createRandomAccountWithBalance(t, int64(n)*amount+util.RandomMoney())
+	receiver := createRandomAccountWithBalance(t, int64(n)*amount+util.RandomMoney())
+
 	// create a channel of errors to publish to errors to the main thread from the go routine
 	errs := make(chan error)
 	results := make(chan TransferTxResult)
@@ -54,6 +60,7 @@ func TestStore_TransferTx(t *testing.T) {
 		require.Equal(t, sender.ID, transfer.FromAccountID)
 		require.Equal(t, receiver.ID, transfer.ToAccountID)
 		require.Equal(t, amount, transfer.Amount)
+		require.Equal(t, amount, transfer.ToAmount)
 		require.NotZero(t, transfer.ID)
 		require.NotZero(t, transfer.CreatedAt)
 
@@ -116,15 +123,16 @@ func TestStore_TransferTx(t *testing.T) {
 }
 
 func TestStore_TransferTx_Deadlock(t *testing.T) {
-	store := NewStore(testDB)
-
-	sender := createRandomAccount(t)
-	receiver := createRandomAccount(t)
+	store := NewStore(testDB, zerolog.Nop())
 
 	// run n concurrent transfer transactions
 	n := 10
 	amount := int64(10)
 
+	// the sender needs enough money to cover every transfer
+	sender := createRandomAccountWithBalance(t, int64(n)*amount+util.RandomMoney())
+	receiver := createRandomAccountWithBalance(t, int64(n)*amount+util.RandomMoney())
+
 	// create a channel of errors to publish to errors to the main thread from the go routine
 	errs := make(chan error)
 
@@ -172,3 +180,344 @@ func TestStore_TransferTx_Deadlock(t *testing.T) {
 	require.Equal(t, sender.Balance, updatedAccount1.Balance)
 	require.Equal(t, receiver.Balance, updatedAccount2.Balance)
 }
+
+func TestStore_TransferTx_InsufficientFunds(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop())
+
+	sender := createRandomAccountWithBalance(t, 10)
+	receiver := createRandomAccount(t)
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: sender.ID,
+		ToAccountID:   receiver.ID,
+		Amount:        sender.Balance + 1,
+	})
+
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	// the whole transaction is rolled back, so the balances are untouched
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), sender.ID)
+	require.NoError(t, err)
+
+	updatedAccount2, err := testQueries.GetAccount(context.Background(), receiver.ID)
+	require.NoError(t, err)
+
+	require.Equal(t, sender.Balance, updatedAccount1.Balance)
+	require.Equal(t, receiver.Balance, updatedAccount2.Balance)
+}
+
+func TestStore_IdempotentTransferTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop())
+
+	amount := int64(10)
+
+	sender := createRandomAccountWithBalance(t, amount+util.RandomMoney())
+	receiver := createRandomAccount(t)
+
+	args := IdempotentTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: sender.ID,
+			ToAccountID:   receiver.ID,
+			Amount:        amount,
+		},
+		Username:       sender.Owner,
+		IdempotencyKey: util.RandomString(16),
+		RequestHash:    util.RandomString(32),
+	}
+
+	result1, err := store.IdempotentTransferTx(context.Background(), args)
+	require.NoError(t, err)
+	require.NotZero(t, result1.Transfer.ID)
+
+	// replaying the same request returns the original transfer
+	result2, err := store.IdempotentTransferTx(context.Background(), args)
+	require.NoError(t, err)
+	require.Equal(t, result1.Transfer.ID, result2.Transfer.ID)
+	require.Equal(t, result1.FromAccount.Balance, result2.FromAccount.Balance)
+
+	// reusing the key for a different request is rejected
+	args.RequestHash = util.RandomString(32)
+	_, err = store.IdempotentTransferTx(context.Background(), args)
+	require.ErrorIs(t, err, ErrIdempotencyKeyConflict)
+
+	// the money is only moved once
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), sender.ID)
+	require.NoError(t, err)
+
+	updatedAccount2, err := testQueries.GetAccount(context.Background(), receiver.ID)
+	require.NoError(t, err)
+
+	require.Equal(t, sender.Balance-amount, updatedAccount1.Balance)
+	require.Equal(t, receiver.Balance+amount, updatedAccount2.Balance)
+}
+
+func TestStore_DepositTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop())
+
+	account := createRandomAccount(t)
+
+	args := ExternalTxParams{
+		AccountID:         account.ID,
+		Amount:            100,
+		Source:            "card_processor",
+		ExternalReference: util.RandomString(16),
+	}
+
+	result, err := store.DepositTx(context.Background(), args)
+	require.NoError(t, err)
+
+	require.Equal(t, account.Balance+args.Amount, result.Account.Balance)
+	require.Equal(t, args.Amount, result.Entry.Amount)
+	require.Equal(t, result.Account.Balance, result.Entry.BalanceAfter)
+	require.Equal(t, ExternalTransactionDeposit, result.ExternalTransaction.Kind)
+	require.Equal(t, result.Entry.ID, result.ExternalTransaction.EntryID)
+	require.Equal(t, args.Source, result.ExternalTransaction.Source)
+	require.Equal(t, args.ExternalReference, result.ExternalTransaction.ExternalReference)
+
+	// the source can't post the same reference twice
+	_, err = store.DepositTx(context.Background(), args)
+	require.ErrorIs(t, err, ErrDuplicateExternalReference)
+
+	updatedAccount, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, result.Account.Balance, updatedAccount.Balance)
+}
+
+func TestStore_WithdrawTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop())
+
+	account := createRandomAccountWithBalance(t, 100)
+
+	args := ExternalTxParams{
+		AccountID:         account.ID,
+		Amount:            60,
+		Source:            "atm_network",
+		ExternalReference: util.RandomString(16),
+	}
+
+	result, err := store.WithdrawTx(context.Background(), args)
+	require.NoError(t, err)
+
+	require.Equal(t, int64(40), result.Account.Balance)
+	require.Equal(t, -args.Amount, result.Entry.Amount)
+	require.Equal(t, int64(40), result.Entry.BalanceAfter)
+	require.Equal(t, ExternalTransactionWithdrawal, result.ExternalTransaction.Kind)
+	require.Equal(t, args.Amount, result.ExternalTransaction.Amount)
+
+	// there is not enough money left for a second withdrawal
+	args.ExternalReference = util.RandomString(16)
+
+	_, err = store.WithdrawTx(context.Background(), args)
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	updatedAccount, err := testQueries.GetAccount(context.Background(), account.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(40), updatedAccount.Balance)
+}
+
+func TestStore_CrossCurrencyTransferTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop())
+
+	sender := createRandomAccountWithBalance(t, 1000)
+	receiver := createRandomAccount(t)
+
+	rate, err := fx.NewRate(sender.Currency, receiver.Currency, "0.925")
+	require.NoError(t, err)
+
+	result, err := store.CrossCurrencyTransferTx(context.Background(), CrossCurrencyTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: sender.ID,
+			ToAccountID:   receiver.ID,
+			Amount:        333,
+		},
+		Rate: rate,
+	})
+	require.NoError(t, err)
+
+	// 333 * 0.925 = 308.025, rounded down
+	require.Equal(t, int64(333), result.Transfer.Amount)
+	require.Equal(t, int64(308), result.Transfer.ToAmount)
+	require.Equal(t, rate.String(), result.Transfer.ExchangeRate)
+
+	require.Equal(t, int64(-333), result.FromEntry.Amount)
+	require.Equal(t, int64(308), result.ToEntry.Amount)
+
+	require.Equal(t, sender.Balance-333, result.FromAccount.Balance)
+	require.Equal(t, receiver.Balance+308, result.ToAccount.Balance)
+
+	// an amount that converts to nothing is rejected
+	_, err = store.CrossCurrencyTransferTx(context.Background(), CrossCurrencyTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: sender.ID,
+			ToAccountID:   receiver.ID,
+			Amount:        1,
+		},
+		Rate: rate,
+	})
+	require.ErrorIs(t, err, ErrAmountTooSmall)
+}
+
+func TestStore_ReverseTransferTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop())
+
+	sender := createRandomAccountWithBalance(t, 1000)
+	receiver := createRandomAccount(t)
+
+	rate, err := fx.NewRate(sender.Currency, receiver.Currency, "0.8")
+	require.NoError(t, err)
+
+	original, err := store.CrossCurrencyTransferTx(context.Background(), CrossCurrencyTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: sender.ID,
+			ToAccountID:   receiver.ID,
+			Amount:        500,
+		},
+		Rate: rate,
+	})
+	require.NoError(t, err)
+
+	result, err := store.ReverseTransferTx(context.Background(), original.Transfer.ID)
+	require.NoError(t, err)
+
+	// the exact amounts of the original transfer are moved back
+	require.Equal(t, receiver.ID, result.Transfer.FromAccountID)
+	require.Equal(t, sender.ID, result.Transfer.ToAccountID)
+	require.Equal(t, int64(400), result.Transfer.Amount)
+	require.Equal(t, int64(500), result.Transfer.ToAmount)
+	require.Equal(t, "1.2500000000", result.Transfer.ExchangeRate)
+	require.Equal(t, original.Transfer.ID, result.Transfer.ReversesTransferID.Int64)
+
+	require.Equal(t, sender.Balance, result.ToAccount.Balance)
+	require.Equal(t, receiver.Balance, result.FromAccount.Balance)
+
+	_, err = store.ReverseTransferTx(context.Background(), original.Transfer.ID)
+	require.ErrorIs(t, err, ErrTransferAlreadyReversed)
+
+	_, err = store.ReverseTransferTx(context.Background(), result.Transfer.ID)
+	require.ErrorIs(t, err, ErrTransferNotReversible)
+}
+
+func TestStore_UpdateAccountStatusTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop())
+
+	account := createRandomAccountWithBalance(t, 100)
+	other := createRandomAccountWithBalance(t, 100)
+
+	frozen, err := store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountStatusFrozen,
+		Reason:    "fraud report",
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusFrozen, frozen.Status)
+	require.Equal(t, "fraud report", frozen.StatusReason)
+
+	// a frozen account can't be debited but it can still be credited
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account.ID,
+		ToAccountID:   other.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: other.ID,
+		ToAccountID:   account.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	// the account still holds money
+	_, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountStatusClosed,
+		Reason:    "customer request",
+	})
+	require.ErrorIs(t, err, ErrAccountBalanceNotZero)
+
+	_, err = store.WithdrawTx(context.Background(), ExternalTxParams{
+		AccountID:         account.ID,
+		Amount:            110,
+		Source:            "atm",
+		ExternalReference: util.RandomString(12),
+	})
+	require.ErrorIs(t, err, ErrAccountFrozen)
+
+	_, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountStatusActive,
+		Reason:    "cleared",
+	})
+	require.NoError(t, err)
+
+	_, err = store.WithdrawTx(context.Background(), ExternalTxParams{
+		AccountID:         account.ID,
+		Amount:            110,
+		Source:            "atm",
+		ExternalReference: util.RandomString(12),
+	})
+	require.NoError(t, err)
+
+	closed, err := store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountStatusClosed,
+		Reason:    "customer request",
+	})
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusClosed, closed.Status)
+
+	// a closed account is kept but it can't be credited or reopened
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: other.ID,
+		ToAccountID:   account.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, ErrAccountClosed)
+
+	_, err = store.UpdateAccountStatusTx(context.Background(), UpdateAccountStatusTxParams{
+		AccountID: account.ID,
+		Status:    AccountStatusActive,
+		Reason:    "reopen",
+	})
+	require.ErrorIs(t, err, ErrAccountClosed)
+
+	// but the owner can open a new account in the same currency, only one of them can be open at a time
+	args := CreateAccountParams{Owner: account.Owner, Currency: account.Currency}
+
+	newAccount, err := testQueries.CreateAccount(context.Background(), args)
+	require.NoError(t, err)
+	require.NotEqual(t, account.ID, newAccount.ID)
+
+	_, err = testQueries.CreateAccount(context.Background(), args)
+	require.Error(t, err)
+	require.Equal(t, "unique_violation", err.(*pq.Error).Code.Name())
+}
+
+func TestStore_TransferTx_OutboxEvent(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop())
+
+	account1 := createRandomAccountWithBalance(t, 100)
+	account2 := createRandomAccount(t)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	var payload json.RawMessage
+	err = testDB.QueryRowContext(
+		context.Background(),
+		"SELECT payload FROM outbox WHERE event_type = $1 AND (payload->'transfer'->>'id')::bigint = $2",
+		EventTransferCompleted,
+		result.Transfer.ID,
+	).Scan(&payload)
+	require.NoError(t, err)
+
+	var event TransferCompletedEvent
+	require.NoError(t, json.Unmarshal(payload, &event))
+	require.Equal(t, result.Transfer.ID, event.Transfer.ID)
+	require.Equal(t, result.FromEntry.ID, event.FromEntry.ID)
+	require.Equal(t, result.ToEntry.ID, event.ToEntry.ID)
+}
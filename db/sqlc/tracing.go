@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+)
+
+// the spans are no-ops until a tracer provider is registered, e.g. when tracing is not enabled
+var tracer = otel.Tracer("github.com/aybarsacar/simplebank/db")
+
+// prefix of the comment sqlc puts before every query, e.g. "-- name: GetAccount :one"
+const queryNamePrefix = "-- name: "
+
+// tracedDBTX creates a span for every statement, named after the sqlc query it runs
+// it wraps both the connection pool and the transactions, so the statements of execTx are traced too
+type tracedDBTX struct {
+	db DBTX
+	// txSpan is the span of the transaction the statements run in, nil for the connection pool
+	// the functions of execTx use the context of their caller, so their statements are put under it here
+	txSpan trace.Span
+}
+
+func (t tracedDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := t.startQuerySpan(ctx, query)
+	defer span.End()
+
+	result, err := t.db.ExecContext(ctx, query, args...)
+	recordError(span, err)
+
+	return result, err
+}
+
+func (t tracedDBTX) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := t.startQuerySpan(ctx, query)
+	defer span.End()
+
+	stmt, err := t.db.PrepareContext(ctx, query)
+	recordError(span, err)
+
+	return stmt, err
+}
+
+func (t tracedDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := t.startQuerySpan(ctx, query)
+	defer span.End()
+
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	recordError(span, err)
+
+	return rows, err
+}
+
+func (t tracedDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := t.startQuerySpan(ctx, query)
+	defer span.End()
+
+	// the query has run when the row is returned, its error is only reported by Scan
+	row := t.db.QueryRowContext(ctx, query, args...)
+	if err := row.Err(); err != sql.ErrNoRows {
+		recordError(span, err)
+	}
+
+	return row
+}
+
+func (t tracedDBTX) startQuerySpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	if t.txSpan != nil {
+		ctx = trace.ContextWithSpan(ctx, t.txSpan)
+	}
+
+	name := queryName(query)
+
+	return tracer.Start(ctx, "db."+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationKey.String(name),
+			// the statements are parameterized, so they don't hold the values of the requests
+			semconv.DBStatementKey.String(query),
+		),
+	)
+}
+
+// queryName returns the name sqlc gave the query, or "query" for the statements written by hand
+func queryName(query string) string {
+	if !strings.HasPrefix(query, queryNamePrefix) {
+		return "query"
+	}
+
+	name, _, _ := strings.Cut(strings.TrimPrefix(query, queryNamePrefix), " ")
+
+	return name
+}
+
+func recordError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
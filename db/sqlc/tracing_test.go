@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"testing"
+)
+
+// fakeDBTX runs no statements, it returns the error of the test
+type fakeDBTX struct {
+	DBTX
+	err error
+}
+
+func (db fakeDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return nil, db.err
+}
+
+func TestQueryName(t *testing.T) {
+	require.Equal(t, "DeleteAccount", queryName(deleteAccount))
+	require.Equal(t, "GetAccount", queryName(getAccount))
+	require.Equal(t, "query", queryName("SELECT 1"))
+}
+
+func TestTracedDBTX(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	txCtx, txSpan := provider.Tracer("test").Start(context.Background(), "tx")
+
+	// the statements of a transaction run with the context of the caller, not the one of its span
+	dbtx := tracedDBTX{db: fakeDBTX{err: errors.New("connection reset")}, txSpan: txSpan}
+
+	defaultTracer := tracer
+	tracer = provider.Tracer("test")
+	t.Cleanup(func() { tracer = defaultTracer })
+
+	_, err := dbtx.ExecContext(context.Background(), deleteAccount, 1)
+	require.Error(t, err)
+
+	txSpan.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	querySpan := spans[0]
+	require.Equal(t, "db.DeleteAccount", querySpan.Name())
+	require.Equal(t, trace.SpanContextFromContext(txCtx).SpanID(), querySpan.Parent().SpanID())
+	require.Equal(t, codes.Error, querySpan.Status().Code)
+}
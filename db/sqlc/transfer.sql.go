@@ -7,24 +7,39 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 const createTransfer = `-- name: CreateTransfer :one
 INSERT INTO transfers (from_account_id,
                        to_account_id,
-                       amount)
-VALUES ($1, $2, $3)
-RETURNING id, from_account_id, to_account_id, amount, created_at
+                       amount,
+                       to_amount,
+                       exchange_rate,
+                       reverses_transfer_id)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, reverses_transfer_id
 `
 
 type CreateTransferParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID      int64         `json:"from_account_id"`
+	ToAccountID        int64         `json:"to_account_id"`
+	Amount             int64         `json:"amount"`
+	ToAmount           int64         `json:"to_amount"`
+	ExchangeRate       string        `json:"exchange_rate"`
+	ReversesTransferID sql.NullInt64 `json:"reverses_transfer_id"`
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.ToAmount,
+		arg.ExchangeRate,
+		arg.ReversesTransferID,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -32,12 +47,15 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.ReversesTransferID,
 	)
 	return i, err
 }
 
 const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, reverses_transfer_id
 FROM transfers
 WHERE id = $1
 LIMIT 1
@@ -52,12 +70,210 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.ExchangeRate,
+		&i.ReversesTransferID,
 	)
 	return i, err
 }
 
+const listAccountTransfers = `-- name: ListAccountTransfers :many
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, reverses_transfer_id
+FROM transfers
+WHERE (from_account_id = $1 OR to_account_id = $1)
+  AND ($2::bigint IS NULL
+    OR from_account_id = $2
+    OR to_account_id = $2)
+  AND ($3::bigint IS NULL
+    OR CASE WHEN from_account_id = $1 THEN amount ELSE to_amount END >= $3)
+  AND ($4::bigint IS NULL
+    OR CASE WHEN from_account_id = $1 THEN amount ELSE to_amount END <= $4)
+ORDER BY id
+LIMIT $5 OFFSET $6
+`
+
+type ListAccountTransfersParams struct {
+	AccountID      int64         `json:"account_id"`
+	CounterpartyID sql.NullInt64 `json:"counterparty_id"`
+	MinAmount      sql.NullInt64 `json:"min_amount"`
+	MaxAmount      sql.NullInt64 `json:"max_amount"`
+	PageLimit      int32         `json:"page_limit"`
+	PageOffset     int32         `json:"page_offset"`
+}
+
+func (q *Queries) ListAccountTransfers(ctx context.Context, arg ListAccountTransfersParams) ([]Transfer, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountTransfers,
+		arg.AccountID,
+		arg.CounterpartyID,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Transfer
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.ReversesTransferID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAccountTransfersAfter = `-- name: ListAccountTransfersAfter :many
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, reverses_transfer_id
+FROM transfers
+WHERE (from_account_id = $1 OR to_account_id = $1)
+  AND ($2::bigint IS NULL
+    OR from_account_id = $2
+    OR to_account_id = $2)
+  AND ($3::bigint IS NULL
+    OR CASE WHEN from_account_id = $1 THEN amount ELSE to_amount END >= $3)
+  AND ($4::bigint IS NULL
+    OR CASE WHEN from_account_id = $1 THEN amount ELSE to_amount END <= $4)
+  AND (created_at, id) > ($5::timestamptz, $6::bigint)
+ORDER BY created_at, id
+LIMIT $7
+`
+
+type ListAccountTransfersAfterParams struct {
+	AccountID       int64         `json:"account_id"`
+	CounterpartyID  sql.NullInt64 `json:"counterparty_id"`
+	MinAmount       sql.NullInt64 `json:"min_amount"`
+	MaxAmount       sql.NullInt64 `json:"max_amount"`
+	CursorCreatedAt time.Time     `json:"cursor_created_at"`
+	CursorID        int64         `json:"cursor_id"`
+	PageLimit       int32         `json:"page_limit"`
+}
+
+func (q *Queries) ListAccountTransfersAfter(ctx context.Context, arg ListAccountTransfersAfterParams) ([]Transfer, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountTransfersAfter,
+		arg.AccountID,
+		arg.CounterpartyID,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Transfer
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.ReversesTransferID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAccountTransfersBefore = `-- name: ListAccountTransfersBefore :many
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, reverses_transfer_id
+FROM transfers
+WHERE (from_account_id = $1 OR to_account_id = $1)
+  AND ($2::bigint IS NULL
+    OR from_account_id = $2
+    OR to_account_id = $2)
+  AND ($3::bigint IS NULL
+    OR CASE WHEN from_account_id = $1 THEN amount ELSE to_amount END >= $3)
+  AND ($4::bigint IS NULL
+    OR CASE WHEN from_account_id = $1 THEN amount ELSE to_amount END <= $4)
+  AND (created_at, id) < ($5::timestamptz, $6::bigint)
+ORDER BY created_at DESC, id DESC
+LIMIT $7
+`
+
+type ListAccountTransfersBeforeParams struct {
+	AccountID       int64         `json:"account_id"`
+	CounterpartyID  sql.NullInt64 `json:"counterparty_id"`
+	MinAmount       sql.NullInt64 `json:"min_amount"`
+	MaxAmount       sql.NullInt64 `json:"max_amount"`
+	CursorCreatedAt time.Time     `json:"cursor_created_at"`
+	CursorID        int64         `json:"cursor_id"`
+	PageLimit       int32         `json:"page_limit"`
+}
+
+func (q *Queries) ListAccountTransfersBefore(ctx context.Context, arg ListAccountTransfersBeforeParams) ([]Transfer, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountTransfersBefore,
+		arg.AccountID,
+		arg.CounterpartyID,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Transfer
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.ReversesTransferID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, exchange_rate, reverses_transfer_id
 FROM transfers
 WHERE from_account_id = $1
    OR to_account_id = $2
@@ -92,6 +308,9 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ToAmount,
+			&i.ExchangeRate,
+			&i.ReversesTransferID,
 		); err != nil {
 			return nil, err
 		}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"github.com/aybarsacar/simplebank/fx"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestQueries_ListAccountTransfers(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop())
+
+	account1 := createRandomAccountWithBalance(t, 100)
+	account2 := createRandomAccountWithBalance(t, 100)
+	account3 := createRandomAccountWithBalance(t, 100)
+
+	for _, args := range []TransferTxParams{
+		{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: 10},
+		{FromAccountID: account2.ID, ToAccountID: account1.ID, Amount: 50},
+		{FromAccountID: account1.ID, ToAccountID: account3.ID, Amount: 10},
+	} {
+		_, err := store.TransferTx(context.Background(), args)
+		require.NoError(t, err)
+	}
+
+	transfers, err := testQueries.ListAccountTransfers(context.Background(), ListAccountTransfersParams{
+		AccountID: account1.ID,
+		PageLimit: 5,
+	})
+	require.NoError(t, err)
+	require.Len(t, transfers, 3)
+
+	// only the transfers with account2 of at least 20
+	transfers, err = testQueries.ListAccountTransfers(context.Background(), ListAccountTransfersParams{
+		AccountID:      account1.ID,
+		CounterpartyID: sql.NullInt64{Int64: account2.ID, Valid: true},
+		MinAmount:      sql.NullInt64{Int64: 20, Valid: true},
+		PageLimit:      5,
+	})
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.Equal(t, account2.ID, transfers[0].FromAccountID)
+	require.Equal(t, int64(50), transfers[0].Amount)
+}
+
+func TestQueries_ListAccountTransfers_ReceivedAmount(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop())
+
+	sender := createRandomAccountWithBalance(t, 1000)
+	receiver := createRandomAccount(t)
+
+	rate, err := fx.NewRate(sender.Currency, receiver.Currency, "2")
+	require.NoError(t, err)
+
+	_, err = store.CrossCurrencyTransferTx(context.Background(), CrossCurrencyTransferTxParams{
+		TransferTxParams: TransferTxParams{
+			FromAccountID: sender.ID,
+			ToAccountID:   receiver.ID,
+			Amount:        100,
+		},
+		Rate: rate,
+	})
+	require.NoError(t, err)
+
+	// the receiver got 200 in its own currency, the 100 of the sender are in another one
+	params := ListAccountTransfersParams{
+		AccountID: receiver.ID,
+		MinAmount: sql.NullInt64{Int64: 150, Valid: true},
+		PageLimit: 5,
+	}
+
+	transfers, err := testQueries.ListAccountTransfers(context.Background(), params)
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.Equal(t, int64(200), transfers[0].ToAmount)
+
+	// the sender sent 100 in its own currency
+	params.AccountID = sender.ID
+	transfers, err = testQueries.ListAccountTransfers(context.Background(), params)
+	require.NoError(t, err)
+	require.Empty(t, transfers)
+}
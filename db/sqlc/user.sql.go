@@ -7,12 +7,13 @@ package db
 
 import (
 	"context"
+	"time"
 )
 
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (username, hashed_password, full_name, email)
 VALUES ($1, $2, $3, $4)
-RETURNING username, hashed_password, full_name, email, password_changed_at, created_at
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, locked_until, is_email_verified, role_changed_at, sessions_revoked_at
 `
 
 type CreateUserParams struct {
@@ -37,12 +38,18 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.RoleChangedAt,
+		&i.SessionsRevokedAt,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT username, hashed_password, full_name, email, password_changed_at, created_at
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, locked_until, is_email_verified, role_changed_at, sessions_revoked_at
 FROM users
 WHERE username = $1
 LIMIT 1
@@ -58,6 +65,277 @@ func (q *Queries) GetUser(ctx context.Context, username string) (User, error) {
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.RoleChangedAt,
+		&i.SessionsRevokedAt,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, locked_until, is_email_verified, role_changed_at, sessions_revoked_at
+FROM users
+WHERE email = $1
+LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.RoleChangedAt,
+		&i.SessionsRevokedAt,
+	)
+	return i, err
+}
+
+const getUserCredentialsChangedAt = `-- name: GetUserCredentialsChangedAt :one
+SELECT GREATEST(password_changed_at, role_changed_at, sessions_revoked_at)::timestamptz AS changed_at
+FROM users
+WHERE username = $1
+LIMIT 1
+`
+
+// the tokens issued before the password or the role of the user last changed, or before their sessions were revoked, are revoked
+func (q *Queries) GetUserCredentialsChangedAt(ctx context.Context, username string) (time.Time, error) {
+	row := q.db.QueryRowContext(ctx, getUserCredentialsChangedAt, username)
+	var changed_at time.Time
+	err := row.Scan(&changed_at)
+	return changed_at, err
+}
+
+const isUserEmailVerified = `-- name: IsUserEmailVerified :one
+SELECT is_email_verified
+FROM users
+WHERE username = $1
+LIMIT 1
+`
+
+func (q *Queries) IsUserEmailVerified(ctx context.Context, username string) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isUserEmailVerified, username)
+	var is_email_verified bool
+	err := row.Scan(&is_email_verified)
+	return is_email_verified, err
+}
+
+const recordFailedLogin = `-- name: RecordFailedLogin :one
+UPDATE users
+SET failed_login_attempts = failed_login_attempts + 1,
+    locked_until          = CASE
+                                WHEN failed_login_attempts + 1 >= $1::int
+                                    THEN now() + make_interval(secs => LEAST(
+                                        $2::float8 *
+                                        power(2, LEAST(failed_login_attempts + 1 - $1::int, 32)),
+                                        $3::float8
+                                    ))
+                                ELSE locked_until
+        END
+WHERE username = $4
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, locked_until, is_email_verified, role_changed_at, sessions_revoked_at
+`
+
+type RecordFailedLoginParams struct {
+	MaxAttempts       int32   `json:"max_attempts"`
+	LockoutSeconds    float64 `json:"lockout_seconds"`
+	MaxLockoutSeconds float64 `json:"max_lockout_seconds"`
+	Username          string  `json:"username"`
+}
+
+// the user is locked out from the max_attempts-th failed login on, and every failure after a lockout doubles it,
+// up to max_lockout_seconds
+func (q *Queries) RecordFailedLogin(ctx context.Context, arg RecordFailedLoginParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, recordFailedLogin,
+		arg.MaxAttempts,
+		arg.LockoutSeconds,
+		arg.MaxLockoutSeconds,
+		arg.Username,
+	)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.RoleChangedAt,
+		&i.SessionsRevokedAt,
+	)
+	return i, err
+}
+
+const unlockUser = `-- name: UnlockUser :one
+UPDATE users
+SET failed_login_attempts = 0,
+    locked_until          = NULL
+WHERE username = $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, locked_until, is_email_verified, role_changed_at, sessions_revoked_at
+`
+
+func (q *Queries) UnlockUser(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRowContext(ctx, unlockUser, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.RoleChangedAt,
+		&i.SessionsRevokedAt,
+	)
+	return i, err
+}
+
+const updateUserRole = `-- name: UpdateUserRole :one
+UPDATE users
+SET role            = $1,
+    role_changed_at = now()
+WHERE username = $2
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, locked_until, is_email_verified, role_changed_at, sessions_revoked_at
+`
+
+type UpdateUserRoleParams struct {
+	Role     string `json:"role"`
+	Username string `json:"username"`
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserRole, arg.Role, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.RoleChangedAt,
+		&i.SessionsRevokedAt,
+	)
+	return i, err
+}
+
+const updateUserSessionsRevokedAt = `-- name: UpdateUserSessionsRevokedAt :one
+UPDATE users
+SET sessions_revoked_at = now()
+WHERE username = $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, locked_until, is_email_verified, role_changed_at, sessions_revoked_at
+`
+
+// the access tokens issued before are rejected, like after a password or a role change
+func (q *Queries) UpdateUserSessionsRevokedAt(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserSessionsRevokedAt, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.RoleChangedAt,
+		&i.SessionsRevokedAt,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :one
+UPDATE users
+SET hashed_password       = $1,
+    password_changed_at   = now(),
+    failed_login_attempts = 0,
+    locked_until          = NULL
+WHERE username = $2
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, locked_until, is_email_verified, role_changed_at, sessions_revoked_at
+`
+
+type UpdateUserPasswordParams struct {
+	HashedPassword string `json:"hashed_password"`
+	Username       string `json:"username"`
+}
+
+// the user proved they own the account, so a lockout after failed logins ends too
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserPassword, arg.HashedPassword, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.RoleChangedAt,
+		&i.SessionsRevokedAt,
+	)
+	return i, err
+}
+
+const verifyUserEmail = `-- name: VerifyUserEmail :one
+UPDATE users
+SET is_email_verified = true
+WHERE username = $1
+  AND email = $2
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, failed_login_attempts, locked_until, is_email_verified, role_changed_at, sessions_revoked_at
+`
+
+type VerifyUserEmailParams struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+// the email must still be the one the code was sent to
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, verifyUserEmail, arg.Username, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.FailedLoginAttempts,
+		&i.LockedUntil,
+		&i.IsEmailVerified,
+		&i.RoleChangedAt,
+		&i.SessionsRevokedAt,
 	)
 	return i, err
 }
@@ -2,7 +2,9 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"github.com/aybarsacar/simplebank/util"
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/require"
 	"testing"
 	"time"
@@ -33,6 +35,41 @@ func TestQueries_GetUser(t *testing.T) {
 	require.WithinDuration(t, user1.PasswordChangedAt, user2.PasswordChangedAt, time.Second)
 }
 
+func TestQueries_RecordFailedLogin(t *testing.T) {
+	user := createRandomUser(t)
+	require.Zero(t, user.FailedLoginAttempts)
+	require.False(t, user.LockedUntil.Valid)
+
+	args := RecordFailedLoginParams{
+		MaxAttempts:       3,
+		LockoutSeconds:    60,
+		MaxLockoutSeconds: 150,
+		Username:          user.Username,
+	}
+
+	// the user is locked out from the third failed login on, and every failure after that doubles the lockout
+	lockouts := []time.Duration{0, 0, time.Minute, 2 * time.Minute, 150 * time.Second}
+
+	for i, lockout := range lockouts {
+		updatedUser, err := testQueries.RecordFailedLogin(context.Background(), args)
+		require.NoError(t, err)
+		require.Equal(t, int32(i+1), updatedUser.FailedLoginAttempts)
+
+		if lockout == 0 {
+			require.False(t, updatedUser.LockedUntil.Valid)
+			continue
+		}
+
+		require.True(t, updatedUser.LockedUntil.Valid)
+		require.WithinDuration(t, time.Now().Add(lockout), updatedUser.LockedUntil.Time, 5*time.Second)
+	}
+
+	unlockedUser, err := testQueries.UnlockUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Zero(t, unlockedUser.FailedLoginAttempts)
+	require.False(t, unlockedUser.LockedUntil.Valid)
+}
+
 func createRandomUser(t *testing.T) User {
 
 	hashedPassword, err := util.HashPassword(util.RandomString(6))
@@ -57,7 +94,55 @@ func createRandomUser(t *testing.T) User {
 	require.Equal(t, args.Email, user.Email)
 
 	require.True(t, user.PasswordChangedAt.IsZero())
+	// every new user is a customer until an admin changes their role
+	require.Equal(t, util.CustomerRole, user.Role)
 	require.NotZero(t, user.CreatedAt)
 
 	return user
 }
+
+func TestQueries_UpdateUserRole(t *testing.T) {
+	user := createRandomUser(t)
+	require.True(t, user.RoleChangedAt.IsZero())
+
+	updatedUser, err := testQueries.UpdateUserRole(context.Background(), UpdateUserRoleParams{
+		Role:     util.TellerRole,
+		Username: user.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.TellerRole, updatedUser.Role)
+	require.WithinDuration(t, time.Now(), updatedUser.RoleChangedAt, 5*time.Second)
+
+	// the tokens issued before the role change are revoked, even though the password never changed
+	changedAt, err := testQueries.GetUserCredentialsChangedAt(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.WithinDuration(t, updatedUser.RoleChangedAt, changedAt, time.Millisecond)
+}
+
+func TestStore_UpdateUserRoleTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop())
+
+	session := createRandomSession(t)
+
+	result, err := store.UpdateUserRoleTx(context.Background(), UpdateUserRoleParams{
+		Role:     util.AdminRole,
+		Username: session.Username,
+	})
+	require.NoError(t, err)
+	require.Equal(t, util.AdminRole, result.User.Role)
+	require.False(t, result.User.RoleChangedAt.IsZero())
+
+	// the sessions of the old role are revoked in the same transaction
+	require.Len(t, result.RevokedSessions, 1)
+	require.Equal(t, session.ID, result.RevokedSessions[0].ID)
+
+	revoked, err := testQueries.IsTokenRevoked(context.Background(), session.ID)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	_, err = store.UpdateUserRoleTx(context.Background(), UpdateUserRoleParams{
+		Role:     util.AdminRole,
+		Username: util.RandomOwner(),
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestStore_CreateUserTx(t *testing.T) {
+	user, verifyEmail := createRandomUserTx(t)
+
+	require.False(t, user.IsEmailVerified)
+
+	// the mail is due right away and has no code until it is sent
+	require.Equal(t, user.Username, verifyEmail.Username)
+	require.Equal(t, user.Email, verifyEmail.Email)
+	require.Empty(t, verifyEmail.SecretCodeHash)
+	require.False(t, verifyEmail.IsUsed)
+	require.Zero(t, verifyEmail.Attempts)
+	require.False(t, verifyEmail.SentAt.Valid)
+
+	verified, err := testQueries.IsUserEmailVerified(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.False(t, verified)
+}
+
+func TestStore_VerifyEmailTx(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop())
+
+	user, verifyEmail := createRandomUserTx(t)
+	secretCodeHash := markRandomVerifyEmailSent(t, verifyEmail, time.Hour)
+
+	args := VerifyEmailTxParams{
+		ID:             verifyEmail.ID,
+		SecretCodeHash: secretCodeHash,
+	}
+
+	result, err := store.VerifyEmailTx(context.Background(), args)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, result.User.Username)
+	require.True(t, result.User.IsEmailVerified)
+	require.True(t, result.VerifyEmail.IsUsed)
+
+	verified, err := testQueries.IsUserEmailVerified(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.True(t, verified)
+
+	// the code can only be used once
+	_, err = store.VerifyEmailTx(context.Background(), args)
+	require.ErrorIs(t, err, ErrInvalidVerifyEmail)
+}
+
+func TestStore_VerifyEmailTx_Invalid(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop())
+
+	_, unsentEmail := createRandomUserTx(t)
+
+	_, expiredEmail := createRandomUserTx(t)
+	expiredCodeHash := markRandomVerifyEmailSent(t, expiredEmail, -time.Minute)
+
+	_, sentEmail := createRandomUserTx(t)
+	markRandomVerifyEmailSent(t, sentEmail, time.Hour)
+
+	testCases := []struct {
+		name           string
+		id             int64
+		secretCodeHash string
+	}{
+		{
+			name:           "NotFound",
+			id:             -1,
+			secretCodeHash: util.HashSecret(util.RandomString(32)),
+		},
+		{
+			name:           "NotSent",
+			id:             unsentEmail.ID,
+			secretCodeHash: "",
+		},
+		{
+			name:           "Expired",
+			id:             expiredEmail.ID,
+			secretCodeHash: expiredCodeHash,
+		},
+		{
+			name:           "WrongCode",
+			id:             sentEmail.ID,
+			secretCodeHash: util.HashSecret(util.RandomString(32)),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+				ID:             testCase.id,
+				SecretCodeHash: testCase.secretCodeHash,
+			})
+			require.ErrorIs(t, err, ErrInvalidVerifyEmail)
+		})
+	}
+}
+
+func createRandomUserTx(t *testing.T) (User, VerifyEmail) {
+	store := NewStore(testDB, zerolog.Nop())
+
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	result, err := store.CreateUserTx(context.Background(), CreateUserParams{
+		Username:       util.RandomOwner(),
+		HashedPassword: hashedPassword,
+		FullName:       util.RandomOwner(),
+		Email:          util.RandomEmail(),
+	})
+	require.NoError(t, err)
+	require.NotZero(t, result.VerifyEmail.ID)
+
+	return result.User, result.VerifyEmail
+}
+
+// markRandomVerifyEmailSent records a new code as sent, like the sender does, and returns its hash
+func markRandomVerifyEmailSent(t *testing.T, verifyEmail VerifyEmail, duration time.Duration) string {
+	secretCode, err := util.NewSecret()
+	require.NoError(t, err)
+
+	secretCodeHash := util.HashSecret(secretCode)
+
+	err = testQueries.MarkVerifyEmailSent(context.Background(), MarkVerifyEmailSentParams{
+		SecretCodeHash: secretCodeHash,
+		ExpiredAt:      time.Now().Add(duration),
+		ID:             verifyEmail.ID,
+	})
+	require.NoError(t, err)
+
+	return secretCodeHash
+}
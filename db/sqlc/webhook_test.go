@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func createRandomWebhookSubscription(t *testing.T, owner string, events ...string) WebhookSubscription {
+	args := CreateWebhookSubscriptionParams{
+		Owner:  owner,
+		Url:    "https://example.com/webhooks",
+		Events: events,
+		Secret: "subscription-secret",
+	}
+
+	subscription, err := testQueries.CreateWebhookSubscription(context.Background(), args)
+	require.NoError(t, err)
+
+	require.NotZero(t, subscription.ID)
+	require.Equal(t, args.Owner, subscription.Owner)
+	require.Equal(t, args.Url, subscription.Url)
+	require.Equal(t, args.Events, subscription.Events)
+	require.Equal(t, args.Secret, subscription.Secret)
+	require.NotZero(t, subscription.CreatedAt)
+
+	return subscription
+}
+
+func TestQueries_ListWebhookSubscriptionsByEvent(t *testing.T) {
+	user := createRandomUser(t)
+
+	credited := createRandomWebhookSubscription(t, user.Username, "account.credited")
+	both := createRandomWebhookSubscription(t, user.Username, "account.credited", "account.debited")
+
+	subscriptions, err := testQueries.ListWebhookSubscriptionsByEvent(context.Background(), ListWebhookSubscriptionsByEventParams{
+		Owner:     user.Username,
+		EventType: "account.debited",
+	})
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 1)
+	require.Equal(t, both.ID, subscriptions[0].ID)
+
+	subscriptions, err = testQueries.ListWebhookSubscriptions(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 2)
+	require.Equal(t, credited.ID, subscriptions[0].ID)
+}
+
+func TestQueries_WebhookDeliveries(t *testing.T) {
+	store := NewStore(testDB, zerolog.Nop())
+
+	account1 := createRandomAccountWithBalance(t, 100)
+	account2 := createRandomAccount(t)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	var outboxID int64
+	err = testDB.QueryRowContext(
+		context.Background(),
+		"SELECT id FROM outbox WHERE event_type = $1 AND (payload->'transfer'->>'id')::bigint = $2",
+		EventTransferCompleted,
+		result.Transfer.ID,
+	).Scan(&outboxID)
+	require.NoError(t, err)
+
+	subscription := createRandomWebhookSubscription(t, account2.Owner, "account.credited")
+
+	args := CreateWebhookDeliveryParams{
+		SubscriptionID: subscription.ID,
+		OutboxID:       outboxID,
+		EntryID:        result.ToEntry.ID,
+		EventType:      "account.credited",
+		Payload:        json.RawMessage(`{"account_id": 1}`),
+	}
+
+	delivery, err := testQueries.CreateWebhookDelivery(context.Background(), args)
+	require.NoError(t, err)
+	require.Equal(t, "pending", delivery.Status)
+	require.Zero(t, delivery.Attempts)
+
+	// the outbox delivers an event again after a failure, the delivery is only created once
+	_, err = testQueries.CreateWebhookDelivery(context.Background(), args)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	err = testQueries.MarkWebhookDeliveryFailed(context.Background(), MarkWebhookDeliveryFailedParams{
+		Status:         "failed",
+		LastError:      "webhook responded with status 500",
+		ResponseStatus: sql.NullInt32{Int32: 500, Valid: true},
+		NextAttemptAt:  time.Now().Add(time.Hour),
+		ID:             delivery.ID,
+	})
+	require.NoError(t, err)
+
+	replayed, err := testQueries.ReplayWebhookDelivery(context.Background(), delivery.ID)
+	require.NoError(t, err)
+	require.Equal(t, "pending", replayed.Status)
+	require.Equal(t, int32(1), replayed.Attempts)
+	require.Equal(t, sql.NullInt32{Int32: 500, Valid: true}, replayed.ResponseStatus)
+	require.WithinDuration(t, time.Now(), replayed.NextAttemptAt, time.Minute)
+
+	deliveries, err := testQueries.ListWebhookDeliveries(context.Background(), ListWebhookDeliveriesParams{
+		SubscriptionID: subscription.ID,
+		PageLimit:      10,
+		PageOffset:     0,
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, delivery.ID, deliveries[0].ID)
+}
@@ -0,0 +1,102 @@
+package fx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aybarsacar/simplebank/util"
+	"math/big"
+)
+
+// number of decimal places a rate is rounded to, the rounded rate is the one applied and recorded
+const RatePrecision = 10
+
+var (
+	ErrUnsupportedCurrency = errors.New("exchange rate for the currency is not available")
+	ErrAmountOverflow      = errors.New("converted amount does not fit in 64 bits")
+)
+
+// RateProvider gives the exchange rate between two currencies
+type RateProvider interface {
+	Rate(ctx context.Context, from string, to string) (Rate, error)
+}
+
+// Rate converts an amount of the From currency to the To currency
+// the value is the price of one unit of From in To, e.g. 1 USD = 0.92 EUR,
+// while the amounts it converts are in minor units, e.g. cents
+type Rate struct {
+	From  string
+	To    string
+	value *big.Rat
+}
+
+// NewRate creates a rate from its decimal representation, e.g. "0.92"
+func NewRate(from string, to string, value string) (Rate, error) {
+	v, ok := new(big.Rat).SetString(value)
+	if !ok || v.Sign() <= 0 {
+		return Rate{}, fmt.Errorf("invalid exchange rate %q from %s to %s", value, from, to)
+	}
+
+	return newRate(from, to, v), nil
+}
+
+func newRate(from string, to string, value *big.Rat) Rate {
+	// round the rate first, so the rate that is recorded is exactly the one that was applied
+	rounded, _ := new(big.Rat).SetString(value.FloatString(RatePrecision))
+
+	return Rate{From: from, To: to, value: rounded}
+}
+
+// Convert returns the amount in the To currency, rounded down so the bank never pays more than it received
+func (rate Rate) Convert(amount int64) (int64, error) {
+	fromDecimals, ok := util.MinorUnits(rate.From)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, rate.From)
+	}
+
+	toDecimals, ok := util.MinorUnits(rate.To)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, rate.To)
+	}
+
+	converted := new(big.Rat).Mul(new(big.Rat).SetInt64(amount), rate.value)
+
+	// move between minor units with different decimal places, e.g. cents to yen
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(toDecimals-fromDecimals))), nil))
+	if toDecimals > fromDecimals {
+		converted.Mul(converted, scale)
+	} else {
+		converted.Quo(converted, scale)
+	}
+
+	// Quo truncates towards zero
+	result := new(big.Int).Quo(converted.Num(), converted.Denom())
+	if !result.IsInt64() {
+		return 0, ErrAmountOverflow
+	}
+
+	return result.Int64(), nil
+}
+
+// String returns the decimal representation of the rate, e.g. "0.9200000000"
+func (rate Rate) String() string {
+	return rate.value.FloatString(RatePrecision)
+}
+
+// Inverse returns the rate that converts the To currency back to the From currency
+func (rate Rate) Inverse() Rate {
+	return newRate(rate.To, rate.From, new(big.Rat).Inv(rate.value))
+}
+
+// IsZero tells if the rate was not set
+func (rate Rate) IsZero() bool {
+	return rate.value == nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
@@ -0,0 +1,118 @@
+package fx
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRate_Convert(t *testing.T) {
+	rate, err := NewRate("USD", "EUR", "0.92")
+	require.NoError(t, err)
+	require.Equal(t, "0.9200000000", rate.String())
+
+	amount, err := rate.Convert(1000)
+	require.NoError(t, err)
+	require.Equal(t, int64(920), amount)
+
+	// rounded down
+	amount, err = rate.Convert(1)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), amount)
+
+	rate, err = NewRate("EUR", "USD", "2")
+	require.NoError(t, err)
+
+	_, err = rate.Convert(math.MaxInt64)
+	require.ErrorIs(t, err, ErrAmountOverflow)
+}
+
+func TestRate_ConvertMinorUnits(t *testing.T) {
+	rate, err := NewRate("USD", "JPY", "150.5")
+	require.NoError(t, err)
+
+	// 12.34 USD = 1857.17 JPY, rounded down to 1857
+	amount, err := rate.Convert(1234)
+	require.NoError(t, err)
+	require.Equal(t, int64(1857), amount)
+
+	rate, err = NewRate("JPY", "USD", "0.0066")
+	require.NoError(t, err)
+
+	// 1500 JPY = 9.90 USD
+	amount, err = rate.Convert(1500)
+	require.NoError(t, err)
+	require.Equal(t, int64(990), amount)
+
+	rate, err = NewRate("USD", "XYZ", "1")
+	require.NoError(t, err)
+
+	_, err = rate.Convert(100)
+	require.ErrorIs(t, err, ErrUnsupportedCurrency)
+}
+
+func TestRate_Inverse(t *testing.T) {
+	rate, err := NewRate("USD", "EUR", "0.8")
+	require.NoError(t, err)
+
+	inverse := rate.Inverse()
+	require.Equal(t, "EUR", inverse.From)
+	require.Equal(t, "USD", inverse.To)
+	require.Equal(t, "1.2500000000", inverse.String())
+}
+
+func TestNewRate_Invalid(t *testing.T) {
+	for _, value := range []string{"", "abc", "0", "-1.5"} {
+		_, err := NewRate("USD", "EUR", value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestStaticRateProvider(t *testing.T) {
+	provider, err := NewStaticRateProvider("USD", map[string]string{"EUR": "0.8", "CAD": "1.25"})
+	require.NoError(t, err)
+
+	rate, err := provider.Rate(context.Background(), "USD", "EUR")
+	require.NoError(t, err)
+	require.Equal(t, "0.8000000000", rate.String())
+
+	// cross rate, 1 EUR = 1.25 / 0.8 CAD
+	rate, err = provider.Rate(context.Background(), "EUR", "CAD")
+	require.NoError(t, err)
+	require.Equal(t, "1.5625000000", rate.String())
+
+	// rounded to the precision
+	rate, err = provider.Rate(context.Background(), "CAD", "EUR")
+	require.NoError(t, err)
+	require.Equal(t, "0.6400000000", rate.String())
+
+	rate, err = provider.Rate(context.Background(), "EUR", "USD")
+	require.NoError(t, err)
+	require.Equal(t, "1.2500000000", rate.String())
+
+	_, err = provider.Rate(context.Background(), "USD", "JPY")
+	require.ErrorIs(t, err, ErrUnsupportedCurrency)
+
+	_, err = NewStaticRateProvider("USD", map[string]string{"EUR": "zero"})
+	require.Error(t, err)
+}
+
+func TestNewFileRateProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+
+	err := os.WriteFile(path, []byte(`{"base": "USD", "rates": {"EUR": "0.5"}}`), 0o600)
+	require.NoError(t, err)
+
+	provider, err := NewFileRateProvider(path)
+	require.NoError(t, err)
+
+	rate, err := provider.Rate(context.Background(), "EUR", "USD")
+	require.NoError(t, err)
+	require.Equal(t, "2.0000000000", rate.String())
+
+	_, err = NewFileRateProvider(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
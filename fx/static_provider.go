@@ -0,0 +1,77 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// StaticRateProvider serves rates that are known up front, so it works offline
+// rates are given against a single base currency and the cross rates are derived from them
+type StaticRateProvider struct {
+	base  string
+	rates map[string]*big.Rat
+}
+
+// NewStaticRateProvider creates a provider from the rates of each currency against the base currency
+// e.g. base "USD" and {"EUR": "0.92"} means 1 USD = 0.92 EUR
+func NewStaticRateProvider(base string, rates map[string]string) (*StaticRateProvider, error) {
+	provider := StaticRateProvider{
+		base:  base,
+		rates: map[string]*big.Rat{base: big.NewRat(1, 1)},
+	}
+
+	for currency, value := range rates {
+		rate, ok := new(big.Rat).SetString(value)
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid exchange rate %q for %s", value, currency)
+		}
+
+		provider.rates[currency] = rate
+	}
+
+	return &provider, nil
+}
+
+// ratesFile is the format of the file read by NewFileRateProvider
+type ratesFile struct {
+	Base  string            `json:"base"`
+	Rates map[string]string `json:"rates"`
+}
+
+// NewFileRateProvider loads the rates from a JSON file like {"base": "USD", "rates": {"EUR": "0.92"}}
+func NewFileRateProvider(path string) (*StaticRateProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read rates file: %w", err)
+	}
+
+	var file ratesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("cannot parse rates file: %w", err)
+	}
+
+	if file.Base == "" {
+		return nil, fmt.Errorf("rates file has no base currency")
+	}
+
+	return NewStaticRateProvider(file.Base, file.Rates)
+}
+
+// Rate returns the rate to convert from one currency to the other
+func (provider *StaticRateProvider) Rate(ctx context.Context, from string, to string) (Rate, error) {
+	fromRate, ok := provider.rates[from]
+	if !ok {
+		return Rate{}, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, from)
+	}
+
+	toRate, ok := provider.rates[to]
+	if !ok {
+		return Rate{}, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, to)
+	}
+
+	// 1 from = (toRate / fromRate) to
+	return newRate(from, to, new(big.Rat).Quo(toRate, fromRate)), nil
+}
@@ -0,0 +1,129 @@
+package gapi
+
+import (
+	"context"
+	"encoding/json"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/pb"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// actions of the audited methods, the same as the actions of the HTTP api
+var auditActions = map[string]string{
+	"/pb.SimpleBank/CreateUser":        "user.create",
+	"/pb.SimpleBank/LoginUser":         "user.login",
+	"/pb.SimpleBank/ResendVerifyEmail": "user.resend_verify_email",
+	"/pb.SimpleBank/CreateAccount":     "account.create",
+	"/pb.SimpleBank/CreateTransfer":    "transfer.create",
+}
+
+// actions of the events the methods record themselves
+const auditActionLockUser = "user.lock"
+
+const (
+	auditOutcomeSuccess = "success"
+	auditOutcomeFailure = "failure"
+)
+
+// auditInterceptor records an audit event for the state-changing methods after they have run
+// the status code of the events is the gRPC status code, an event that can't be stored is only logged
+func auditInterceptor(store db.Store) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		action, ok := auditActions[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		res, err := handler(ctx, req)
+
+		code := status.Code(err)
+
+		outcome := auditOutcomeSuccess
+		if code != codes.OK {
+			outcome = auditOutcomeFailure
+		}
+
+		recordAuditEvent(ctx, store, action, auditActor(ctx, req), auditResources(req, res), outcome, code)
+
+		return res, err
+	}
+}
+
+// recordAuditEvent stores an audit event of the call, e.g. for an event a method causes next to the one of the method,
+// an event that can't be stored is only logged
+func recordAuditEvent(
+	ctx context.Context,
+	store db.Store,
+	action string,
+	actor string,
+	resources map[string]any,
+	outcome string,
+	code codes.Code,
+) {
+	logger := zerolog.Ctx(ctx)
+
+	resourceIDs, err := json.Marshal(resources)
+	if err != nil {
+		logger.Error().Err(err).Str("action", action).Msg("cannot encode the resources of audit event")
+		return
+	}
+
+	mtdt := extractMetadata(ctx)
+
+	_, err = store.CreateAuditEvent(ctx, db.CreateAuditEventParams{
+		Actor:       actor,
+		Action:      action,
+		ResourceIds: resourceIDs,
+		ClientIp:    mtdt.ClientIP,
+		RequestID:   mtdt.RequestID,
+		Outcome:     outcome,
+		StatusCode:  int32(code),
+	})
+
+	if err != nil {
+		logger.Error().Err(err).Str("action", action).Msg("cannot record audit event")
+	}
+}
+
+// auditActor is the authenticated user, or the username of a request that isn't authenticated, e.g. a login
+func auditActor(ctx context.Context, req any) string {
+	if payload := authPayload(ctx); payload != nil {
+		return payload.Username
+	}
+
+	if named, ok := req.(interface{ GetUsername() string }); ok {
+		return named.GetUsername()
+	}
+
+	return ""
+}
+
+// auditResources collects the ids of the resources the request created or used
+func auditResources(req any, res any) map[string]any {
+	resources := map[string]any{}
+
+	if transfer, ok := req.(*pb.CreateTransferRequest); ok {
+		resources["from_account_id"] = transfer.GetFromAccountId()
+		resources["to_account_id"] = transfer.GetToAccountId()
+	}
+
+	switch res := res.(type) {
+	case *pb.CreateAccountResponse:
+		if res != nil {
+			resources["account_id"] = res.GetAccount().GetId()
+		}
+	case *pb.CreateTransferResponse:
+		if res != nil {
+			resources["transfer_id"] = res.GetTransfer().GetId()
+		}
+	case *pb.LoginUserResponse:
+		if res != nil {
+			resources["session_id"] = res.GetSessionId()
+		}
+	}
+
+	return resources
+}
@@ -0,0 +1,63 @@
+package gapi
+
+import (
+	"errors"
+	"github.com/aybarsacar/simplebank/bank"
+	"github.com/aybarsacar/simplebank/fx"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain is the domain of the reasons attached to the errors, the reasons are the error codes of the HTTP api
+const errorDomain = "simplebank"
+
+func fieldViolation(field string, err error) *errdetails.BadRequest_FieldViolation {
+	return &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: err.Error(),
+	}
+}
+
+// invalidArgumentError lists every invalid field of the request
+func invalidArgumentError(violations []*errdetails.BadRequest_FieldViolation) error {
+	badRequest := &errdetails.BadRequest{FieldViolations: violations}
+	statusInvalid := status.New(codes.InvalidArgument, "invalid parameters")
+
+	statusDetails, err := statusInvalid.WithDetails(badRequest)
+	if err != nil {
+		return statusInvalid.Err()
+	}
+
+	return statusDetails.Err()
+}
+
+// reasonError attaches a stable, machine-readable reason to the error,
+// so clients don't have to match on the message text
+func reasonError(code codes.Code, reason string, err error) error {
+	statusReason := status.New(code, err.Error())
+
+	statusDetails, detailsErr := statusReason.WithDetails(&errdetails.ErrorInfo{
+		Reason: reason,
+		Domain: errorDomain,
+	})
+	if detailsErr != nil {
+		return statusReason.Err()
+	}
+
+	return statusDetails.Err()
+}
+
+// bankError maps the errors of the shared checks of the bank package to the codes of the gRPC api
+func bankError(err error) error {
+	switch {
+	case errors.Is(err, bank.ErrAccountNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, bank.ErrCurrencyMismatch),
+		errors.Is(err, bank.ErrCrossCurrencyDisabled),
+		errors.Is(err, fx.ErrUnsupportedCurrency):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
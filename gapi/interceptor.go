@@ -0,0 +1,91 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aybarsacar/simplebank/metrics"
+	"github.com/aybarsacar/simplebank/token"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+const (
+	authorizationHeader     = "authorization"
+	authorizationTypeBearer = "bearer"
+)
+
+// methods that can be called without an access token
+var publicMethods = map[string]bool{
+	"/pb.SimpleBank/CreateUser": true,
+	"/pb.SimpleBank/LoginUser":  true,
+}
+
+type authorizationPayloadKey struct{}
+
+// authInterceptor is the gRPC counterpart of the authMiddleware of the HTTP api
+// it verifies the access token in the authorization metadata and stores its payload in the context
+func authInterceptor(tokenMaker token.Maker, denylist *token.Denylist) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if publicMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		payload, err := authenticate(ctx, tokenMaker, denylist)
+		if err != nil {
+			return nil, err
+		}
+
+		withUsername(ctx, payload.Username)
+
+		return handler(context.WithValue(ctx, authorizationPayloadKey{}, payload), req)
+	}
+}
+
+func authenticate(ctx context.Context, tokenMaker token.Maker, denylist *token.Denylist) (*token.Payload, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing metadata")
+	}
+
+	values := md.Get(authorizationHeader)
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "authorization header is not provided")
+	}
+
+	fields := strings.Fields(values[0])
+	if len(fields) < 2 {
+		return nil, status.Error(codes.Unauthenticated, "invalid authorisation header format")
+	}
+
+	if strings.ToLower(fields[0]) != authorizationTypeBearer {
+		return nil, status.Error(codes.Unauthenticated, "unsupported authorisation type")
+	}
+
+	payload, err := tokenMaker.VerifyToken(fields[1], token.TokenTypeAccessToken)
+	if err != nil {
+		metrics.TokenVerificationFailed(errors.Is(err, token.ErrExpiredToken))
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	// the token is valid but it could have been revoked before it expired, e.g. the user logged out
+	revoked, err := denylist.IsRevoked(ctx, payload)
+	if err != nil {
+		return nil, status.Error(codes.Internal, fmt.Sprintf("cannot check the token: %s", err))
+	}
+
+	if revoked {
+		return nil, status.Error(codes.Unauthenticated, "token has been revoked")
+	}
+
+	return payload, nil
+}
+
+// authPayload returns the payload of the access token, it is nil for the public methods
+func authPayload(ctx context.Context) *token.Payload {
+	payload, _ := ctx.Value(authorizationPayloadKey{}).(*token.Payload)
+	return payload
+}
@@ -0,0 +1,76 @@
+package gapi
+
+import (
+	"context"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+// codes of the calls that failed because of the server, logged as errors
+var serverErrorCodes = map[codes.Code]bool{
+	codes.Unknown:          true,
+	codes.Internal:         true,
+	codes.Unavailable:      true,
+	codes.DataLoss:         true,
+	codes.DeadlineExceeded: true,
+}
+
+// loggerInterceptor is the gRPC counterpart of the loggerMiddleware of the HTTP api
+// it puts a logger that carries the request id into the context and writes a log line for every call once it has been handled
+// it must run first, so the other interceptors and the store log with the request id
+func loggerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		startTime := time.Now()
+
+		mtdt := extractMetadata(ctx)
+		ctx = withRequestID(ctx, mtdt.RequestID)
+
+		requestLogger := log.With().Str("request_id", mtdt.RequestID).Logger()
+		ctx = requestLogger.WithContext(ctx)
+
+		res, err := handler(ctx, req)
+
+		code := status.Code(err)
+		logger := zerolog.Ctx(ctx)
+
+		event := logger.Info()
+		if serverErrorCodes[code] {
+			event = logger.Error().Err(err)
+		}
+
+		event.
+			Str("method", info.FullMethod).
+			Str("code", code.String()).
+			Dur("duration", time.Since(startTime)).
+			Str("client_ip", mtdt.ClientIP).
+			Str("user_agent", mtdt.UserAgent).
+			Msg("grpc request")
+
+		return res, err
+	}
+}
+
+// withRequestID keeps the request id in the incoming metadata, so a generated one is the same for the audit events
+func withRequestID(ctx context.Context, requestID string) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		md = metadata.MD{}
+	}
+
+	md = md.Copy()
+	md.Set(requestIDHeader, requestID)
+
+	return metadata.NewIncomingContext(ctx, md)
+}
+
+// withUsername adds the authenticated user to the logger of the call
+func withUsername(ctx context.Context, username string) {
+	zerolog.Ctx(ctx).UpdateContext(func(c zerolog.Context) zerolog.Context {
+		return c.Str("username", username)
+	})
+}
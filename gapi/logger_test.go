@@ -0,0 +1,72 @@
+package gapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/aybarsacar/simplebank/db/dbtest"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/pb"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"testing"
+	"time"
+)
+
+func TestLoggerInterceptor(t *testing.T) {
+	account := db.Account{
+		ID:       util.RandomInt(1, 1000),
+		Owner:    util.RandomOwner(),
+		Currency: util.USD,
+	}
+
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	store := mockdb.NewMockStore(controller)
+
+	var auditEvent db.CreateAuditEventParams
+
+	dbtest.ExpectAuthorized(store, account.Owner)
+	dbtest.ExpectVerifiedEmail(store, account.Owner)
+
+	store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(1).Return(account, nil)
+	store.EXPECT().
+		CreateAuditEvent(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(ctx context.Context, args db.CreateAuditEventParams) (db.AuditEvent, error) {
+			auditEvent = args
+			return db.AuditEvent{}, nil
+		})
+
+	var logs bytes.Buffer
+
+	logger := log.Logger
+	log.Logger = zerolog.New(&logs)
+	t.Cleanup(func() { log.Logger = logger })
+
+	server := newTestServer(t, store)
+	client := newTestClient(t, server)
+
+	ctx := newContextWithBearerToken(t, server.tokenMaker, account.Owner, util.CustomerRole, time.Minute)
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	_, err := client.CreateAccount(metadata.NewOutgoingContext(context.Background(), md), &pb.CreateAccountRequest{Currency: account.Currency})
+	require.NoError(t, err)
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &line))
+
+	// the client sent no request id, the generated one is the same in the log and the audit event
+	require.NotEmpty(t, line["request_id"])
+	require.Equal(t, auditEvent.RequestID, line["request_id"])
+	require.Equal(t, account.Owner, line["username"])
+	require.Equal(t, "/pb.SimpleBank/CreateAccount", line["method"])
+	require.Equal(t, "OK", line["code"])
+}
@@ -0,0 +1,53 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+	"github.com/aybarsacar/simplebank/db/dbtest"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	"github.com/aybarsacar/simplebank/token"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"os"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T, store *mockdb.MockStore) *Server {
+	config := util.Config{
+		TokenSymmetricKey:    util.RandomString(32),
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
+	}
+
+	// a new server loads the currencies once
+	dbtest.ExpectLoadCurrencies(store)
+
+	server, err := NewServer(config, store, token.NewDenylist(store))
+	require.NoError(t, err)
+
+	return server
+}
+
+// newContextWithBearerToken is the context of a request sent with an access token of the user
+func newContextWithBearerToken(t *testing.T, tokenMaker token.Maker, username string, role string, duration time.Duration) context.Context {
+	accessToken, _, err := tokenMaker.CreateToken(username, role, duration, token.TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	md := metadata.MD{
+		authorizationHeader: []string{fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken)},
+	}
+
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+// main entry point to the tests
+func TestMain(m *testing.M) {
+	// the tests that check the logs capture them, the others don't write access logs
+	log.Logger = zerolog.Nop()
+
+	os.Exit(m.Run())
+}
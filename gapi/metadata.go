@@ -0,0 +1,52 @@
+package gapi
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+)
+
+const (
+	userAgentHeader = "user-agent"
+	requestIDHeader = "x-request-id"
+	// longer request ids sent by clients are replaced, so they can't flood the logs
+	maxRequestIDLength = 128
+)
+
+// Metadata is what the server knows about the client of a request
+type Metadata struct {
+	UserAgent string
+	ClientIP  string
+	// RequestID is the id sent by the client, or a new one when it sent none
+	RequestID string
+}
+
+func extractMetadata(ctx context.Context) Metadata {
+	mtdt := Metadata{}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if userAgents := md.Get(userAgentHeader); len(userAgents) > 0 {
+			mtdt.UserAgent = userAgents[0]
+		}
+
+		if requestIDs := md.Get(requestIDHeader); len(requestIDs) > 0 {
+			mtdt.RequestID = requestIDs[0]
+		}
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		mtdt.ClientIP = p.Addr.String()
+
+		if host, _, err := net.SplitHostPort(mtdt.ClientIP); err == nil {
+			mtdt.ClientIP = host
+		}
+	}
+
+	if mtdt.RequestID == "" || len(mtdt.RequestID) > maxRequestIDLength {
+		mtdt.RequestID = uuid.NewString()
+	}
+
+	return mtdt
+}
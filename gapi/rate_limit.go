@@ -0,0 +1,69 @@
+package gapi
+
+import (
+	"context"
+	"github.com/aybarsacar/simplebank/ratelimit"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+)
+
+type rateLimitRoute struct {
+	method string
+	path   string
+}
+
+// the routes of the HTTP api whose limits apply to the methods, a call takes a token from the same bucket as a request to the route
+var rateLimitRoutes = map[string]rateLimitRoute{
+	"/pb.SimpleBank/CreateUser":        {method: http.MethodPost, path: "/api/v1/users"},
+	"/pb.SimpleBank/LoginUser":         {method: http.MethodPost, path: "/api/v1/users/login"},
+	"/pb.SimpleBank/ResendVerifyEmail": {method: http.MethodPost, path: "/api/v1/users/verify_email/resend"},
+	"/pb.SimpleBank/CreateAccount":     {method: http.MethodPost, path: "/api/v1/accounts"},
+	"/pb.SimpleBank/GetAccount":        {method: http.MethodGet, path: "/api/v1/accounts/:id"},
+	"/pb.SimpleBank/ListAccounts":      {method: http.MethodGet, path: "/api/v1/accounts"},
+	"/pb.SimpleBank/CreateTransfer":    {method: http.MethodPost, path: "/api/v1/transfers"},
+}
+
+// SetRateLimitStore replaces the in-memory store of the rate limits, e.g. with the store of the HTTP server, so both count the same buckets
+// it must be called before the server is started
+func (server *Server) SetRateLimitStore(store ratelimit.Store) {
+	server.rateLimiter = store
+}
+
+// rateLimitInterceptor is the gRPC counterpart of the rateLimitMiddleware of the HTTP api
+// the authenticated calls are counted by username, so it must run after the authInterceptor, the public ones are counted by peer IP
+func (server *Server) rateLimitInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		route, ok := rateLimitRoutes[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		limit, ok := server.rateLimits.Limit(route.method, route.path)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		username := ""
+		if payload := authPayload(ctx); payload != nil {
+			username = payload.Username
+		}
+
+		key := ratelimit.Key(route.method, route.path, extractMetadata(ctx).ClientIP, username)
+
+		result, err := server.rateLimiter.Take(ctx, key, limit)
+		if err != nil {
+			// an unavailable store must not take the api down with it
+			zerolog.Ctx(ctx).Error().Err(err).Msg("cannot check the rate limit")
+			return handler(ctx, req)
+		}
+
+		if !result.Allowed {
+			return nil, status.Error(codes.ResourceExhausted, "too many requests, retry later")
+		}
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,115 @@
+package gapi
+
+import (
+	"context"
+	"github.com/aybarsacar/simplebank/db/dbtest"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/pb"
+	"github.com/aybarsacar/simplebank/ratelimit"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingRateLimitStore remembers the keys of the buckets the tokens are taken from
+type recordingRateLimitStore struct {
+	ratelimit.Store
+	mutex sync.Mutex
+	keys  []string
+}
+
+func (store *recordingRateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	store.mutex.Lock()
+	store.keys = append(store.keys, key)
+	store.mutex.Unlock()
+
+	return store.Store.Take(ctx, key, limit)
+}
+
+func TestRateLimitPublicMethod(t *testing.T) {
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	store := mockdb.NewMockStore(controller)
+
+	// only the calls that get past the limit reach their method and are audited
+	dbtest.ExpectAuditEvent(store).Times(3)
+
+	server := newTestServer(t, store)
+	server.rateLimits = ratelimit.Rules{"POST /api/v1/users/login": {Requests: 2, Period: time.Minute}}
+
+	client := newTestClient(t, server)
+
+	// the request is invalid, the limit is checked before the method runs
+	for i := 0; i < 2; i++ {
+		_, err := client.LoginUser(context.Background(), &pb.LoginUserRequest{})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+
+	_, err := client.LoginUser(context.Background(), &pb.LoginUserRequest{})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// the methods without a limit are not throttled
+	_, err = client.CreateUser(context.Background(), &pb.CreateUserRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestRateLimitAuthenticatedMethod(t *testing.T) {
+	account := db.Account{
+		ID:       util.RandomInt(1, 1000),
+		Owner:    util.RandomOwner(),
+		Currency: util.RandomCurrency(),
+	}
+
+	otherUser := util.RandomOwner()
+
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	store := mockdb.NewMockStore(controller)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(2).Return(account, nil)
+
+	// the limit is checked after the token, the credentials of the owner are cached by their second call
+	dbtest.ExpectAuthorized(store, account.Owner)
+	store.EXPECT().IsTokenRevoked(gomock.Any(), gomock.Any()).Times(1).Return(false, nil)
+	dbtest.ExpectAuthorized(store, otherUser)
+
+	server := newTestServer(t, store)
+	server.rateLimits = ratelimit.Rules{"GET /api/v1/accounts/:id": {Requests: 1, Period: time.Minute}}
+
+	rateLimiter := &recordingRateLimitStore{Store: ratelimit.NewMemoryStore()}
+	server.SetRateLimitStore(rateLimiter)
+
+	client := newTestClient(t, server)
+
+	getAccount := func(username string) codes.Code {
+		ctx := newContextWithBearerToken(t, server.tokenMaker, username, util.CustomerRole, time.Minute)
+		md, _ := metadata.FromIncomingContext(ctx)
+
+		_, err := client.GetAccount(metadata.NewOutgoingContext(context.Background(), md), &pb.GetAccountRequest{Id: account.ID})
+		return status.Code(err)
+	}
+
+	require.Equal(t, codes.OK, getAccount(account.Owner))
+	require.Equal(t, codes.ResourceExhausted, getAccount(account.Owner))
+
+	// the users are counted by username, not by the IP they share, the other user isn't the owner of the account
+	require.Equal(t, codes.PermissionDenied, getAccount(otherUser))
+
+	// the calls take their tokens from the buckets of the HTTP route, so a client has the same limit on both apis
+	require.Equal(t, []string{
+		ratelimit.Key(http.MethodGet, "/api/v1/accounts/:id", "", account.Owner),
+		ratelimit.Key(http.MethodGet, "/api/v1/accounts/:id", "", account.Owner),
+		ratelimit.Key(http.MethodGet, "/api/v1/accounts/:id", "", otherUser),
+	}, rateLimiter.keys)
+}
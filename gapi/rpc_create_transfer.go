@@ -0,0 +1,192 @@
+package gapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/aybarsacar/simplebank/bank"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/fx"
+	"github.com/aybarsacar/simplebank/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// reasons of the transfer errors, the same as the error codes of the HTTP api
+const (
+	reasonInsufficientFunds      = "insufficient_funds"
+	reasonIdempotencyKeyConflict = "idempotency_key_conflict"
+	reasonInvalidConvertedAmount = "invalid_converted_amount"
+	reasonAccountFrozen          = "account_frozen"
+	reasonAccountClosed          = "account_closed"
+)
+
+func (server *Server) CreateTransfer(ctx context.Context, req *pb.CreateTransferRequest) (*pb.CreateTransferResponse, error) {
+	if violations := validateCreateTransferRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	payload := authPayload(ctx)
+
+	if err := server.requireVerifiedEmail(ctx, payload.Username); err != nil {
+		return nil, err
+	}
+
+	fromAccount, err := server.validAccount(ctx, req.GetFromAccountId(), req.GetCurrency())
+	if err != nil {
+		return nil, err
+	}
+
+	if fromAccount.Owner != payload.Username {
+		return nil, status.Error(codes.PermissionDenied, "from account does not belong to the authenticated user")
+	}
+
+	toCurrency := req.GetCurrency()
+	if req.ToCurrency != nil {
+		toCurrency = req.GetToCurrency()
+	}
+
+	if _, err := server.validAccount(ctx, req.GetToAccountId(), toCurrency); err != nil {
+		return nil, err
+	}
+
+	// the zero rate means both accounts use the same currency
+	var rate fx.Rate
+
+	if toCurrency != req.GetCurrency() {
+		if rate, err = server.exchangeRate(ctx, req.GetCurrency(), toCurrency); err != nil {
+			return nil, err
+		}
+	}
+
+	args := db.TransferTxParams{
+		FromAccountID: req.GetFromAccountId(),
+		ToAccountID:   req.GetToAccountId(),
+		Amount:        req.GetAmount(),
+		Currency:      req.GetCurrency(),
+	}
+
+	var result db.TransferTxResult
+
+	switch {
+	case req.GetIdempotencyKey() != "":
+		result, err = server.store.IdempotentTransferTx(ctx, db.IdempotentTransferTxParams{
+			TransferTxParams: args,
+			Rate:             rate,
+			Username:         payload.Username,
+			IdempotencyKey:   req.GetIdempotencyKey(),
+			RequestHash:      hashTransferRequest(req),
+		})
+	case !rate.IsZero():
+		result, err = server.store.CrossCurrencyTransferTx(ctx, db.CrossCurrencyTransferTxParams{
+			TransferTxParams: args,
+			Rate:             rate,
+		})
+	default:
+		result, err = server.store.TransferTx(ctx, args)
+	}
+
+	if err != nil {
+		return nil, transferError(err)
+	}
+
+	return &pb.CreateTransferResponse{
+		Transfer:    convertTransfer(result.Transfer),
+		FromAccount: convertAccount(result.FromAccount),
+		ToAccount:   convertAccount(result.ToAccount),
+		FromEntry:   convertEntry(result.FromEntry),
+		ToEntry:     convertEntry(result.ToEntry),
+	}, nil
+}
+
+func validateCreateTransferRequest(req *pb.CreateTransferRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := validateID(req.GetFromAccountId()); err != nil {
+		violations = append(violations, fieldViolation("from_account_id", err))
+	}
+
+	if err := validateID(req.GetToAccountId()); err != nil {
+		violations = append(violations, fieldViolation("to_account_id", err))
+	}
+
+	if req.GetAmount() <= 0 {
+		violations = append(violations, fieldViolation("amount", errors.New("must be greater than 0")))
+	}
+
+	currencyErr := validateCurrency(req.GetCurrency())
+	if currencyErr != nil {
+		violations = append(violations, fieldViolation("currency", currencyErr))
+	}
+
+	// money can still leave an account in a disabled currency, but it can't go into one
+	if req.ToCurrency != nil {
+		if err := validateEnabledCurrency(req.GetToCurrency()); err != nil {
+			violations = append(violations, fieldViolation("to_currency", err))
+		}
+	} else if currencyErr == nil {
+		if err := validateEnabledCurrency(req.GetCurrency()); err != nil {
+			violations = append(violations, fieldViolation("currency", err))
+		}
+	}
+
+	if len(req.GetIdempotencyKey()) > maxIdempotencyKeyLength {
+		err := fmt.Errorf("must be at most %d characters", maxIdempotencyKeyLength)
+		violations = append(violations, fieldViolation("idempotency_key", err))
+	}
+
+	return violations
+}
+
+// validAccount checks the account exists and its currency matches the currency of the request
+func (server *Server) validAccount(ctx context.Context, accountID int64, currency string) (db.Account, error) {
+	account, err := bank.ValidAccount(ctx, server.store, accountID, currency)
+	if err != nil {
+		return account, bankError(err)
+	}
+
+	return account, nil
+}
+
+// exchangeRate gets the rate to convert between the currencies of the accounts of a transfer
+func (server *Server) exchangeRate(ctx context.Context, from string, to string) (fx.Rate, error) {
+	rate, err := bank.ExchangeRate(ctx, server.rates, from, to)
+	if err != nil {
+		return rate, bankError(err)
+	}
+
+	return rate, nil
+}
+
+func transferError(err error) error {
+	switch {
+	case errors.Is(err, db.ErrInsufficientFunds):
+		return reasonError(codes.FailedPrecondition, reasonInsufficientFunds, err)
+	case errors.Is(err, db.ErrAccountFrozen):
+		return reasonError(codes.FailedPrecondition, reasonAccountFrozen, err)
+	case errors.Is(err, db.ErrAccountClosed):
+		return reasonError(codes.FailedPrecondition, reasonAccountClosed, err)
+	case errors.Is(err, db.ErrAmountTooSmall), errors.Is(err, fx.ErrAmountOverflow):
+		return reasonError(codes.FailedPrecondition, reasonInvalidConvertedAmount, err)
+	case errors.Is(err, db.ErrIdempotencyKeyConflict):
+		return reasonError(codes.AlreadyExists, reasonIdempotencyKeyConflict, err)
+	}
+
+	return status.Errorf(codes.Internal, "failed to create transfer: %s", err)
+}
+
+// hashTransferRequest is the fingerprint of the request, used to detect an idempotency key reused with a different transfer
+// the key itself is left out, like the HTTP api that reads it from a header
+func hashTransferRequest(req *pb.CreateTransferRequest) string {
+	fingerprint := proto.Clone(req).(*pb.CreateTransferRequest)
+	fingerprint.IdempotencyKey = nil
+
+	data, _ := proto.MarshalOptions{Deterministic: true}.Marshal(fingerprint)
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
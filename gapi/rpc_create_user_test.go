@@ -0,0 +1,131 @@
+package gapi
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aybarsacar/simplebank/db/dbtest"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/pb"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestCreateUserRPC(t *testing.T) {
+	password := util.RandomString(6)
+
+	user := db.User{
+		Username: util.RandomOwner(),
+		FullName: util.RandomOwner(),
+		Email:    util.RandomEmail(),
+		Role:     util.CustomerRole,
+	}
+
+	req := &pb.CreateUserRequest{
+		Username: user.Username,
+		FullName: user.FullName,
+		Email:    user.Email,
+		Password: password,
+	}
+
+	testCases := []struct {
+		name          string
+		req           *pb.CreateUserRequest
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, res *pb.CreateUserResponse, err error)
+	}{
+		{
+			name: "OK",
+			req:  req,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUserTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, args db.CreateUserParams) (db.CreateUserTxResult, error) {
+						require.Equal(t, user.Username, args.Username)
+						require.NoError(t, util.CheckPassword(password, args.HashedPassword))
+						return db.CreateUserTxResult{User: user}, nil
+					})
+
+				store.EXPECT().
+					CreateAuditEvent(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, args db.CreateAuditEventParams) (db.AuditEvent, error) {
+						require.Equal(t, user.Username, args.Actor)
+						require.Equal(t, "user.create", args.Action)
+						require.Equal(t, auditOutcomeSuccess, args.Outcome)
+						require.Equal(t, int32(codes.OK), args.StatusCode)
+						require.NotEmpty(t, args.RequestID)
+						require.True(t, json.Valid(args.ResourceIds))
+						return db.AuditEvent{}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateUserResponse, err error) {
+				require.NoError(t, err)
+				require.Equal(t, user.Username, res.GetUser().GetUsername())
+				require.Equal(t, user.Email, res.GetUser().GetEmail())
+			},
+		},
+		{
+			name: "DuplicateUsername",
+			req:  req,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(1).Return(db.CreateUserTxResult{}, &pq.Error{Code: "23505"})
+
+				store.EXPECT().
+					CreateAuditEvent(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, args db.CreateAuditEventParams) (db.AuditEvent, error) {
+						require.Equal(t, auditOutcomeFailure, args.Outcome)
+						require.Equal(t, int32(codes.AlreadyExists), args.StatusCode)
+						return db.AuditEvent{}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateUserResponse, err error) {
+				require.Equal(t, codes.AlreadyExists, status.Code(err))
+			},
+		},
+		{
+			name: "InvalidEmail",
+			req: &pb.CreateUserRequest{
+				Username: user.Username,
+				FullName: user.FullName,
+				Email:    "invalid-email",
+				Password: password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				dbtest.ExpectAuditEvent(store)
+
+				store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, res *pb.CreateUserResponse, err error) {
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+
+			testCase.buildStubs(store)
+
+			server := newTestServer(t, store)
+			client := newTestClient(t, server)
+
+			// CreateUser is public, so it is called without an access token
+			res, err := client.CreateUser(context.Background(), testCase.req)
+
+			testCase.checkResponse(t, res, err)
+		})
+	}
+}
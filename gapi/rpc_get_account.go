@@ -0,0 +1,40 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/pb"
+	"github.com/aybarsacar/simplebank/token"
+	"github.com/aybarsacar/simplebank/util"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (server *Server) GetAccount(ctx context.Context, req *pb.GetAccountRequest) (*pb.GetAccountResponse, error) {
+	if err := validateID(req.GetId()); err != nil {
+		return nil, invalidArgumentError([]*errdetails.BadRequest_FieldViolation{fieldViolation("id", err)})
+	}
+
+	account, err := server.store.GetAccount(ctx, req.GetId())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "account not found")
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to get account: %s", err)
+	}
+
+	if !canReadAccount(authPayload(ctx), account) {
+		return nil, status.Error(codes.PermissionDenied, "account does not belong to the user")
+	}
+
+	return &pb.GetAccountResponse{Account: convertAccount(account)}, nil
+}
+
+// canReadAccount tells if the user can read the account
+// customers can only read their own accounts while the staff can read every account
+func canReadAccount(payload *token.Payload, account db.Account) bool {
+	return account.Owner == payload.Username || util.IsStaffRole(payload.Role)
+}
@@ -0,0 +1,129 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/pb"
+	"github.com/aybarsacar/simplebank/token"
+	"github.com/aybarsacar/simplebank/util"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"time"
+)
+
+// reason of the error of a locked user, the same as the error code of the HTTP api
+const reasonUserLocked = "user_locked"
+
+func (server *Server) LoginUser(ctx context.Context, req *pb.LoginUserRequest) (*pb.LoginUserResponse, error) {
+	if violations := validateLoginUserRequest(req); violations != nil {
+		return nil, invalidArgumentError(violations)
+	}
+
+	user, err := server.store.GetUser(ctx, req.GetUsername())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to find user: %s", err)
+	}
+
+	// a locked user can't log in even with the correct password, like in the HTTP api
+	if util.LockedOut(user.LockedUntil) {
+		return nil, userLockedError(user)
+	}
+
+	if err := util.CheckPassword(req.GetPassword(), user.HashedPassword); err != nil {
+		return nil, server.failLogin(ctx, user)
+	}
+
+	// a successful login resets the failed attempts
+	if user.FailedLoginAttempts > 0 || user.LockedUntil.Valid {
+		user, err = server.store.UnlockUser(ctx, user.Username)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to reset failed logins: %s", err)
+		}
+	}
+
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, server.config.AccessTokenDuration, token.TokenTypeAccessToken)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create access token: %s", err)
+	}
+
+	// the long-lived refresh token is used to get new access tokens without logging in again
+	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, server.config.RefreshTokenDuration, token.TokenTypeRefreshToken)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create refresh token: %s", err)
+	}
+
+	mtdt := extractMetadata(ctx)
+
+	session, err := server.store.CreateSession(ctx, db.CreateSessionParams{
+		ID:           refreshPayload.ID,
+		Username:     user.Username,
+		RefreshToken: refreshToken,
+		UserAgent:    mtdt.UserAgent,
+		ClientIp:     mtdt.ClientIP,
+		IsBlocked:    false,
+		ExpiresAt:    refreshPayload.ExpiresAt,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create session: %s", err)
+	}
+
+	return &pb.LoginUserResponse{
+		User:                  convertUser(user),
+		SessionId:             session.ID.String(),
+		AccessToken:           accessToken,
+		RefreshToken:          refreshToken,
+		AccessTokenExpiresAt:  timestamppb.New(accessPayload.ExpiresAt),
+		RefreshTokenExpiresAt: timestamppb.New(refreshPayload.ExpiresAt),
+	}, nil
+}
+
+// failLogin counts the failed login of the user, the user is locked out once there are too many
+// the login that locks the user out gets the error of a locked user and is recorded in the audit trail
+func (server *Server) failLogin(ctx context.Context, user db.User) error {
+	lockout := server.config.LoginLockout()
+
+	user, err := server.store.RecordFailedLogin(ctx, db.RecordFailedLoginParams{
+		MaxAttempts:       int32(lockout.MaxAttempts),
+		LockoutSeconds:    lockout.Duration.Seconds(),
+		MaxLockoutSeconds: lockout.MaxDuration.Seconds(),
+		Username:          user.Username,
+	})
+
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to record failed login: %s", err)
+	}
+
+	if !util.LockedOut(user.LockedUntil) {
+		return status.Error(codes.Unauthenticated, "incorrect password")
+	}
+
+	resources := map[string]any{"locked_until": user.LockedUntil.Time}
+	recordAuditEvent(ctx, server.store, auditActionLockUser, user.Username, resources, auditOutcomeSuccess, codes.PermissionDenied)
+
+	return userLockedError(user)
+}
+
+func userLockedError(user db.User) error {
+	err := fmt.Errorf("user is locked until %s after too many failed logins", user.LockedUntil.Time.UTC().Format(time.RFC3339))
+	return reasonError(codes.PermissionDenied, reasonUserLocked, err)
+}
+
+func validateLoginUserRequest(req *pb.LoginUserRequest) (violations []*errdetails.BadRequest_FieldViolation) {
+	if err := validateUsername(req.GetUsername()); err != nil {
+		violations = append(violations, fieldViolation("username", err))
+	}
+
+	if err := validatePassword(req.GetPassword()); err != nil {
+		violations = append(violations, fieldViolation("password", err))
+	}
+
+	return violations
+}
@@ -0,0 +1,37 @@
+package gapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ResendVerifyEmail queues another verification mail for the authenticated user, like the HTTP api
+func (server *Server) ResendVerifyEmail(ctx context.Context, req *pb.ResendVerifyEmailRequest) (*pb.ResendVerifyEmailResponse, error) {
+	user, err := server.store.GetUser(ctx, authPayload(ctx).Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+
+		return nil, status.Errorf(codes.Internal, "failed to get user: %s", err)
+	}
+
+	if user.IsEmailVerified {
+		return nil, reasonError(codes.FailedPrecondition, reasonEmailAlreadyVerified, errors.New("email is already verified"))
+	}
+
+	_, err = server.store.CreateVerifyEmail(ctx, db.CreateVerifyEmailParams{
+		Username: user.Username,
+		Email:    user.Email,
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create verify email: %s", err)
+	}
+
+	return &pb.ResendVerifyEmailResponse{}, nil
+}
@@ -0,0 +1,123 @@
+package gapi
+
+import (
+	"context"
+	"fmt"
+	"github.com/aybarsacar/simplebank/bank"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/fx"
+	"github.com/aybarsacar/simplebank/pb"
+	"github.com/aybarsacar/simplebank/ratelimit"
+	"github.com/aybarsacar/simplebank/token"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	"net"
+)
+
+// Server serves the gRPC requests of the banking service
+// it runs next to the HTTP server and uses the same store and tokens
+type Server struct {
+	pb.UnimplementedSimpleBankServer
+	config     util.Config
+	store      db.Store
+	tokenMaker token.Maker
+	denylist   *token.Denylist
+	// rates is nil when transfers between accounts of different currencies are not enabled
+	rates       fx.RateProvider
+	grpcServer  *grpc.Server
+	rateLimits  ratelimit.Rules
+	rateLimiter ratelimit.Store
+}
+
+// NewServer creates a gRPC server
+// it takes the denylist of the HTTP server
+func NewServer(config util.Config, store db.Store, denylist *token.Denylist) (*Server, error) {
+	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create token maker: %w", err)
+	}
+
+	// the same limits as the HTTP api, the methods are limited by the routes they share with it
+	rateLimits, err := ratelimit.ParseRules(config.RateLimits)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse rate limits: %w", err)
+	}
+
+	server := &Server{
+		config:      config,
+		store:       store,
+		tokenMaker:  tokenMaker,
+		denylist:    denylist,
+		rateLimits:  rateLimits,
+		rateLimiter: ratelimit.NewMemoryStore(),
+	}
+
+	if config.FXRatesFile != "" {
+		server.rates, err = fx.NewFileRateProvider(config.FXRatesFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create rate provider: %w", err)
+		}
+	}
+
+	if err := bank.LoadCurrencies(context.Background(), store); err != nil {
+		return nil, fmt.Errorf("cannot load currencies: %w", err)
+	}
+
+	server.grpcServer = server.newGRPCServer()
+
+	// lets clients like grpcurl list the services and methods
+	reflection.Register(server.grpcServer)
+
+	return server, nil
+}
+
+// Start runs the gRPC server on a specific address until Shutdown is called
+// it returns nil once the server is shut down
+func (server *Server) Start(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("cannot create listener: %w", err)
+	}
+
+	log.Info().Str("address", listener.Addr().String()).Msg("start gRPC server")
+
+	return server.grpcServer.Serve(listener)
+}
+
+// Shutdown stops accepting new calls and waits for the active ones to finish
+// if the context ends first, the remaining calls are cancelled and its error is returned
+func (server *Server) Shutdown(ctx context.Context) error {
+	stopped := make(chan struct{})
+
+	go func() {
+		server.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		server.grpcServer.Stop()
+		return ctx.Err()
+	}
+}
+
+// newGRPCServer registers the server with its interceptors, the logger runs first,
+// the authentication runs before the rate limit, and the calls that are over their limit are not audited
+func (server *Server) newGRPCServer() *grpc.Server {
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			loggerInterceptor(),
+			authInterceptor(server.tokenMaker, server.denylist),
+			server.rateLimitInterceptor(),
+			auditInterceptor(server.store),
+		),
+	)
+
+	pb.RegisterSimpleBankServer(grpcServer, server)
+
+	return grpcServer
+}
@@ -0,0 +1,31 @@
+package gapi
+
+import (
+	"context"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	"github.com/aybarsacar/simplebank/pb"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestServerShutdown(t *testing.T) {
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	server := newTestServer(t, mockdb.NewMockStore(controller))
+	client := newTestClient(t, server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	require.NoError(t, server.Shutdown(ctx))
+
+	// no new calls are accepted
+	_, err := client.CreateUser(context.Background(), &pb.CreateUserRequest{})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+}
@@ -0,0 +1,84 @@
+package gapi
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aybarsacar/simplebank/util"
+	"net/mail"
+	"regexp"
+)
+
+// the same rules as the binding tags of the HTTP requests
+
+var isValidUsername = regexp.MustCompile(`^[a-zA-Z0-9]+$`).MatchString
+
+func validateString(value string, minLength int, maxLength int) error {
+	n := len(value)
+	if n < minLength || n > maxLength {
+		return fmt.Errorf("must contain from %d-%d characters", minLength, maxLength)
+	}
+
+	return nil
+}
+
+func validateUsername(value string) error {
+	if err := validateString(value, 1, 100); err != nil {
+		return err
+	}
+
+	if !isValidUsername(value) {
+		return errors.New("must contain only letters or digits")
+	}
+
+	return nil
+}
+
+func validatePassword(value string) error {
+	return validateString(value, 6, 100)
+}
+
+func validateFullName(value string) error {
+	return validateString(value, 1, 100)
+}
+
+func validateEmail(value string) error {
+	if err := validateString(value, 3, 200); err != nil {
+		return err
+	}
+
+	if _, err := mail.ParseAddress(value); err != nil {
+		return errors.New("is not a valid email address")
+	}
+
+	return nil
+}
+
+func validateCurrency(value string) error {
+	// check the currency is in the registry, the money in the accounts of a disabled currency can still be moved out
+	if !util.IsKnownCurrency(value) {
+		return errors.New("is not a supported currency")
+	}
+
+	return nil
+}
+
+// validateEnabledCurrency checks the currency can be used for new accounts and for the money going into accounts
+func validateEnabledCurrency(value string) error {
+	if err := validateCurrency(value); err != nil {
+		return err
+	}
+
+	if !util.IsSupportedCurrency(value) {
+		return errors.New("is disabled")
+	}
+
+	return nil
+}
+
+func validateID(value int64) error {
+	if value < 1 {
+		return errors.New("must be a positive integer")
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package gapi
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// the same as the error codes of the HTTP api
+const (
+	reasonEmailNotVerified     = "email_not_verified"
+	reasonEmailAlreadyVerified = "email_already_verified"
+)
+
+// requireVerifiedEmail fails until the user verified their email with the link in the verification mail,
+// the users can't create accounts or make transfers before
+func (server *Server) requireVerifiedEmail(ctx context.Context, username string) error {
+	verified, err := server.store.IsUserEmailVerified(ctx, username)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to check the email verification: %s", err)
+	}
+
+	if !verified {
+		err := errors.New("email must be verified first, follow the link in the verification mail")
+		return reasonError(codes.FailedPrecondition, reasonEmailNotVerified, err)
+	}
+
+	return nil
+}
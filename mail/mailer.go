@@ -0,0 +1,111 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"os"
+	"sync"
+	"time"
+)
+
+// kinds of mailers
+const (
+	MailerLog  = "log"
+	MailerFile = "file"
+)
+
+// Message is a plain text mail to a single recipient
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Mailer delivers the mails of the service, e.g. the password reset tokens
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// NewMailer creates the mailer of the given kind, the kind must be configured explicitly
+// neither of them sends real mails, they let the flows that need mails be used locally and in tests
+func NewMailer(kind string, file string) (Mailer, error) {
+	switch kind {
+	case "":
+		return nil, fmt.Errorf("the mailer is not configured, it must be %s or %s", MailerLog, MailerFile)
+	case MailerLog:
+		return NewLogMailer(), nil
+	case MailerFile:
+		if file == "" {
+			return nil, fmt.Errorf("the file of the %s mailer is not configured", MailerFile)
+		}
+
+		return NewFileMailer(file), nil
+	default:
+		return nil, fmt.Errorf("unsupported mailer %q", kind)
+	}
+}
+
+// LogMailer writes the recipient and the subject of every mail to the log
+// the bodies are never logged as they hold secrets, e.g. the password reset tokens,
+// so the verification and password reset flows can only be followed with the file mailer
+type LogMailer struct{}
+
+// NewLogMailer creates a mailer that writes the mails to the log of the request, or to the global log
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+func (mailer *LogMailer) Send(ctx context.Context, message Message) error {
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		logger = &log.Logger
+	}
+
+	logger.Info().
+		Str("to", message.To).
+		Str("subject", message.Subject).
+		Msg("mail sent")
+
+	return nil
+}
+
+// FileMailer appends every mail as a line of JSON to a local file
+type FileMailer struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// NewFileMailer creates a mailer that appends the mails to the file at path, the file is created if it doesn't exist
+func NewFileMailer(path string) *FileMailer {
+	return &FileMailer{path: path}
+}
+
+type fileMail struct {
+	Message
+	SentAt time.Time `json:"sent_at"`
+}
+
+func (mailer *FileMailer) Send(ctx context.Context, message Message) error {
+	line, err := json.Marshal(fileMail{Message: message, SentAt: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	mailer.mutex.Lock()
+	defer mailer.mutex.Unlock()
+
+	file, err := os.OpenFile(mailer.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,83 @@
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func randomMessage() Message {
+	return Message{
+		To:      util.RandomEmail(),
+		Subject: util.RandomString(10),
+		Body:    util.RandomString(40),
+	}
+}
+
+func TestNewMailer(t *testing.T) {
+	_, err := NewMailer("", "")
+	require.Error(t, err)
+
+	mailer, err := NewMailer(MailerLog, "")
+	require.NoError(t, err)
+	require.IsType(t, &LogMailer{}, mailer)
+
+	mailer, err = NewMailer(MailerFile, filepath.Join(t.TempDir(), "mails.jsonl"))
+	require.NoError(t, err)
+	require.IsType(t, &FileMailer{}, mailer)
+
+	_, err = NewMailer(MailerFile, "")
+	require.Error(t, err)
+
+	_, err = NewMailer("smtp", "")
+	require.Error(t, err)
+}
+
+func TestLogMailer(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := zerolog.New(&buffer)
+
+	message := randomMessage()
+
+	err := NewLogMailer().Send(logger.WithContext(context.Background()), message)
+	require.NoError(t, err)
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &line))
+	require.Equal(t, message.To, line["to"])
+	require.Equal(t, message.Subject, line["subject"])
+	require.NotContains(t, line, "body")
+	require.NotContains(t, buffer.String(), message.Body)
+}
+
+func TestFileMailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mails.jsonl")
+	mailer := NewFileMailer(path)
+
+	messages := []Message{randomMessage(), randomMessage()}
+	for _, message := range messages {
+		require.NoError(t, mailer.Send(context.Background(), message))
+	}
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var sent []Message
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var mail fileMail
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &mail))
+		require.False(t, mail.SentAt.IsZero())
+		sent = append(sent, mail.Message)
+	}
+
+	require.Equal(t, messages, sent)
+}
@@ -0,0 +1,95 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/outbox"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/aybarsacar/simplebank/worker"
+	"time"
+)
+
+// PasswordResetSender mails the tokens of the password resets requested with forgotPassword
+// the request only queues the token, so its response doesn't tell whether a user has the email
+// a mail that fails is retried with the backoff of the outbox until the token expires
+type PasswordResetSender struct {
+	store        db.Store
+	mailer       Mailer
+	pollInterval time.Duration
+}
+
+// NewPasswordResetSender creates a sender that checks for due password reset mails every poll interval
+func NewPasswordResetSender(store db.Store, mailer Mailer, pollInterval time.Duration) *PasswordResetSender {
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+
+	return &PasswordResetSender{
+		store:        store,
+		mailer:       mailer,
+		pollInterval: pollInterval,
+	}
+}
+
+// Start sends the password reset mails until the context is cancelled
+func (sender *PasswordResetSender) Start(ctx context.Context) {
+	worker.Poll(ctx, sender.pollInterval, batchSize, sender.SendPending, "cannot send password reset mails")
+}
+
+// SendPending sends one batch of the password reset mails that are due and returns how many were claimed
+func (sender *PasswordResetSender) SendPending(ctx context.Context) (int, error) {
+	resetTokens, err := sender.store.ClaimPasswordResetTokens(ctx, db.ClaimPasswordResetTokensParams{
+		LeaseUntil: time.Now().Add(claimLease),
+		BatchSize:  batchSize,
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	for _, resetToken := range resetTokens {
+		if err := sender.send(ctx, resetToken); err != nil {
+			return len(resetTokens), err
+		}
+	}
+
+	return len(resetTokens), nil
+}
+
+// send mails a new token and records the outcome, only the hash of the token is stored once the mail is sent
+// only the errors of recording the outcome are returned, a failed mail is retried later with another token
+func (sender *PasswordResetSender) send(ctx context.Context, resetToken db.PasswordResetToken) error {
+	token, err := util.NewSecret()
+	if err != nil {
+		return err
+	}
+
+	err = sender.mailer.Send(ctx, newPasswordResetMessage(resetToken, token))
+	if err != nil {
+		return sender.store.MarkPasswordResetTokenFailed(ctx, db.MarkPasswordResetTokenFailedParams{
+			LastError:     err.Error(),
+			NextAttemptAt: time.Now().Add(outbox.Backoff(resetToken.Attempts)),
+			ID:            resetToken.ID,
+		})
+	}
+
+	return sender.store.MarkPasswordResetTokenSent(ctx, db.MarkPasswordResetTokenSentParams{
+		TokenHash: util.HashSecret(token),
+		ID:        resetToken.ID,
+	})
+}
+
+func newPasswordResetMessage(resetToken db.PasswordResetToken, token string) Message {
+	return Message{
+		To:      resetToken.Email,
+		Subject: "Reset your Simple Bank password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nuse this token to set a new password before %s:\n\n%s\n\n"+
+				"If you didn't ask to reset your password, you can ignore this mail.\n",
+			resetToken.Username,
+			resetToken.ExpiresAt.UTC().Format(time.RFC3339),
+			token,
+		),
+	}
+}
@@ -0,0 +1,105 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPasswordResetSender_SendPending(t *testing.T) {
+	resetToken := db.PasswordResetToken{
+		ID:        1,
+		Username:  util.RandomOwner(),
+		Email:     util.RandomEmail(),
+		Attempts:  2,
+		ExpiresAt: time.Now().Add(time.Hour),
+		CreatedAt: time.Now(),
+	}
+
+	testCases := []struct {
+		name          string
+		mailerErr     error
+		buildStubs    func(store *mockdb.MockStore, mailer *recordingMailer)
+		checkMessages func(t *testing.T, messages []Message)
+	}{
+		{
+			name: "Sent",
+			buildStubs: func(store *mockdb.MockStore, mailer *recordingMailer) {
+				store.EXPECT().ClaimPasswordResetTokens(gomock.Any(), gomock.Any()).Times(1).Return([]db.PasswordResetToken{resetToken}, nil)
+				store.EXPECT().MarkPasswordResetTokenFailed(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					MarkPasswordResetTokenSent(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, args db.MarkPasswordResetTokenSentParams) error {
+						require.Equal(t, resetToken.ID, args.ID)
+
+						// the token is the only line of the body that hashes to the stored hash
+						require.Len(t, mailer.messages, 1)
+						found := false
+						for _, line := range strings.Split(mailer.messages[0].Body, "\n") {
+							if util.HashSecret(line) == args.TokenHash {
+								found = true
+							}
+						}
+
+						require.True(t, found)
+						require.NotContains(t, mailer.messages[0].Body, args.TokenHash)
+						return nil
+					})
+			},
+			checkMessages: func(t *testing.T, messages []Message) {
+				require.Len(t, messages, 1)
+				require.Equal(t, resetToken.Email, messages[0].To)
+			},
+		},
+		{
+			name:      "MailerFailed",
+			mailerErr: errors.New("mail server is down"),
+			buildStubs: func(store *mockdb.MockStore, mailer *recordingMailer) {
+				store.EXPECT().ClaimPasswordResetTokens(gomock.Any(), gomock.Any()).Times(1).Return([]db.PasswordResetToken{resetToken}, nil)
+				store.EXPECT().MarkPasswordResetTokenSent(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					MarkPasswordResetTokenFailed(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, args db.MarkPasswordResetTokenFailedParams) error {
+						require.Equal(t, resetToken.ID, args.ID)
+						require.Equal(t, "mail server is down", args.LastError)
+						// the third attempt waits 4 seconds
+						require.WithinDuration(t, time.Now().Add(4*time.Second), args.NextAttemptAt, time.Second)
+						return nil
+					})
+			},
+			checkMessages: func(t *testing.T, messages []Message) {
+				require.Len(t, messages, 1)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			mailer := &recordingMailer{err: testCase.mailerErr}
+
+			testCase.buildStubs(store, mailer)
+
+			sender := NewPasswordResetSender(store, mailer, time.Second)
+
+			count, err := sender.SendPending(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, 1, count)
+
+			testCase.checkMessages(t, mailer.messages)
+		})
+	}
+}
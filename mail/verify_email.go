@@ -0,0 +1,130 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/outbox"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/aybarsacar/simplebank/worker"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultPollInterval = time.Second
+	// used when VERIFY_EMAIL_DURATION is not configured
+	defaultVerifyEmailDuration = 24 * time.Hour
+	// number of verification mails claimed at once
+	batchSize = 100
+	// a claimed mail is claimed again if it is not marked within the lease, e.g. the process stopped
+	claimLease = time.Minute
+)
+
+// VerifyEmailSender mails the secret codes of the email verifications created with the users
+// a mail that fails is retried with the backoff of the outbox
+type VerifyEmailSender struct {
+	store        db.Store
+	mailer       Mailer
+	pollInterval time.Duration
+	codeDuration time.Duration
+	// the link in the mail is this url with the id and the code of the verification in its query
+	verifyURL string
+}
+
+// NewVerifyEmailSender creates a sender that checks for due verification mails every poll interval,
+// the codes in the mails can be used for the code duration
+func NewVerifyEmailSender(
+	store db.Store,
+	mailer Mailer,
+	pollInterval time.Duration,
+	codeDuration time.Duration,
+	verifyURL string,
+) *VerifyEmailSender {
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+
+	if codeDuration <= 0 {
+		codeDuration = defaultVerifyEmailDuration
+	}
+
+	return &VerifyEmailSender{
+		store:        store,
+		mailer:       mailer,
+		pollInterval: pollInterval,
+		codeDuration: codeDuration,
+		verifyURL:    verifyURL,
+	}
+}
+
+// Start sends the verification mails until the context is cancelled
+func (sender *VerifyEmailSender) Start(ctx context.Context) {
+	worker.Poll(ctx, sender.pollInterval, batchSize, sender.SendPending, "cannot send verification mails")
+}
+
+// SendPending sends one batch of the verification mails that are due and returns how many were claimed
+func (sender *VerifyEmailSender) SendPending(ctx context.Context) (int, error) {
+	verifyEmails, err := sender.store.ClaimVerifyEmails(ctx, db.ClaimVerifyEmailsParams{
+		LeaseUntil: time.Now().Add(claimLease),
+		BatchSize:  batchSize,
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	for _, verifyEmail := range verifyEmails {
+		if err := sender.send(ctx, verifyEmail); err != nil {
+			return len(verifyEmails), err
+		}
+	}
+
+	return len(verifyEmails), nil
+}
+
+// send mails a new secret code and records the outcome, only the hash of the code is stored once the mail is sent
+// only the errors of recording the outcome are returned, a failed mail is retried later with another code
+func (sender *VerifyEmailSender) send(ctx context.Context, verifyEmail db.VerifyEmail) error {
+	secretCode, err := util.NewSecret()
+	if err != nil {
+		return err
+	}
+
+	expiredAt := time.Now().Add(sender.codeDuration)
+
+	err = sender.mailer.Send(ctx, sender.newMessage(verifyEmail, secretCode, expiredAt))
+	if err != nil {
+		return sender.store.MarkVerifyEmailFailed(ctx, db.MarkVerifyEmailFailedParams{
+			LastError:     err.Error(),
+			NextAttemptAt: time.Now().Add(outbox.Backoff(verifyEmail.Attempts)),
+			ID:            verifyEmail.ID,
+		})
+	}
+
+	return sender.store.MarkVerifyEmailSent(ctx, db.MarkVerifyEmailSentParams{
+		SecretCodeHash: util.HashSecret(secretCode),
+		ExpiredAt:      expiredAt,
+		ID:             verifyEmail.ID,
+	})
+}
+
+func (sender *VerifyEmailSender) newMessage(verifyEmail db.VerifyEmail, secretCode string, expiredAt time.Time) Message {
+	query := url.Values{}
+	query.Set("id", strconv.FormatInt(verifyEmail.ID, 10))
+	query.Set("code", secretCode)
+
+	return Message{
+		To:      verifyEmail.Email,
+		Subject: "Verify your Simple Bank email",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nplease verify your email before %s by opening this link:\n\n%s?%s\n\n"+
+				"You can create accounts and make transfers once your email is verified.\n",
+			verifyEmail.Username,
+			expiredAt.UTC().Format(time.RFC3339),
+			sender.verifyURL,
+			query.Encode(),
+		),
+	}
+}
@@ -0,0 +1,144 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testVerifyURL = "http://localhost:8080/api/v1/users/verify_email"
+
+type recordingMailer struct {
+	messages []Message
+	err      error
+}
+
+func (mailer *recordingMailer) Send(ctx context.Context, message Message) error {
+	mailer.messages = append(mailer.messages, message)
+	return mailer.err
+}
+
+// verifyLink returns the query of the verification link in the body of a mail
+func verifyLink(t *testing.T, body string) url.Values {
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, testVerifyURL+"?") {
+			link, err := url.Parse(line)
+			require.NoError(t, err)
+			return link.Query()
+		}
+	}
+
+	require.FailNow(t, "the mail has no verification link", body)
+	return nil
+}
+
+func TestVerifyEmailSender_SendPending(t *testing.T) {
+	verifyEmail := db.VerifyEmail{
+		ID:        1,
+		Username:  util.RandomOwner(),
+		Email:     util.RandomEmail(),
+		Attempts:  2,
+		CreatedAt: time.Now(),
+	}
+
+	testCases := []struct {
+		name          string
+		mailerErr     error
+		buildStubs    func(store *mockdb.MockStore, mailer *recordingMailer)
+		checkMessages func(t *testing.T, messages []Message)
+	}{
+		{
+			name: "Sent",
+			buildStubs: func(store *mockdb.MockStore, mailer *recordingMailer) {
+				store.EXPECT().ClaimVerifyEmails(gomock.Any(), gomock.Any()).Times(1).Return([]db.VerifyEmail{verifyEmail}, nil)
+				store.EXPECT().MarkVerifyEmailFailed(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					MarkVerifyEmailSent(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, args db.MarkVerifyEmailSentParams) error {
+						require.Equal(t, verifyEmail.ID, args.ID)
+						require.WithinDuration(t, time.Now().Add(time.Hour), args.ExpiredAt, time.Second)
+
+						// only the hash of the code in the mail is stored
+						require.Len(t, mailer.messages, 1)
+						query := verifyLink(t, mailer.messages[0].Body)
+						require.Equal(t, strconv.FormatInt(verifyEmail.ID, 10), query.Get("id"))
+						require.Equal(t, util.HashSecret(query.Get("code")), args.SecretCodeHash)
+						return nil
+					})
+			},
+			checkMessages: func(t *testing.T, messages []Message) {
+				require.Len(t, messages, 1)
+				require.Equal(t, verifyEmail.Email, messages[0].To)
+			},
+		},
+		{
+			name:      "MailerFailed",
+			mailerErr: errors.New("mail server is down"),
+			buildStubs: func(store *mockdb.MockStore, mailer *recordingMailer) {
+				store.EXPECT().ClaimVerifyEmails(gomock.Any(), gomock.Any()).Times(1).Return([]db.VerifyEmail{verifyEmail}, nil)
+				store.EXPECT().MarkVerifyEmailSent(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					MarkVerifyEmailFailed(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, args db.MarkVerifyEmailFailedParams) error {
+						require.Equal(t, verifyEmail.ID, args.ID)
+						require.Equal(t, "mail server is down", args.LastError)
+						// the third attempt waits 4 seconds
+						require.WithinDuration(t, time.Now().Add(4*time.Second), args.NextAttemptAt, time.Second)
+						return nil
+					})
+			},
+			checkMessages: func(t *testing.T, messages []Message) {
+				require.Len(t, messages, 1)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+			mailer := &recordingMailer{err: testCase.mailerErr}
+
+			testCase.buildStubs(store, mailer)
+
+			sender := NewVerifyEmailSender(store, mailer, time.Second, time.Hour, testVerifyURL)
+
+			count, err := sender.SendPending(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, 1, count)
+
+			testCase.checkMessages(t, mailer.messages)
+		})
+	}
+}
+
+func TestVerifyEmailSender_ClaimError(t *testing.T) {
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	store := mockdb.NewMockStore(controller)
+	store.EXPECT().ClaimVerifyEmails(gomock.Any(), gomock.Any()).Times(1).Return(nil, errors.New("database is down"))
+
+	mailer := &recordingMailer{}
+	sender := NewVerifyEmailSender(store, mailer, time.Second, time.Hour, testVerifyURL)
+
+	count, err := sender.SendPending(context.Background())
+	require.Error(t, err)
+	require.Zero(t, count)
+	require.Empty(t, mailer.messages)
+}
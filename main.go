@@ -1,53 +1,269 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"github.com/aybarsacar/simplebank/api"
 	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/gapi"
+	"github.com/aybarsacar/simplebank/mail"
+	"github.com/aybarsacar/simplebank/metrics"
+	"github.com/aybarsacar/simplebank/outbox"
+	"github.com/aybarsacar/simplebank/ratelimit"
+	"github.com/aybarsacar/simplebank/token"
+	"github.com/aybarsacar/simplebank/tracing"
 	"github.com/aybarsacar/simplebank/util"
+	"github.com/aybarsacar/simplebank/webhook"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/lib/pq"
-	"log"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 )
 
+// used when SHUTDOWN_TIMEOUT is not configured
+const defaultShutdownTimeout = 30 * time.Second
+
+// used when LOG_LEVEL is not configured
+const defaultLogLevel = zerolog.InfoLevel
+
+// name of the database in the connection pool metrics
+const metricsDBName = "simple_bank"
+
 func main() {
 	config, err := util.LoadConfig(".")
 	if err != nil {
-		log.Fatal("Cannot load config", err)
+		log.Fatal().Err(err).Msg("cannot load config")
+	}
+
+	setupLogger(config.LogLevel)
+
+	shutdownTracing, err := tracing.Setup(config.TracingExporter, config.TracingFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot set up tracing")
 	}
 
 	conn, err := sql.Open(config.DBDriver, config.DBSource)
 
 	if err != nil {
-		log.Fatal("Cannot connect to database", err)
+		log.Fatal().Err(err).Msg("cannot connect to database")
+	}
+
+	if err := metrics.RegisterDB(conn, metricsDBName); err != nil {
+		log.Fatal().Err(err).Msg("cannot register the database metrics")
 	}
 
-	runDatabaseMigration(config.MigrationURL, config.DBSource)
+	migrations := runDatabaseMigration(config.MigrationURL, config.DBSource)
 
-	store := db.NewStore(conn)
-	server, err := api.NewServer(config, store)
+	// the store logs with the logger of the request when there is one, so its lines carry the request id
+	store := db.NewStore(conn, log.Logger)
+
+	// cancelled on SIGINT or SIGTERM, e.g. when a deploy replaces the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// the background workers stop when the context is cancelled
+	var workers sync.WaitGroup
+
+	runOutboxDispatcher(ctx, &workers, config, store)
+	runMailSenders(ctx, &workers, config, store)
+
+	// the servers share the denylist, so a token one of them revokes is rejected by both without waiting for their caches
+	denylist := token.NewDenylist(store)
+
+	grpcServer, err := gapi.NewServer(config, store, denylist)
 	if err != nil {
-		log.Fatal("Cannot start the server", err)
+		log.Fatal().Err(err).Msg("cannot create the gRPC server")
 	}
 
-	err = server.Start(config.ServerAddress)
+	server, err := api.NewServer(config, store, denylist)
 	if err != nil {
-		log.Fatal("Cannot start the server", err)
+		log.Fatal().Err(err).Msg("cannot create the server")
+	}
+
+	// the HTTP and the gRPC api take the tokens of a client from the same buckets
+	rateLimiter := ratelimit.NewMemoryStore()
+	server.SetRateLimitStore(rateLimiter)
+	grpcServer.SetRateLimitStore(rateLimiter)
+
+	// the readiness probe reports the migration version, it only passes after the migrations succeeded
+	server.MarkReady(migrations)
+
+	// the currencies enabled or disabled on the other instances are picked up without a restart
+	workers.Add(1)
+
+	go func() {
+		defer workers.Done()
+		server.RefreshCurrencies(ctx)
+	}()
+
+	// the gRPC api for the internal services runs next to the HTTP server
+	go func() {
+		if err := grpcServer.Start(config.GRPCServerAddress); err != nil {
+			log.Fatal().Err(err).Msg("cannot start the gRPC server")
+		}
+	}()
+
+	go func() {
+		if err := server.Start(config.ServerAddress); err != nil {
+			log.Fatal().Err(err).Msg("cannot start the server")
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+
+	log.Info().Msg("shutting down, waiting for the active requests to finish")
+
+	shutdown(config, server, grpcServer, &workers, conn, shutdownTracing)
+
+	if sourceErr, dbErr := migrations.Close(); sourceErr != nil || dbErr != nil {
+		log.Error().AnErr("source_error", sourceErr).AnErr("database_error", dbErr).Msg("cannot close the migrations")
+	}
+}
+
+// setupLogger makes the global logger write JSON lines with a timestamp to stdout, from the given level up
+func setupLogger(level string) {
+	logLevel, err := zerolog.ParseLevel(level)
+	if err != nil || level == "" {
+		logLevel = defaultLogLevel
+	}
+
+	zerolog.SetGlobalLevel(logLevel)
+	log.Logger = zerolog.New(os.Stdout).With().Timestamp().Logger()
+
+	if err != nil {
+		log.Warn().Err(err).Str("level", logLevel.String()).Msg("invalid log level, using the default")
+	}
+}
+
+// shutdown stops new traffic, waits for the active requests and the background workers,
+// and closes the database once nothing uses it anymore, the spans of the last requests are flushed at the end
+func shutdown(
+	config util.Config,
+	server *api.Server,
+	grpcServer *gapi.Server,
+	workers *sync.WaitGroup,
+	conn *sql.DB,
+	shutdownTracing func(context.Context) error,
+) {
+	timeout := config.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
 	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var servers sync.WaitGroup
+	servers.Add(2)
+
+	go func() {
+		defer servers.Done()
+
+		if err := server.Shutdown(ctx); err != nil {
+			log.Error().Err(err).Msg("cannot shut down the server gracefully")
+		}
+	}()
+
+	go func() {
+		defer servers.Done()
+
+		if err := grpcServer.Shutdown(ctx); err != nil {
+			log.Error().Err(err).Msg("cannot shut down the gRPC server gracefully")
+		}
+	}()
+
+	servers.Wait()
+	workers.Wait()
+
+	if err := conn.Close(); err != nil {
+		log.Error().Err(err).Msg("cannot close the database")
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		log.Error().Err(err).Msg("cannot flush the traces")
+	}
+
+	log.Info().Msg("shutdown complete")
 }
 
-func runDatabaseMigration(migrationURL string, dbSource string) {
+// runDatabaseMigration migrates the database to the latest version
+// the returned instance is kept open, so the readiness probe can report the current version
+func runDatabaseMigration(migrationURL string, dbSource string) *migrate.Migrate {
 	m, err := migrate.New(migrationURL, dbSource)
 	if err != nil {
-		log.Fatal("cannot create new migrate instance", err)
-		return
+		log.Fatal().Err(err).Msg("cannot create new migrate instance")
 	}
 
-	if err := m.Up(); err != nil {
-		log.Fatal("failed to run migrate up", err)
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		log.Fatal().Err(err).Msg("failed to run migrate up")
 	}
 
-	log.Println("db migration successful")
+	log.Info().Msg("db migration successful")
+
+	return m
+}
+
+// runOutboxDispatcher delivers the events of the outbox to the configured sinks in the background
+// the events always go to the webhook fanout, which creates the deliveries of the user subscriptions
+// that the webhook sender then posts
+// both stop when the context is cancelled, an event or delivery they had claimed is sent again after its lease
+func runOutboxDispatcher(ctx context.Context, workers *sync.WaitGroup, config util.Config, store db.Store) {
+	sinks := []outbox.Sink{webhook.NewFanout(store)}
+
+	if config.OutboxWebhookURL != "" {
+		sinks = append(sinks, outbox.NewWebhookSink(config.OutboxWebhookURL))
+	}
+
+	if config.OutboxFile != "" {
+		sinks = append(sinks, outbox.NewFileSink(config.OutboxFile))
+	}
+
+	dispatcher := outbox.NewDispatcher(store, config.OutboxPollInterval, sinks...)
+	sender := webhook.NewSender(store, config.OutboxPollInterval)
+
+	workers.Add(2)
+
+	go func() {
+		defer workers.Done()
+		dispatcher.Start(ctx)
+	}()
+
+	go func() {
+		defer workers.Done()
+		sender.Start(ctx)
+	}()
+}
+
+// runMailSenders mails the secret codes of the email verifications of new users
+// and the tokens of the password resets in the background
+// they stop when the context is cancelled, a mail they had claimed is sent again after its lease
+func runMailSenders(ctx context.Context, workers *sync.WaitGroup, config util.Config, store db.Store) {
+	mailer, err := mail.NewMailer(config.Mailer, config.MailerFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot create the mailer")
+	}
+
+	verifyEmailSender := mail.NewVerifyEmailSender(store, mailer, config.OutboxPollInterval, config.VerifyEmailDuration, config.VerifyEmailURL)
+	passwordResetSender := mail.NewPasswordResetSender(store, mailer, config.OutboxPollInterval)
+
+	workers.Add(2)
+
+	go func() {
+		defer workers.Done()
+		verifyEmailSender.Start(ctx)
+	}()
+
+	go func() {
+		defer workers.Done()
+		passwordResetSender.Start(ctx)
+	}()
 }
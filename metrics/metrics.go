@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"database/sql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const namespace = "simple_bank"
+
+// kinds of transfers
+const (
+	TransferKindSameCurrency  = "same_currency"
+	TransferKindCrossCurrency = "cross_currency"
+)
+
+// outcomes of transfers, the failures are labeled with the error code of the api
+const (
+	TransferOutcomeSuccess  = "success"
+	TransferOutcomeReplayed = "replayed"
+	TransferOutcomeError    = "error"
+)
+
+// reasons of token verification failures
+const (
+	TokenFailureExpired = "expired"
+	TokenFailureInvalid = "invalid"
+)
+
+// metrics doesn't import the other packages of the service, so all of them can record metrics
+// the metrics are registered in the default registry, which also holds the go runtime and process metrics
+var (
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of the HTTP requests by method, route template and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	transfers = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_total",
+		Help:      "Number of transfers by kind and outcome.",
+	}, []string{"kind", "outcome"})
+
+	transferAmount = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "transfer_amount",
+		Help:      "Amounts of the transfers in minor units of the currency of the from account, by kind, outcome and currency.",
+		// from 1.00 to 1,000,000.00 in a currency with two minor units,
+		// the amounts of different currencies aren't comparable, so they are only aggregated by currency
+		Buckets: prometheus.ExponentialBuckets(100, 10, 7),
+	}, []string{"kind", "outcome", "currency"})
+
+	tokenVerificationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_verification_failures_total",
+		Help:      "Number of access tokens that failed verification by reason.",
+	}, []string{"reason"})
+)
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// RegisterDB exports the sql.DBStats of the connection pool, e.g. the open and in use connections
+func RegisterDB(db *sql.DB, name string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveHTTPRequest records a request, the route is the template the request matched, e.g. /api/v1/accounts/:id,
+// so the number of series doesn't grow with the ids in the paths
+func ObserveHTTPRequest(method string, route string, status int, duration time.Duration) {
+	httpRequestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// ObserveTransfer records the outcome and the amount of a transfer, the amount is in minor units of the currency
+func ObserveTransfer(kind string, outcome string, currency string, amount int64) {
+	transfers.WithLabelValues(kind, outcome).Inc()
+	transferAmount.WithLabelValues(kind, outcome, currency).Observe(float64(amount))
+}
+
+// TokenVerificationFailed records an access token that failed verification,
+// expired tells a token.ErrExpiredToken from a token.ErrInvalidToken
+func TokenVerificationFailed(expired bool) {
+	reason := TokenFailureInvalid
+	if expired {
+		reason = TokenFailureExpired
+	}
+
+	tokenVerificationFailures.WithLabelValues(reason).Inc()
+}
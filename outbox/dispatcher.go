@@ -0,0 +1,137 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/worker"
+	"time"
+)
+
+const (
+	defaultPollInterval = time.Second
+	// number of events claimed at once
+	batchSize = 100
+	// a claimed event is claimed again if it is not delivered or failed within the lease, e.g. the process stopped
+	// the deliveries of a batch are cut off when the lease runs out, so an event is never sent by two dispatchers at once
+	claimLease = time.Minute
+	minBackoff = time.Second
+	maxBackoff = time.Hour
+)
+
+// Event is the message delivered to the sinks
+// it is delivered at least once, so the sinks should ignore an id they already handled
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Sink receives the events of the outbox, e.g. a webhook or a file
+type Sink interface {
+	Deliver(ctx context.Context, event Event) error
+}
+
+// Dispatcher delivers the events written to the outbox by the store transactions
+// an event that fails is retried with an exponential backoff until every sink accepts it
+type Dispatcher struct {
+	store        db.Store
+	sinks        []Sink
+	pollInterval time.Duration
+	lease        time.Duration
+}
+
+// NewDispatcher creates a dispatcher that checks the outbox for new events every poll interval
+func NewDispatcher(store db.Store, pollInterval time.Duration, sinks ...Sink) *Dispatcher {
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+
+	return &Dispatcher{
+		store:        store,
+		sinks:        sinks,
+		pollInterval: pollInterval,
+		lease:        claimLease,
+	}
+}
+
+// Start delivers the events until the context is cancelled
+func (dispatcher *Dispatcher) Start(ctx context.Context) {
+	worker.Poll(ctx, dispatcher.pollInterval, batchSize, dispatcher.DispatchPending, "cannot dispatch outbox events")
+}
+
+// DispatchPending delivers one batch of the events that are due and returns how many were claimed
+func (dispatcher *Dispatcher) DispatchPending(ctx context.Context) (int, error) {
+	leaseUntil := time.Now().Add(dispatcher.lease)
+
+	events, err := dispatcher.store.ClaimOutboxEvents(ctx, db.ClaimOutboxEventsParams{
+		LeaseUntil: leaseUntil,
+		BatchSize:  batchSize,
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	// the sinks only get the events while they are leased to this dispatcher
+	leaseCtx, cancel := context.WithDeadline(ctx, leaseUntil)
+	defer cancel()
+
+	for _, event := range events {
+		// the events left when the lease runs out can be claimed again right away
+		if leaseCtx.Err() != nil {
+			break
+		}
+
+		if err := dispatcher.deliver(ctx, leaseCtx, event); err != nil {
+			return len(events), err
+		}
+	}
+
+	return len(events), nil
+}
+
+// deliver sends the event to every sink with the lease context and records the outcome
+// only the errors of recording the outcome are returned, a failed delivery is retried later
+func (dispatcher *Dispatcher) deliver(ctx context.Context, leaseCtx context.Context, outboxEvent db.Outbox) error {
+	event := Event{
+		ID:        outboxEvent.ID,
+		Type:      outboxEvent.EventType,
+		Payload:   outboxEvent.Payload,
+		CreatedAt: outboxEvent.CreatedAt,
+	}
+
+	for _, sink := range dispatcher.sinks {
+		if err := sink.Deliver(leaseCtx, event); err != nil {
+			// a delivery cut off by the end of the lease is not an attempt, the event is claimed again
+			if leaseCtx.Err() != nil {
+				return nil
+			}
+
+			// the sinks that already accepted the event get it again on the next attempt
+			return dispatcher.store.MarkOutboxEventFailed(ctx, db.MarkOutboxEventFailedParams{
+				LastError:     err.Error(),
+				NextAttemptAt: time.Now().Add(Backoff(outboxEvent.Attempts)),
+				ID:            outboxEvent.ID,
+			})
+		}
+	}
+
+	return dispatcher.store.MarkOutboxEventDelivered(ctx, outboxEvent.ID)
+}
+
+// Backoff returns how long to wait before the next attempt, after the given number of failed attempts
+// it doubles with every attempt, from a second up to an hour
+func Backoff(attempts int32) time.Duration {
+	backoff := minBackoff
+	for i := int32(0); i < attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+
+	return backoff
+}
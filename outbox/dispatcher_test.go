@@ -0,0 +1,139 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type recordingSink struct {
+	events []Event
+	err    error
+}
+
+func (sink *recordingSink) Deliver(ctx context.Context, event Event) error {
+	sink.events = append(sink.events, event)
+	return sink.err
+}
+
+func TestDispatcher_DispatchPending(t *testing.T) {
+	event := db.Outbox{
+		ID:        1,
+		EventType: db.EventTransferCompleted,
+		Payload:   json.RawMessage(`{"transfer":{"id":1}}`),
+		Attempts:  2,
+		CreatedAt: time.Now(),
+	}
+
+	testCases := []struct {
+		name       string
+		sinkErr    error
+		buildStubs func(store *mockdb.MockStore)
+	}{
+		{
+			name: "Delivered",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ClaimOutboxEvents(gomock.Any(), gomock.Any()).Times(1).Return([]db.Outbox{event}, nil)
+				store.EXPECT().MarkOutboxEventDelivered(gomock.Any(), gomock.Eq(event.ID)).Times(1).Return(nil)
+				store.EXPECT().MarkOutboxEventFailed(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:    "SinkFailed",
+			sinkErr: errors.New("sink is down"),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ClaimOutboxEvents(gomock.Any(), gomock.Any()).Times(1).Return([]db.Outbox{event}, nil)
+				store.EXPECT().MarkOutboxEventDelivered(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					MarkOutboxEventFailed(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, args db.MarkOutboxEventFailedParams) error {
+						require.Equal(t, event.ID, args.ID)
+						require.Equal(t, "sink is down", args.LastError)
+						// the third attempt waits 4 seconds
+						require.WithinDuration(t, time.Now().Add(4*time.Second), args.NextAttemptAt, time.Second)
+						return nil
+					})
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+
+			testCase.buildStubs(store)
+
+			sink := &recordingSink{err: testCase.sinkErr}
+			dispatcher := NewDispatcher(store, time.Second, sink)
+
+			count, err := dispatcher.DispatchPending(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, 1, count)
+
+			require.Len(t, sink.events, 1)
+			require.Equal(t, event.ID, sink.events[0].ID)
+			require.Equal(t, event.EventType, sink.events[0].Type)
+			require.JSONEq(t, string(event.Payload), string(sink.events[0].Payload))
+		})
+	}
+}
+
+// blockingSink holds the deliveries until their context is done
+type blockingSink struct {
+	events []Event
+}
+
+func (sink *blockingSink) Deliver(ctx context.Context, event Event) error {
+	sink.events = append(sink.events, event)
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestDispatcher_DispatchPendingLeaseExpired(t *testing.T) {
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	store := mockdb.NewMockStore(controller)
+
+	events := []db.Outbox{
+		{ID: 1, EventType: db.EventTransferCompleted, Payload: json.RawMessage(`{}`)},
+		{ID: 2, EventType: db.EventTransferCompleted, Payload: json.RawMessage(`{}`)},
+	}
+
+	store.EXPECT().ClaimOutboxEvents(gomock.Any(), gomock.Any()).Times(1).Return(events, nil)
+	store.EXPECT().MarkOutboxEventDelivered(gomock.Any(), gomock.Any()).Times(0)
+	store.EXPECT().MarkOutboxEventFailed(gomock.Any(), gomock.Any()).Times(0)
+
+	sink := &blockingSink{}
+	dispatcher := NewDispatcher(store, time.Second, sink)
+	dispatcher.lease = 50 * time.Millisecond
+
+	count, err := dispatcher.DispatchPending(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, len(events), count)
+
+	// the delivery of the first event used the whole lease, the second one is left for the next claim
+	require.Len(t, sink.events, 1)
+	require.Equal(t, events[0].ID, sink.events[0].ID)
+}
+
+func TestBackoff(t *testing.T) {
+	require.Equal(t, time.Second, Backoff(0))
+	require.Equal(t, 2*time.Second, Backoff(1))
+	require.Equal(t, 8*time.Second, Backoff(3))
+	require.Equal(t, time.Hour, Backoff(20))
+	require.Equal(t, time.Hour, Backoff(1000))
+}
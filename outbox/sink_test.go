@@ -0,0 +1,77 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func randomEvent(id int64) Event {
+	return Event{
+		ID:        id,
+		Type:      "transfer.completed",
+		Payload:   json.RawMessage(`{"transfer":{"id":1}}`),
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	var received Event
+	var eventID string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		eventID = r.Header.Get(eventIDHeaderKey)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	event := randomEvent(42)
+
+	err := NewWebhookSink(server.URL).Deliver(context.Background(), event)
+	require.NoError(t, err)
+
+	require.Equal(t, "42", eventID)
+	require.Equal(t, event.ID, received.ID)
+	require.Equal(t, event.Type, received.Type)
+	require.JSONEq(t, string(event.Payload), string(received.Payload))
+}
+
+func TestWebhookSink_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	err := NewWebhookSink(server.URL).Deliver(context.Background(), randomEvent(1))
+	require.Error(t, err)
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink := NewFileSink(path)
+
+	require.NoError(t, sink.Deliver(context.Background(), randomEvent(1)))
+	require.NoError(t, sink.Deliver(context.Background(), randomEvent(2)))
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var ids []int64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		ids = append(ids, event.ID)
+	}
+
+	require.Equal(t, []int64{1, 2}, ids)
+}
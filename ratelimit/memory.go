@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// upper bound of the buckets kept in memory, so a flood of new clients can't exhaust the memory
+const maxMemoryBuckets = 100_000
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	// fullAt is when the bucket has refilled, from then on it is the same as a new one
+	fullAt time.Time
+}
+
+// MemoryStore keeps the buckets in the memory of the process
+// every instance of the service has its own buckets
+type MemoryStore struct {
+	mutex   sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Take takes a token from the bucket of the key, the bucket refills at Requests tokens per Period
+func (store *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	now := store.now()
+	capacity := float64(limit.Requests)
+	// the time it takes to refill one token
+	interval := limit.Period / time.Duration(limit.Requests)
+
+	b, ok := store.buckets[key]
+	if !ok {
+		if len(store.buckets) >= maxMemoryBuckets {
+			store.evict(now)
+		}
+
+		b = &bucket{tokens: capacity, updatedAt: now}
+		store.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.updatedAt)
+	b.tokens = math.Min(capacity, b.tokens+elapsed.Seconds()/interval.Seconds())
+	b.updatedAt = now
+
+	result := Result{Limit: limit.Requests}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(interval))
+	}
+
+	result.Remaining = int(b.tokens)
+	result.ResetAfter = time.Duration((capacity - b.tokens) * float64(interval))
+	b.fullAt = now.Add(result.ResetAfter)
+
+	return result, nil
+}
+
+// drops the buckets that have refilled, or everything if the store is still full
+// must be called with the mutex locked
+func (store *MemoryStore) evict(now time.Time) {
+	for key, b := range store.buckets {
+		if !now.Before(b.fullAt) {
+			delete(store.buckets, key)
+		}
+	}
+
+	if len(store.buckets) >= maxMemoryBuckets {
+		store.buckets = make(map[string]*bucket)
+	}
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Now()
+
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	limit := Limit{Requests: 3, Period: 3 * time.Second}
+
+	// a new client can use the whole bucket at once
+	for i := 2; i >= 0; i-- {
+		result, err := store.Take(context.Background(), "client", limit)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		require.Equal(t, 3, result.Limit)
+		require.Equal(t, i, result.Remaining)
+	}
+
+	result, err := store.Take(context.Background(), "client", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining)
+	require.Equal(t, time.Second, result.RetryAfter)
+	require.Equal(t, 3*time.Second, result.ResetAfter)
+
+	// the other clients have their own buckets
+	result, err = store.Take(context.Background(), "other client", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	// one token is refilled per second
+	now = now.Add(time.Second)
+
+	result, err = store.Take(context.Background(), "client", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	result, err = store.Take(context.Background(), "client", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+}
+
+func TestMemoryStoreEvict(t *testing.T) {
+	now := time.Now()
+
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	limit := Limit{Requests: 1, Period: time.Minute}
+
+	_, err := store.Take(context.Background(), "idle client", limit)
+	require.NoError(t, err)
+
+	now = now.Add(time.Minute)
+
+	_, err = store.Take(context.Background(), "active client", limit)
+	require.NoError(t, err)
+
+	store.evict(now)
+
+	// only the bucket that has refilled is dropped, the client can't reset its bucket this way
+	require.NotContains(t, store.buckets, "idle client")
+	require.Contains(t, store.buckets, "active client")
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit lets Requests requests through per Period, e.g. 5 per minute
+// the bucket holds up to Requests tokens, so a client that was idle can send them in a burst
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Result is the state of a bucket after a request took a token from it
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long a rejected client has to wait for the next token
+	RetryAfter time.Duration
+	// ResetAfter is how long it takes until the bucket is full again
+	ResetAfter time.Duration
+}
+
+// Store keeps the buckets of the clients, the in-memory store is the default
+// a shared store, e.g. in Redis, lets several instances of the service enforce the same limits
+type Store interface {
+	// Take takes a token from the bucket of the key for a request
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Rules are the limits by route, keyed by the method and the route template, e.g. "POST /api/v1/users/login"
+type Rules map[string]Limit
+
+// Limit returns the limit of a route, false when the route is not limited
+func (rules Rules) Limit(method string, route string) (Limit, bool) {
+	limit, ok := rules[ruleKey(method, route)]
+	return limit, ok
+}
+
+func ruleKey(method string, route string) string {
+	return method + " " + route
+}
+
+// Key is the key of the bucket of a client on a route, the authenticated clients are counted by username and the others by IP
+// the HTTP and the gRPC api use the same keys, so a client can't get around a limit by switching the api
+func Key(method string, route string, clientIP string, username string) string {
+	client := "ip:" + clientIP
+	if username != "" {
+		client = "user:" + username
+	}
+
+	return ruleKey(method, route) + " " + client
+}
+
+// ParseRules parses the limits of the config, each one is "<method>:<route>=<requests>/<period>",
+// e.g. "POST:/api/v1/users/login=5/1m" lets 5 login requests through per minute
+func ParseRules(rules []string) (Rules, error) {
+	parsed := Rules{}
+
+	for _, rule := range rules {
+		route, limit, ok := strings.Cut(rule, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit %q: missing the limit", rule)
+		}
+
+		method, path, ok := strings.Cut(route, ":")
+		if !ok || method == "" || !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("invalid rate limit %q: the route must be <method>:<route>", rule)
+		}
+
+		requests, period, ok := strings.Cut(limit, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit %q: the limit must be <requests>/<period>", rule)
+		}
+
+		count, err := strconv.Atoi(requests)
+		if err != nil || count <= 0 {
+			return nil, fmt.Errorf("invalid rate limit %q: the number of requests must be positive", rule)
+		}
+
+		duration, err := time.ParseDuration(period)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid rate limit %q: the period must be a positive duration", rule)
+		}
+
+		parsed[ruleKey(strings.ToUpper(method), path)] = Limit{Requests: count, Period: duration}
+	}
+
+	return parsed, nil
+}
+
+// SetHeaders writes the X-RateLimit-* headers of the result, and the Retry-After header when the request was rejected
+// the durations are rounded up to whole seconds, so clients don't retry too early
+func SetHeaders(header http.Header, result Result) {
+	header.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	header.Set("X-RateLimit-Reset", strconv.Itoa(seconds(result.ResetAfter)))
+
+	if !result.Allowed {
+		header.Set("Retry-After", strconv.Itoa(seconds(result.RetryAfter)))
+	}
+}
+
+func seconds(duration time.Duration) int {
+	return int((duration + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules([]string{"post:/api/v1/users/login=5/1m", "POST:/api/v1/transfers=30/1h"})
+	require.NoError(t, err)
+
+	limit, ok := rules.Limit(http.MethodPost, "/api/v1/users/login")
+	require.True(t, ok)
+	require.Equal(t, Limit{Requests: 5, Period: time.Minute}, limit)
+
+	limit, ok = rules.Limit(http.MethodPost, "/api/v1/transfers")
+	require.True(t, ok)
+	require.Equal(t, Limit{Requests: 30, Period: time.Hour}, limit)
+
+	_, ok = rules.Limit(http.MethodGet, "/api/v1/transfers")
+	require.False(t, ok)
+
+	for _, rule := range []string{
+		"/api/v1/users/login=5/1m",
+		"POST:api/v1/users/login=5/1m",
+		"POST:/api/v1/users/login",
+		"POST:/api/v1/users/login=5",
+		"POST:/api/v1/users/login=0/1m",
+		"POST:/api/v1/users/login=5/minute",
+		"POST:/api/v1/users/login=5/-1m",
+	} {
+		_, err := ParseRules([]string{rule})
+		require.Error(t, err, rule)
+	}
+}
+
+func TestKey(t *testing.T) {
+	require.Equal(t, "POST /api/v1/users/login ip:10.0.0.1", Key("POST", "/api/v1/users/login", "10.0.0.1", ""))
+
+	// the authenticated clients are counted by username, whatever their IP
+	require.Equal(t, "GET /api/v1/accounts/:id user:alice", Key("GET", "/api/v1/accounts/:id", "10.0.0.1", "alice"))
+}
+
+func TestSetHeaders(t *testing.T) {
+	header := http.Header{}
+
+	SetHeaders(header, Result{Allowed: true, Limit: 5, Remaining: 4, ResetAfter: 12 * time.Second})
+
+	require.Equal(t, "5", header.Get("X-RateLimit-Limit"))
+	require.Equal(t, "4", header.Get("X-RateLimit-Remaining"))
+	require.Equal(t, "12", header.Get("X-RateLimit-Reset"))
+	require.Empty(t, header.Get("Retry-After"))
+
+	SetHeaders(header, Result{Limit: 5, RetryAfter: 1500 * time.Millisecond, ResetAfter: time.Minute})
+
+	require.Equal(t, "0", header.Get("X-RateLimit-Remaining"))
+	require.Equal(t, "2", header.Get("Retry-After"))
+}
@@ -0,0 +1,180 @@
+package token
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+const (
+	// how long a token that is not revoked is trusted before the database is asked again
+	denylistCacheDuration = 30 * time.Second
+	// upper bound of the cache, so it stays small
+	maxDenylistCacheEntries = 10_000
+)
+
+type denylistEntry struct {
+	revoked    bool
+	validUntil time.Time
+}
+
+type credentialsEntry struct {
+	changedAt  time.Time
+	validUntil time.Time
+}
+
+// RevocationStore looks up the tokens that were revoked before they expired, e.g. db.Store
+// the tokens issued before the password or the role of their user changed are revoked too
+type RevocationStore interface {
+	IsTokenRevoked(ctx context.Context, id uuid.UUID) (bool, error)
+	GetUserCredentialsChangedAt(ctx context.Context, username string) (time.Time, error)
+}
+
+// Denylist tells if a token was revoked before it expired
+// answers are cached in memory, so most requests don't hit the database
+type Denylist struct {
+	store       RevocationStore
+	mutex       sync.RWMutex
+	entries     map[uuid.UUID]denylistEntry
+	credentials map[string]credentialsEntry
+}
+
+// NewDenylist creates a denylist that caches the answers of the store
+func NewDenylist(store RevocationStore) *Denylist {
+	return &Denylist{
+		store:       store,
+		entries:     make(map[uuid.UUID]denylistEntry),
+		credentials: make(map[string]credentialsEntry),
+	}
+}
+
+// IsRevoked checks if the token of the payload is on the denylist
+// or was issued before the password or the role of its user changed
+func (denylist *Denylist) IsRevoked(ctx context.Context, payload *Payload) (bool, error) {
+	revoked, err := denylist.isTokenRevoked(ctx, payload)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	credentialsChangedAt, err := denylist.credentialsChangedAt(ctx, payload.Username)
+	if err != nil {
+		return false, err
+	}
+
+	return payload.IssuedAt.Before(credentialsChangedAt), nil
+}
+
+func (denylist *Denylist) isTokenRevoked(ctx context.Context, payload *Payload) (bool, error) {
+	now := time.Now()
+
+	denylist.mutex.RLock()
+	entry, ok := denylist.entries[payload.ID]
+	denylist.mutex.RUnlock()
+
+	if ok && now.Before(entry.validUntil) {
+		return entry.revoked, nil
+	}
+
+	revoked, err := denylist.store.IsTokenRevoked(ctx, payload.ID)
+	if err != nil {
+		return false, err
+	}
+
+	// a revoked token stays revoked, so it is cached until the token expires
+	validUntil := now.Add(denylistCacheDuration)
+	if revoked {
+		validUntil = payload.ExpiresAt
+	}
+
+	denylist.set(payload.ID, denylistEntry{revoked: revoked, validUntil: validUntil})
+
+	return revoked, nil
+}
+
+func (denylist *Denylist) credentialsChangedAt(ctx context.Context, username string) (time.Time, error) {
+	now := time.Now()
+
+	denylist.mutex.RLock()
+	entry, ok := denylist.credentials[username]
+	denylist.mutex.RUnlock()
+
+	if ok && now.Before(entry.validUntil) {
+		return entry.changedAt, nil
+	}
+
+	changedAt, err := denylist.store.GetUserCredentialsChangedAt(ctx, username)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	denylist.setCredentials(username, credentialsEntry{changedAt: changedAt, validUntil: now.Add(denylistCacheDuration)})
+
+	return changedAt, nil
+}
+
+// CredentialsChanged revokes the tokens of the user issued before changedAt in the cache,
+// call it after the password or the role is changed in the database
+// the other instances of the service see the change once their cached answer expires
+func (denylist *Denylist) CredentialsChanged(username string, changedAt time.Time) {
+	denylist.setCredentials(username, credentialsEntry{changedAt: changedAt, validUntil: time.Now().Add(denylistCacheDuration)})
+}
+
+// Revoked marks a token as revoked in the cache, call it after the token is added to the database
+func (denylist *Denylist) Revoked(tokenID uuid.UUID, expiresAt time.Time) {
+	denylist.set(tokenID, denylistEntry{revoked: true, validUntil: expiresAt})
+}
+
+func (denylist *Denylist) set(tokenID uuid.UUID, entry denylistEntry) {
+	denylist.mutex.Lock()
+	defer denylist.mutex.Unlock()
+
+	if len(denylist.entries) >= maxDenylistCacheEntries {
+		denylist.evict()
+	}
+
+	denylist.entries[tokenID] = entry
+}
+
+func (denylist *Denylist) setCredentials(username string, entry credentialsEntry) {
+	denylist.mutex.Lock()
+	defer denylist.mutex.Unlock()
+
+	if len(denylist.credentials) >= maxDenylistCacheEntries {
+		denylist.evictCredentials()
+	}
+
+	denylist.credentials[username] = entry
+}
+
+// drops the entries that are no longer valid, or everything if the cache is still full
+// must be called with the mutex locked
+func (denylist *Denylist) evict() {
+	now := time.Now()
+
+	for tokenID, entry := range denylist.entries {
+		if !now.Before(entry.validUntil) {
+			delete(denylist.entries, tokenID)
+		}
+	}
+
+	if len(denylist.entries) >= maxDenylistCacheEntries {
+		denylist.entries = make(map[uuid.UUID]denylistEntry)
+	}
+}
+
+// evictCredentials is evict for the password and role changes of the users
+// must be called with the mutex locked
+func (denylist *Denylist) evictCredentials() {
+	now := time.Now()
+
+	for username, entry := range denylist.credentials {
+		if !now.Before(entry.validUntil) {
+			delete(denylist.credentials, username)
+		}
+	}
+
+	if len(denylist.credentials) >= maxDenylistCacheEntries {
+		denylist.credentials = make(map[string]credentialsEntry)
+	}
+}
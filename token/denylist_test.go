@@ -0,0 +1,93 @@
+package token
+
+import (
+	"context"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	"github.com/aybarsacar/simplebank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestDenylistCache(t *testing.T) {
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	store := mockdb.NewMockStore(controller)
+	denylist := NewDenylist(store)
+
+	payload, err := NewPayload(util.RandomOwner(), util.CustomerRole, time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	// only the first lookup goes to the database
+	store.EXPECT().
+		IsTokenRevoked(gomock.Any(), gomock.Eq(payload.ID)).
+		Times(1).
+		Return(false, nil)
+
+	store.EXPECT().
+		GetUserCredentialsChangedAt(gomock.Any(), gomock.Eq(payload.Username)).
+		Times(1).
+		Return(time.Time{}, nil)
+
+	for i := 0; i < 3; i++ {
+		revoked, err := denylist.IsRevoked(context.Background(), payload)
+		require.NoError(t, err)
+		require.False(t, revoked)
+	}
+
+	// revoking the token locally takes effect immediately
+	denylist.Revoked(payload.ID, payload.ExpiresAt)
+
+	revoked, err := denylist.IsRevoked(context.Background(), payload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func TestDenylistCredentialsChanged(t *testing.T) {
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	store := mockdb.NewMockStore(controller)
+	denylist := NewDenylist(store)
+
+	username := util.RandomOwner()
+
+	oldPayload, err := NewPayload(username, util.CustomerRole, time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+
+	store.EXPECT().
+		IsTokenRevoked(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(false, nil)
+
+	// the password was changed after the old token was issued
+	changedAt := oldPayload.IssuedAt.Add(time.Second)
+
+	store.EXPECT().
+		GetUserCredentialsChangedAt(gomock.Any(), gomock.Eq(username)).
+		Times(1).
+		Return(changedAt, nil)
+
+	revoked, err := denylist.IsRevoked(context.Background(), oldPayload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	newPayload, err := NewPayload(username, util.CustomerRole, time.Minute, TokenTypeAccessToken)
+	require.NoError(t, err)
+	newPayload.IssuedAt = changedAt.Add(time.Second)
+
+	revoked, err = denylist.IsRevoked(context.Background(), newPayload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	// a local password change takes effect immediately, even for the tokens issued after the cached one
+	denylist.CredentialsChanged(username, newPayload.IssuedAt.Add(time.Second))
+
+	revoked, err = denylist.IsRevoked(context.Background(), newPayload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
@@ -24,22 +24,23 @@ func NewJWTMaker(secretKey string) (Maker, error) {
 
 // implement the interface
 
-// CreateToken creates a new token for a specific username and duration
-func (maker *JWTMaker) CreateToken(username string, duration time.Duration) (string, error) {
+// CreateToken creates a new token of a type for a specific username, role and duration
+func (maker *JWTMaker) CreateToken(username string, role string, duration time.Duration, tokenType TokenType) (string, *Payload, error) {
 
-	payload, err := NewPayload(username, duration)
+	payload, err := NewPayload(username, role, duration, tokenType)
 	if err != nil {
-		return "", err
+		return "", payload, err
 	}
 
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
 
-	return jwtToken.SignedString([]byte(maker.secretKey))
+	token, err := jwtToken.SignedString([]byte(maker.secretKey))
+	return token, payload, err
 }
 
-// VerifyToken checks if the token is valid or not
+// VerifyToken checks if the token is valid and of the expected type or not
 // if valid returns the payload stored inside the body of token
-func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
+func (maker *JWTMaker) VerifyToken(token string, tokenType TokenType) (*Payload, error) {
 
 	// parse the token
 	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, func(token *jwt.Token) (interface{}, error) {
@@ -73,6 +74,11 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 		return nil, ErrInvalidToken
 	}
 
+	err = payload.verifyType(tokenType)
+	if err != nil {
+		return nil, err
+	}
+
 	// payload got successfully
 	return payload, nil
 }
@@ -4,10 +4,11 @@ import "time"
 
 // Maker is an interface for managing tokens
 type Maker interface {
-	// CreateToken creates a new token for a specific username and duration
-	CreateToken(username string, duration time.Duration) (string, error)
+	// CreateToken creates a new token of a type for a specific username, role and duration
+	// it also returns the payload, so the caller can keep track of the token ID and expiry
+	CreateToken(username string, role string, duration time.Duration, tokenType TokenType) (string, *Payload, error)
 
-	// VerifyToken checks if the token is valid or not
+	// VerifyToken checks if the token is valid and of the expected type or not
 	// if valid returns the payload stored inside the body of token
-	VerifyToken(token string) (*Payload, error)
+	VerifyToken(token string, tokenType TokenType) (*Payload, error)
 }
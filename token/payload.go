@@ -12,16 +12,27 @@ var (
 	ErrInvalidToken = errors.New("token is invalid")
 )
 
+// TokenType tells the access tokens, which authenticate the requests, apart from the refresh tokens,
+// which can only be used to renew the access tokens and to log out
+type TokenType byte
+
+const (
+	TokenTypeAccessToken  TokenType = 1
+	TokenTypeRefreshToken TokenType = 2
+)
+
 // Payload contains the payload data of the token
 type Payload struct {
 	ID        uuid.UUID `json:"id"`
+	Type      TokenType `json:"token_type"`
 	Username  string    `json:"username"`
+	Role      string    `json:"role"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiresAt time.Time `json:"expires_at"`
 }
 
-// NewPayload creates a new token payload with a specific username and duration
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+// NewPayload creates a new token payload of a type with a specific username, role and duration
+func NewPayload(username string, role string, duration time.Duration, tokenType TokenType) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
@@ -29,7 +40,9 @@ func NewPayload(username string, duration time.Duration) (*Payload, error) {
 
 	payload := Payload{
 		ID:        tokenID,
+		Type:      tokenType,
 		Username:  username,
+		Role:      role,
 		IssuedAt:  time.Now(),
 		ExpiresAt: time.Now().Add(duration),
 	}
@@ -46,3 +59,12 @@ func (payload *Payload) Valid() error {
 
 	return nil
 }
+
+// verifyType checks that the token is of the expected type, so e.g. a refresh token can't be used as an access token
+func (payload *Payload) verifyType(tokenType TokenType) error {
+	if payload.Type != tokenType {
+		return ErrInvalidToken
+	}
+
+	return nil
+}
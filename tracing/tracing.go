@@ -0,0 +1,86 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"io"
+	"os"
+)
+
+// ServiceName names the service in the spans
+const ServiceName = "simplebank"
+
+// exporters of the spans
+const (
+	ExporterNone   = ""
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+// Setup registers the global tracer provider and the W3C trace context propagator,
+// so the spans of a request continue the trace of its traceparent header
+// the spans are written as JSON to stdout or to a file, so tracing works without a collector,
+// and they are not recorded at all when no exporter is configured
+// the returned function flushes the remaining spans and closes the file, it must be called before the process exits
+func Setup(exporter string, file string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var writer io.WriteCloser
+
+	switch exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		writer = nopCloser{os.Stdout}
+	case ExporterFile:
+		if file == "" {
+			return nil, fmt.Errorf("the file of the %s exporter is not configured", ExporterFile)
+		}
+
+		var err error
+		writer, err = os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open the trace file: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported trace exporter %q", exporter)
+	}
+
+	spanExporter, err := stdouttrace.New(stdouttrace.WithWriter(writer))
+	if err != nil {
+		writer.Close()
+		return nil, fmt.Errorf("cannot create the trace exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(ServiceName))),
+	)
+
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+
+		return err
+	}, nil
+}
+
+// nopCloser keeps stdout open when the tracer provider is shut down
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,40 @@
+package tracing
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetupFileExporter(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "traces.json")
+
+	shutdown, err := Setup(ExporterFile, file)
+	require.NoError(t, err)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "transfer")
+	span.End()
+
+	// the spans are batched, they are written when the provider is shut down
+	require.NoError(t, shutdown(context.Background()))
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"Name":"transfer"`)
+	require.Contains(t, string(data), ServiceName)
+}
+
+func TestSetupErrors(t *testing.T) {
+	_, err := Setup(ExporterFile, "")
+	require.Error(t, err)
+
+	_, err = Setup("jaeger", "")
+	require.Error(t, err)
+
+	shutdown, err := Setup(ExporterNone, "")
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+}
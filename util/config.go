@@ -8,12 +8,37 @@ import (
 // Config holds all the configuration of the applications
 // the values are read by Viper from a config file or environment variables
 type Config struct {
-	DBDriver            string        `mapstructure:"DB_DRIVER"`
-	DBSource            string        `mapstructure:"DB_SOURCE"`
-	MigrationURL        string        `mapstructure:"MIGRATION_URL"`
-	ServerAddress       string        `mapstructure:"SERVER_ADDRESS"`
-	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
-	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	DBDriver                string        `mapstructure:"DB_DRIVER"`
+	DBSource                string        `mapstructure:"DB_SOURCE"`
+	MigrationURL            string        `mapstructure:"MIGRATION_URL"`
+	ServerAddress           string        `mapstructure:"SERVER_ADDRESS"`
+	GRPCServerAddress       string        `mapstructure:"GRPC_SERVER_ADDRESS"`
+	HTTPReadTimeout         time.Duration `mapstructure:"HTTP_READ_TIMEOUT"`
+	HTTPWriteTimeout        time.Duration `mapstructure:"HTTP_WRITE_TIMEOUT"`
+	HTTPIdleTimeout         time.Duration `mapstructure:"HTTP_IDLE_TIMEOUT"`
+	ShutdownTimeout         time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+	LogLevel                string        `mapstructure:"LOG_LEVEL"`
+	TracingExporter         string        `mapstructure:"TRACING_EXPORTER"`
+	TracingFile             string        `mapstructure:"TRACING_FILE"`
+	TokenSymmetricKey       string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	AccessTokenDuration     time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration    time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	LoginMaxAttempts        int           `mapstructure:"LOGIN_MAX_ATTEMPTS"`
+	LoginLockoutDuration    time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	LoginMaxLockoutDuration time.Duration `mapstructure:"LOGIN_MAX_LOCKOUT_DURATION"`
+	PasswordResetDuration   time.Duration `mapstructure:"PASSWORD_RESET_DURATION"`
+	Mailer                  string        `mapstructure:"MAILER"`
+	MailerFile              string        `mapstructure:"MAILER_FILE"`
+	VerifyEmailURL          string        `mapstructure:"VERIFY_EMAIL_URL"`
+	VerifyEmailDuration     time.Duration `mapstructure:"VERIFY_EMAIL_DURATION"`
+	ServiceTokens           []string      `mapstructure:"SERVICE_TOKENS"`
+	TrustedProxies          []string      `mapstructure:"TRUSTED_PROXIES"`
+	RateLimits              []string      `mapstructure:"RATE_LIMITS"`
+	FXRatesFile             string        `mapstructure:"FX_RATES_FILE"`
+	CurrencyRefreshInterval time.Duration `mapstructure:"CURRENCY_REFRESH_INTERVAL"`
+	OutboxWebhookURL        string        `mapstructure:"OUTBOX_WEBHOOK_URL"`
+	OutboxFile              string        `mapstructure:"OUTBOX_FILE"`
+	OutboxPollInterval      time.Duration `mapstructure:"OUTBOX_POLL_INTERVAL"`
 }
 
 // LoadConfig read configuration from file or environment variables
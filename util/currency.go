@@ -1,17 +1,115 @@
 package util
 
+import (
+	"sort"
+	"sync"
+)
+
 const (
 	USD = "USD"
 	EUR = "EUR"
 	CAD = "CAD"
 	AUD = "AUD"
+	JPY = "JPY"
 )
 
-func IsSupportedCurrency(currency string) bool {
-	switch currency {
-	case USD, EUR, AUD, CAD:
-		return true
+// Currency is an ISO 4217 currency the bank knows about
+type Currency struct {
+	Code string `json:"code"`
+	// MinorUnits is the number of decimal places of the currency, e.g. 2 for cents
+	MinorUnits int    `json:"minor_units"`
+	Symbol     string `json:"symbol"`
+	// Enabled currencies can be used for new accounts and incoming transfers,
+	// the money in the accounts of a disabled currency can still be moved out
+	Enabled bool `json:"enabled"`
+}
+
+// CurrencyRegistry holds the currencies in memory, so they can be checked on every request
+type CurrencyRegistry struct {
+	mutex      sync.RWMutex
+	currencies map[string]Currency
+}
+
+func NewCurrencyRegistry(currencies []Currency) *CurrencyRegistry {
+	registry := CurrencyRegistry{}
+	registry.Load(currencies)
+
+	return &registry
+}
+
+// Load replaces all the currencies of the registry
+func (registry *CurrencyRegistry) Load(currencies []Currency) {
+	byCode := make(map[string]Currency, len(currencies))
+	for _, currency := range currencies {
+		byCode[currency.Code] = currency
 	}
 
-	return false
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	registry.currencies = byCode
+}
+
+// Set adds or replaces a single currency
+func (registry *CurrencyRegistry) Set(currency Currency) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	registry.currencies[currency.Code] = currency
+}
+
+// Get returns the currency, enabled or not
+func (registry *CurrencyRegistry) Get(code string) (Currency, bool) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	currency, ok := registry.currencies[code]
+	return currency, ok
+}
+
+// List returns all the currencies sorted by code
+func (registry *CurrencyRegistry) List() []Currency {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	currencies := make([]Currency, 0, len(registry.currencies))
+	for _, currency := range registry.currencies {
+		currencies = append(currencies, currency)
+	}
+
+	sort.Slice(currencies, func(i, j int) bool {
+		return currencies[i].Code < currencies[j].Code
+	})
+
+	return currencies
+}
+
+// DefaultCurrencies are the currencies known before the registry is loaded from the database
+var DefaultCurrencies = []Currency{
+	{Code: AUD, MinorUnits: 2, Symbol: "A$", Enabled: true},
+	{Code: CAD, MinorUnits: 2, Symbol: "C$", Enabled: true},
+	{Code: EUR, MinorUnits: 2, Symbol: "€", Enabled: true},
+	{Code: JPY, MinorUnits: 0, Symbol: "¥", Enabled: true},
+	{Code: USD, MinorUnits: 2, Symbol: "$", Enabled: true},
+}
+
+// Currencies is the registry used by the whole application
+var Currencies = NewCurrencyRegistry(DefaultCurrencies)
+
+// IsSupportedCurrency tells if the currency is known and enabled
+func IsSupportedCurrency(currency string) bool {
+	c, ok := Currencies.Get(currency)
+	return ok && c.Enabled
+}
+
+// IsKnownCurrency tells if the currency is known, enabled or not
+func IsKnownCurrency(currency string) bool {
+	_, ok := Currencies.Get(currency)
+	return ok
+}
+
+// MinorUnits returns the number of decimal places of the currency, disabled currencies included
+func MinorUnits(currency string) (int, bool) {
+	c, ok := Currencies.Get(currency)
+	return c.MinorUnits, ok
 }
@@ -0,0 +1,50 @@
+package util
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCurrencyRegistry(t *testing.T) {
+	registry := NewCurrencyRegistry([]Currency{
+		{Code: USD, MinorUnits: 2, Symbol: "$", Enabled: true},
+		{Code: JPY, MinorUnits: 0, Symbol: "¥", Enabled: false},
+	})
+
+	currency, ok := registry.Get(JPY)
+	require.True(t, ok)
+	require.Equal(t, 0, currency.MinorUnits)
+	require.False(t, currency.Enabled)
+
+	_, ok = registry.Get(EUR)
+	require.False(t, ok)
+
+	registry.Set(Currency{Code: EUR, MinorUnits: 2, Symbol: "€", Enabled: true})
+
+	currencies := registry.List()
+	require.Len(t, currencies, 3)
+	require.Equal(t, EUR, currencies[0].Code)
+	require.Equal(t, JPY, currencies[1].Code)
+	require.Equal(t, USD, currencies[2].Code)
+
+	registry.Load([]Currency{{Code: CAD, MinorUnits: 2, Symbol: "C$", Enabled: true}})
+	require.Len(t, registry.List(), 1)
+}
+
+func TestIsSupportedCurrency(t *testing.T) {
+	defer Currencies.Load(DefaultCurrencies)
+
+	require.True(t, IsSupportedCurrency(JPY))
+	require.False(t, IsSupportedCurrency("XYZ"))
+
+	Currencies.Set(Currency{Code: JPY, MinorUnits: 0, Symbol: "¥", Enabled: false})
+
+	// a disabled currency can't be used but its amounts can still be formatted
+	require.False(t, IsSupportedCurrency(JPY))
+	require.True(t, IsKnownCurrency(JPY))
+	require.False(t, IsKnownCurrency("XYZ"))
+
+	decimals, ok := MinorUnits(JPY)
+	require.True(t, ok)
+	require.Equal(t, 0, decimals)
+}
@@ -0,0 +1,49 @@
+package util
+
+import (
+	"database/sql"
+	"time"
+)
+
+// defaults of the login lockout, used when the config doesn't set them
+const (
+	defaultLoginMaxAttempts        = 5
+	defaultLoginLockoutDuration    = time.Minute
+	defaultLoginMaxLockoutDuration = 24 * time.Hour
+)
+
+// LoginLockout locks a user out for Duration after MaxAttempts failed logins in a row,
+// every failed login after a lockout doubles it, up to MaxDuration
+type LoginLockout struct {
+	MaxAttempts int
+	Duration    time.Duration
+	MaxDuration time.Duration
+}
+
+// LoginLockout returns the lockout policy of the config, with the defaults for the values it doesn't set
+func (config Config) LoginLockout() LoginLockout {
+	lockout := LoginLockout{
+		MaxAttempts: config.LoginMaxAttempts,
+		Duration:    config.LoginLockoutDuration,
+		MaxDuration: config.LoginMaxLockoutDuration,
+	}
+
+	if lockout.MaxAttempts <= 0 {
+		lockout.MaxAttempts = defaultLoginMaxAttempts
+	}
+
+	if lockout.Duration <= 0 {
+		lockout.Duration = defaultLoginLockoutDuration
+	}
+
+	if lockout.MaxDuration <= 0 {
+		lockout.MaxDuration = defaultLoginMaxLockoutDuration
+	}
+
+	return lockout
+}
+
+// LockedOut tells if a user whose lockout ends at lockedUntil can't log in yet
+func LockedOut(lockedUntil sql.NullTime) bool {
+	return lockedUntil.Valid && time.Now().Before(lockedUntil.Time)
+}
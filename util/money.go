@@ -0,0 +1,146 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	ErrInvalidMoney        = errors.New("invalid money amount")
+	ErrCurrencyMismatch    = errors.New("currencies of the amounts do not match")
+	ErrMoneyOverflow       = errors.New("money amount overflows")
+)
+
+var decimalPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// Money is an amount in the minor units of its currency, e.g. 1234 USD is 12.34 dollars
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// NewMoney creates money from an amount in minor units
+func NewMoney(amount int64, currency string) (Money, error) {
+	if !IsSupportedCurrency(currency) {
+		return Money{}, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, currency)
+	}
+
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// ParseMoney parses a decimal string like "12.34" in the given currency
+// it fails if the value has more decimal places than the currency
+func ParseMoney(value string, currency string) (Money, error) {
+	decimals, ok := MinorUnits(currency)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, currency)
+	}
+
+	if !decimalPattern.MatchString(value) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, value)
+	}
+
+	integer, fraction, _ := strings.Cut(value, ".")
+	if len(fraction) > decimals {
+		return Money{}, fmt.Errorf("%w: %s has %d decimal places", ErrInvalidMoney, currency, decimals)
+	}
+
+	// "12.3" in a currency with 2 decimal places is 1230 minor units
+	minor := integer + fraction + strings.Repeat("0", decimals-len(fraction))
+
+	amount, err := strconv.ParseInt(minor, 10, 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return Money{}, ErrMoneyOverflow
+		}
+
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, value)
+	}
+
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// String formats the amount as a decimal string, e.g. "12.34"
+func (money Money) String() string {
+	decimals, _ := MinorUnits(money.Currency)
+
+	sign := ""
+	// the absolute value of math.MinInt64 does not fit in an int64
+	abs := uint64(money.Amount)
+	if money.Amount < 0 {
+		sign = "-"
+		abs = uint64(-(money.Amount + 1)) + 1
+	}
+
+	digits := strconv.FormatUint(abs, 10)
+	if decimals == 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-decimals] + "." + digits[len(digits)-decimals:]
+}
+
+// Add returns the sum of the amounts, they must have the same currency
+func (money Money) Add(other Money) (Money, error) {
+	if money.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+
+	if (other.Amount > 0 && money.Amount > math.MaxInt64-other.Amount) ||
+		(other.Amount < 0 && money.Amount < math.MinInt64-other.Amount) {
+		return Money{}, ErrMoneyOverflow
+	}
+
+	return Money{Amount: money.Amount + other.Amount, Currency: money.Currency}, nil
+}
+
+// Sub returns the difference of the amounts, they must have the same currency
+func (money Money) Sub(other Money) (Money, error) {
+	if money.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+
+	if (other.Amount < 0 && money.Amount > math.MaxInt64+other.Amount) ||
+		(other.Amount > 0 && money.Amount < math.MinInt64+other.Amount) {
+		return Money{}, ErrMoneyOverflow
+	}
+
+	return Money{Amount: money.Amount - other.Amount, Currency: money.Currency}, nil
+}
+
+// moneyJSON is how money is sent to and received from the clients
+type moneyJSON struct {
+	Value    string `json:"value"`
+	Currency string `json:"currency"`
+}
+
+// MarshalJSON encodes the money as {"value": "12.34", "currency": "USD"}
+func (money Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Value: money.String(), Currency: money.Currency})
+}
+
+// UnmarshalJSON decodes money encoded by MarshalJSON
+func (money *Money) UnmarshalJSON(data []byte) error {
+	var value moneyJSON
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	parsed, err := ParseMoney(value.Value, value.Currency)
+	if err != nil {
+		return err
+	}
+
+	*money = parsed
+	return nil
+}
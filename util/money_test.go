@@ -0,0 +1,102 @@
+package util
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	testCases := []struct {
+		value    string
+		currency string
+		amount   int64
+		err      error
+	}{
+		{value: "12.34", currency: USD, amount: 1234},
+		{value: "12.3", currency: USD, amount: 1230},
+		{value: "12", currency: EUR, amount: 1200},
+		{value: "-0.05", currency: CAD, amount: -5},
+		{value: "1500", currency: JPY, amount: 1500},
+		{value: "12.345", currency: USD, err: ErrInvalidMoney},
+		{value: "12.5", currency: JPY, err: ErrInvalidMoney},
+		{value: "12.", currency: USD, err: ErrInvalidMoney},
+		{value: "1e3", currency: USD, err: ErrInvalidMoney},
+		{value: "", currency: USD, err: ErrInvalidMoney},
+		{value: "12.34", currency: "XYZ", err: ErrUnsupportedCurrency},
+		{value: "92233720368547758.08", currency: USD, err: ErrMoneyOverflow},
+	}
+
+	for _, testCase := range testCases {
+		money, err := ParseMoney(testCase.value, testCase.currency)
+
+		if testCase.err != nil {
+			require.ErrorIs(t, err, testCase.err, testCase.value)
+			continue
+		}
+
+		require.NoError(t, err, testCase.value)
+		require.Equal(t, testCase.amount, money.Amount)
+		require.Equal(t, testCase.currency, money.Currency)
+	}
+}
+
+func TestMoney_String(t *testing.T) {
+	require.Equal(t, "12.34", Money{Amount: 1234, Currency: USD}.String())
+	require.Equal(t, "0.05", Money{Amount: 5, Currency: USD}.String())
+	require.Equal(t, "-0.05", Money{Amount: -5, Currency: USD}.String())
+	require.Equal(t, "0.00", Money{Amount: 0, Currency: EUR}.String())
+	require.Equal(t, "1500", Money{Amount: 1500, Currency: JPY}.String())
+	require.Equal(t, "-92233720368547758.08", Money{Amount: math.MinInt64, Currency: USD}.String())
+
+	// formatting and parsing round trip
+	for _, amount := range []int64{math.MaxInt64, math.MinInt64, 1, -1, 100} {
+		money := Money{Amount: amount, Currency: AUD}
+
+		parsed, err := ParseMoney(money.String(), AUD)
+		require.NoError(t, err)
+		require.Equal(t, money, parsed)
+	}
+}
+
+func TestMoney_Arithmetic(t *testing.T) {
+	a := Money{Amount: 1000, Currency: USD}
+	b := Money{Amount: 250, Currency: USD}
+
+	sum, err := a.Add(b)
+	require.NoError(t, err)
+	require.Equal(t, int64(1250), sum.Amount)
+
+	diff, err := b.Sub(a)
+	require.NoError(t, err)
+	require.Equal(t, int64(-750), diff.Amount)
+
+	_, err = a.Add(Money{Amount: 1, Currency: EUR})
+	require.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	_, err = Money{Amount: math.MaxInt64, Currency: USD}.Add(Money{Amount: 1, Currency: USD})
+	require.ErrorIs(t, err, ErrMoneyOverflow)
+
+	_, err = Money{Amount: math.MinInt64, Currency: USD}.Sub(Money{Amount: 1, Currency: USD})
+	require.ErrorIs(t, err, ErrMoneyOverflow)
+
+	_, err = Money{Amount: -1, Currency: USD}.Sub(Money{Amount: math.MaxInt64, Currency: USD})
+	require.NoError(t, err)
+}
+
+func TestMoney_JSON(t *testing.T) {
+	money := Money{Amount: 1234, Currency: USD}
+
+	data, err := json.Marshal(money)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"value": "12.34", "currency": "USD"}`, string(data))
+
+	var decoded Money
+	err = json.Unmarshal(data, &decoded)
+	require.NoError(t, err)
+	require.Equal(t, money, decoded)
+
+	err = json.Unmarshal([]byte(`{"value": "1.234", "currency": "USD"}`), &decoded)
+	require.ErrorIs(t, err, ErrInvalidMoney)
+}
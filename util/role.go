@@ -0,0 +1,13 @@
+package util
+
+// roles of the users, tellers and admins are bank staff
+const (
+	CustomerRole = "customer"
+	TellerRole   = "teller"
+	AdminRole    = "admin"
+)
+
+// IsStaffRole tells if the role can act on the resources of every customer
+func IsStaffRole(role string) bool {
+	return role == TellerRole || role == AdminRole
+}
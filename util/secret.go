@@ -0,0 +1,26 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+const secretLength = 32
+
+// NewSecret generates a random secret to send to a user, e.g. a password reset token or the secret of a webhook subscription
+func NewSecret() (string, error) {
+	secret := make([]byte, secretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(secret), nil
+}
+
+// HashSecret returns the hex sha256 of a secret, only the hash is stored, so a leaked row can't be used
+// the secrets are random and long, so they don't need a slow hash like the passwords
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,22 @@
+package util
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSecret(t *testing.T) {
+	secret1, err := NewSecret()
+	require.NoError(t, err)
+	require.Len(t, secret1, 2*secretLength)
+
+	secret2, err := NewSecret()
+	require.NoError(t, err)
+	require.NotEqual(t, secret1, secret2)
+
+	hash := HashSecret(secret1)
+	require.Len(t, hash, 64)
+	require.NotEqual(t, secret1, hash)
+	require.Equal(t, hash, HashSecret(secret1))
+	require.NotEqual(t, hash, HashSecret(secret2))
+}
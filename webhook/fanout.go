@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/outbox"
+)
+
+// AccountEvent is the data of the account.credited and account.debited events
+// it has the transfer or the external transaction that created the entry
+type AccountEvent struct {
+	AccountID           int64                   `json:"account_id"`
+	Entry               db.Entry                `json:"entry"`
+	Transfer            *db.Transfer            `json:"transfer,omitempty"`
+	ExternalTransaction *db.ExternalTransaction `json:"external_transaction,omitempty"`
+}
+
+// Fanout is the outbox sink that turns the completed transfers and external transactions
+// into a delivery for every subscription of the owners of the accounts
+// the deliveries are sent by the Sender, so a slow subscriber doesn't hold up the outbox
+type Fanout struct {
+	store db.Store
+}
+
+// NewFanout creates the sink that creates the webhook deliveries
+func NewFanout(store db.Store) *Fanout {
+	return &Fanout{store: store}
+}
+
+func (fanout *Fanout) Deliver(ctx context.Context, event outbox.Event) error {
+	var accountEvents []AccountEvent
+
+	switch event.Type {
+	case db.EventTransferCompleted:
+		var completed db.TransferCompletedEvent
+		if err := json.Unmarshal(event.Payload, &completed); err != nil {
+			return err
+		}
+
+		accountEvents = []AccountEvent{
+			{AccountID: completed.FromEntry.AccountID, Entry: completed.FromEntry, Transfer: &completed.Transfer},
+			{AccountID: completed.ToEntry.AccountID, Entry: completed.ToEntry, Transfer: &completed.Transfer},
+		}
+	case db.EventExternalTransactionCompleted:
+		var completed db.ExternalTransactionCompletedEvent
+		if err := json.Unmarshal(event.Payload, &completed); err != nil {
+			return err
+		}
+
+		accountEvents = []AccountEvent{
+			{AccountID: completed.Entry.AccountID, Entry: completed.Entry, ExternalTransaction: &completed.ExternalTransaction},
+		}
+	default:
+		return nil
+	}
+
+	for _, accountEvent := range accountEvents {
+		if err := fanout.createDeliveries(ctx, event.ID, accountEvent); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createDeliveries creates a delivery for every subscription of the account owner to the event
+// the outbox delivers an event at least once, the deliveries that already exist are skipped
+func (fanout *Fanout) createDeliveries(ctx context.Context, outboxID int64, accountEvent AccountEvent) error {
+	eventType := EventAccountCredited
+	if accountEvent.Entry.Amount < 0 {
+		eventType = EventAccountDebited
+	}
+
+	account, err := fanout.store.GetAccount(ctx, accountEvent.AccountID)
+	if err != nil {
+		return err
+	}
+
+	subscriptions, err := fanout.store.ListWebhookSubscriptionsByEvent(ctx, db.ListWebhookSubscriptionsByEventParams{
+		Owner:     account.Owner,
+		EventType: eventType,
+	})
+
+	if err != nil || len(subscriptions) == 0 {
+		return err
+	}
+
+	payload, err := json.Marshal(accountEvent)
+	if err != nil {
+		return err
+	}
+
+	for _, subscription := range subscriptions {
+		_, err := fanout.store.CreateWebhookDelivery(ctx, db.CreateWebhookDeliveryParams{
+			SubscriptionID: subscription.ID,
+			OutboxID:       outboxID,
+			EntryID:        accountEvent.Entry.ID,
+			EventType:      eventType,
+			Payload:        payload,
+		})
+
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+	}
+
+	return nil
+}
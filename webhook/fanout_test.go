@@ -0,0 +1,110 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/outbox"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestFanout_Deliver(t *testing.T) {
+	fromAccount := db.Account{ID: 1, Owner: "alice"}
+	toAccount := db.Account{ID: 2, Owner: "bob"}
+
+	completed := db.TransferCompletedEvent{
+		Transfer:  db.Transfer{ID: 5, FromAccountID: fromAccount.ID, ToAccountID: toAccount.ID, Amount: 10},
+		FromEntry: db.Entry{ID: 8, AccountID: fromAccount.ID, Amount: -10},
+		ToEntry:   db.Entry{ID: 9, AccountID: toAccount.ID, Amount: 10},
+	}
+
+	payload, err := json.Marshal(completed)
+	require.NoError(t, err)
+
+	event := outbox.Event{ID: 4, Type: db.EventTransferCompleted, Payload: payload}
+
+	subscription := db.WebhookSubscription{ID: 3, Owner: toAccount.Owner, Events: []string{EventAccountCredited}}
+
+	testCases := []struct {
+		name       string
+		event      outbox.Event
+		buildStubs func(store *mockdb.MockStore)
+	}{
+		{
+			name:  "TransferCompleted",
+			event: event,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(fromAccount.ID)).Times(1).Return(fromAccount, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(toAccount.ID)).Times(1).Return(toAccount, nil)
+
+				store.EXPECT().
+					ListWebhookSubscriptionsByEvent(gomock.Any(), gomock.Eq(db.ListWebhookSubscriptionsByEventParams{
+						Owner:     fromAccount.Owner,
+						EventType: EventAccountDebited,
+					})).
+					Times(1).
+					Return(nil, nil)
+
+				store.EXPECT().
+					ListWebhookSubscriptionsByEvent(gomock.Any(), gomock.Eq(db.ListWebhookSubscriptionsByEventParams{
+						Owner:     toAccount.Owner,
+						EventType: EventAccountCredited,
+					})).
+					Times(1).
+					Return([]db.WebhookSubscription{subscription}, nil)
+
+				store.EXPECT().
+					CreateWebhookDelivery(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, args db.CreateWebhookDeliveryParams) (db.WebhookDelivery, error) {
+						require.Equal(t, subscription.ID, args.SubscriptionID)
+						require.Equal(t, event.ID, args.OutboxID)
+						require.Equal(t, completed.ToEntry.ID, args.EntryID)
+						require.Equal(t, EventAccountCredited, args.EventType)
+
+						var data AccountEvent
+						require.NoError(t, json.Unmarshal(args.Payload, &data))
+						require.Equal(t, toAccount.ID, data.AccountID)
+						require.Equal(t, completed.Transfer.ID, data.Transfer.ID)
+						return db.WebhookDelivery{}, nil
+					})
+			},
+		},
+		{
+			name:  "DeliveryAlreadyCreated",
+			event: event,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(2).Return(toAccount, nil)
+				store.EXPECT().ListWebhookSubscriptionsByEvent(gomock.Any(), gomock.Any()).Times(2).Return([]db.WebhookSubscription{subscription}, nil)
+				store.EXPECT().CreateWebhookDelivery(gomock.Any(), gomock.Any()).Times(2).Return(db.WebhookDelivery{}, sql.ErrNoRows)
+			},
+		},
+		{
+			name:  "OtherEvent",
+			event: outbox.Event{ID: 4, Type: "user.created", Payload: json.RawMessage(`{}`)},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateWebhookDelivery(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+
+			testCase.buildStubs(store)
+
+			err := NewFanout(store).Deliver(context.Background(), testCase.event)
+			require.NoError(t, err)
+		})
+	}
+}
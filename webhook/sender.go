@@ -0,0 +1,207 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/aybarsacar/simplebank/outbox"
+	"github.com/aybarsacar/simplebank/worker"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// statuses of the deliveries
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusFailed    = "failed"
+)
+
+const (
+	defaultPollInterval = time.Second
+	// number of deliveries claimed at once
+	batchSize = 100
+	// a claimed delivery is claimed again if it is not marked within the lease, e.g. the process stopped
+	// the posts of a batch are cut off when the lease runs out, so a delivery is never sent by two senders at once
+	claimLease = time.Minute
+	// a delivery that fails this many times is failed, it can still be replayed
+	maxAttempts = 10
+	sendTimeout = 10 * time.Second
+)
+
+// Sender posts the pending deliveries to the urls of their subscriptions
+// a delivery that fails is retried with the backoff of the outbox until it runs out of attempts
+type Sender struct {
+	store        db.Store
+	client       *http.Client
+	pollInterval time.Duration
+	lease        time.Duration
+}
+
+// NewSender creates a sender that checks for due deliveries every poll interval
+func NewSender(store db.Store, pollInterval time.Duration) *Sender {
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+
+	return &Sender{
+		store:        store,
+		client:       newClient(),
+		pollInterval: pollInterval,
+		lease:        claimLease,
+	}
+}
+
+// newClient creates the client that posts the deliveries, it only connects to public addresses
+// the redirects are not followed, a subscriber could send the deliveries to another host with them
+func newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: sendTimeout,
+		Control: dialControl,
+	}
+
+	return &http.Client{
+		Timeout: sendTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: sendTimeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Start sends the deliveries until the context is cancelled
+func (sender *Sender) Start(ctx context.Context) {
+	worker.Poll(ctx, sender.pollInterval, batchSize, sender.SendPending, "cannot send webhook deliveries")
+}
+
+// SendPending sends one batch of the deliveries that are due and returns how many were claimed
+func (sender *Sender) SendPending(ctx context.Context) (int, error) {
+	leaseUntil := time.Now().Add(sender.lease)
+
+	deliveries, err := sender.store.ClaimWebhookDeliveries(ctx, db.ClaimWebhookDeliveriesParams{
+		LeaseUntil: leaseUntil,
+		BatchSize:  batchSize,
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	// the deliveries are only posted while they are leased to this sender
+	leaseCtx, cancel := context.WithDeadline(ctx, leaseUntil)
+	defer cancel()
+
+	for _, delivery := range deliveries {
+		// the deliveries left when the lease runs out can be claimed again right away
+		if leaseCtx.Err() != nil {
+			break
+		}
+
+		if err := sender.send(ctx, leaseCtx, delivery); err != nil {
+			return len(deliveries), err
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// send posts the delivery with the lease context and records the outcome
+// only the errors of recording the outcome are returned, a failed delivery is retried later
+func (sender *Sender) send(ctx context.Context, leaseCtx context.Context, delivery db.WebhookDelivery) error {
+	subscription, err := sender.store.GetWebhookSubscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		// the deliveries of a deleted subscription are deleted with it
+		if err == sql.ErrNoRows {
+			return nil
+		}
+
+		return err
+	}
+
+	statusCode, err := sender.post(leaseCtx, subscription, delivery)
+
+	// a post cut off by the end of the lease is not an attempt, the delivery is claimed again
+	if err != nil && leaseCtx.Err() != nil {
+		return nil
+	}
+
+	responseStatus := sql.NullInt32{
+		Int32: int32(statusCode),
+		Valid: statusCode != 0,
+	}
+
+	if err == nil {
+		return sender.store.MarkWebhookDeliveryDelivered(ctx, db.MarkWebhookDeliveryDeliveredParams{
+			ResponseStatus: responseStatus,
+			ID:             delivery.ID,
+		})
+	}
+
+	status := DeliveryStatusPending
+	if delivery.Attempts+1 >= maxAttempts {
+		status = DeliveryStatusFailed
+	}
+
+	return sender.store.MarkWebhookDeliveryFailed(ctx, db.MarkWebhookDeliveryFailedParams{
+		Status:         status,
+		LastError:      err.Error(),
+		ResponseStatus: responseStatus,
+		NextAttemptAt:  time.Now().Add(outbox.Backoff(delivery.Attempts)),
+		ID:             delivery.ID,
+	})
+}
+
+// post sends the signed message and returns the status code of the response, zero when there was no response
+// any response other than 2xx is a failed delivery
+func (sender *Sender) post(ctx context.Context, subscription db.WebhookSubscription, delivery db.WebhookDelivery) (int, error) {
+	// the subscriptions created before the urls had to use https are not posted to
+	if err := ValidateURL(subscription.Url); err != nil {
+		return 0, err
+	}
+
+	body, err := json.Marshal(Message{
+		ID:        delivery.ID,
+		Type:      delivery.EventType,
+		Data:      delivery.Payload,
+		CreatedAt: delivery.CreatedAt,
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(IDHeaderKey, strconv.FormatInt(delivery.ID, 10))
+	request.Header.Set(TimestampHeaderKey, timestamp)
+	request.Header.Set(SignatureHeaderKey, Sign(subscription.Secret, timestamp, body))
+
+	response, err := sender.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("webhook responded with status %d", response.StatusCode)
+	}
+
+	return response.StatusCode, nil
+}
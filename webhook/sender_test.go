@@ -0,0 +1,294 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	mockdb "github.com/aybarsacar/simplebank/db/mock"
+	db "github.com/aybarsacar/simplebank/db/sqlc"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSender_SendPending(t *testing.T) {
+	delivery := db.WebhookDelivery{
+		ID:             7,
+		SubscriptionID: 3,
+		EventType:      EventAccountCredited,
+		Payload:        json.RawMessage(`{"account_id":1}`),
+		Status:         DeliveryStatusPending,
+		Attempts:       2,
+		CreatedAt:      time.Now(),
+	}
+
+	testCases := []struct {
+		name       string
+		attempts   int32
+		statusCode int
+		buildStubs func(store *mockdb.MockStore)
+	}{
+		{
+			name:       "Delivered",
+			attempts:   2,
+			statusCode: http.StatusOK,
+			buildStubs: func(store *mockdb.MockStore) {
+				args := db.MarkWebhookDeliveryDeliveredParams{
+					ResponseStatus: sql.NullInt32{Int32: http.StatusOK, Valid: true},
+					ID:             delivery.ID,
+				}
+
+				store.EXPECT().MarkWebhookDeliveryDelivered(gomock.Any(), gomock.Eq(args)).Times(1).Return(nil)
+				store.EXPECT().MarkWebhookDeliveryFailed(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:       "Retried",
+			attempts:   2,
+			statusCode: http.StatusServiceUnavailable,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().MarkWebhookDeliveryDelivered(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					MarkWebhookDeliveryFailed(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, args db.MarkWebhookDeliveryFailedParams) error {
+						require.Equal(t, delivery.ID, args.ID)
+						require.Equal(t, DeliveryStatusPending, args.Status)
+						require.Equal(t, sql.NullInt32{Int32: http.StatusServiceUnavailable, Valid: true}, args.ResponseStatus)
+						// the third attempt waits 4 seconds
+						require.WithinDuration(t, time.Now().Add(4*time.Second), args.NextAttemptAt, time.Second)
+						return nil
+					})
+			},
+		},
+		{
+			name:       "Failed",
+			attempts:   maxAttempts - 1,
+			statusCode: http.StatusInternalServerError,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().MarkWebhookDeliveryDelivered(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().
+					MarkWebhookDeliveryFailed(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, args db.MarkWebhookDeliveryFailedParams) error {
+						require.Equal(t, DeliveryStatusFailed, args.Status)
+						return nil
+					})
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			secret := "subscription-secret"
+
+			subscriber := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+
+				timestamp := r.Header.Get(TimestampHeaderKey)
+				require.Equal(t, Sign(secret, timestamp, body), r.Header.Get(SignatureHeaderKey))
+				require.Equal(t, strconv.FormatInt(delivery.ID, 10), r.Header.Get(IDHeaderKey))
+
+				var message Message
+				require.NoError(t, json.Unmarshal(body, &message))
+				require.Equal(t, delivery.ID, message.ID)
+				require.Equal(t, EventAccountCredited, message.Type)
+				require.JSONEq(t, string(delivery.Payload), string(message.Data))
+
+				w.WriteHeader(testCase.statusCode)
+			}))
+			defer subscriber.Close()
+
+			subscription := db.WebhookSubscription{
+				ID:     delivery.SubscriptionID,
+				Url:    subscriber.URL,
+				Secret: secret,
+			}
+
+			claimed := delivery
+			claimed.Attempts = testCase.attempts
+
+			controller := gomock.NewController(t)
+
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+
+			store.EXPECT().ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return([]db.WebhookDelivery{claimed}, nil)
+			store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).Times(1).Return(subscription, nil)
+			testCase.buildStubs(store)
+
+			// the test subscriber listens on the loopback, which the client of the sender refuses
+			sender := NewSender(store, time.Second)
+			sender.client = subscriber.Client()
+
+			count, err := sender.SendPending(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, 1, count)
+		})
+	}
+}
+
+func TestSender_SendPendingRefusedURL(t *testing.T) {
+	subscriber := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the subscriber on the loopback must not be called")
+	}))
+	defer subscriber.Close()
+
+	testCases := []struct {
+		name string
+		url  string
+		err  error
+	}{
+		{
+			name: "InsecureURL",
+			url:  "http://example.com/webhooks",
+			err:  ErrInsecureURL,
+		},
+		{
+			name: "ForbiddenAddress",
+			url:  subscriber.URL,
+			err:  ErrForbiddenAddress,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			delivery := db.WebhookDelivery{
+				ID:             7,
+				SubscriptionID: 3,
+				EventType:      EventAccountCredited,
+				Payload:        json.RawMessage(`{"account_id":1}`),
+				Status:         DeliveryStatusPending,
+			}
+
+			subscription := db.WebhookSubscription{
+				ID:     delivery.SubscriptionID,
+				Url:    testCase.url,
+				Secret: "subscription-secret",
+			}
+
+			controller := gomock.NewController(t)
+
+			defer controller.Finish()
+
+			store := mockdb.NewMockStore(controller)
+
+			store.EXPECT().ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return([]db.WebhookDelivery{delivery}, nil)
+			store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).Times(1).Return(subscription, nil)
+			store.EXPECT().MarkWebhookDeliveryDelivered(gomock.Any(), gomock.Any()).Times(0)
+			store.EXPECT().
+				MarkWebhookDeliveryFailed(gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ context.Context, args db.MarkWebhookDeliveryFailedParams) error {
+					require.Equal(t, delivery.ID, args.ID)
+					require.Contains(t, args.LastError, testCase.err.Error())
+					require.False(t, args.ResponseStatus.Valid)
+					return nil
+				})
+
+			sender := NewSender(store, time.Second)
+
+			count, err := sender.SendPending(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, 1, count)
+		})
+	}
+}
+
+func TestSender_SendPendingLeaseExpired(t *testing.T) {
+	var posts int32
+	release := make(chan struct{})
+
+	subscriber := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&posts, 1)
+		<-release
+	}))
+	defer subscriber.Close()
+	defer close(release)
+
+	deliveries := []db.WebhookDelivery{
+		{ID: 1, SubscriptionID: 3, EventType: EventAccountCredited, Payload: json.RawMessage(`{}`)},
+		{ID: 2, SubscriptionID: 3, EventType: EventAccountCredited, Payload: json.RawMessage(`{}`)},
+	}
+
+	subscription := db.WebhookSubscription{
+		ID:     3,
+		Url:    subscriber.URL,
+		Secret: "subscription-secret",
+	}
+
+	controller := gomock.NewController(t)
+
+	defer controller.Finish()
+
+	store := mockdb.NewMockStore(controller)
+
+	store.EXPECT().ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).Times(1).Return(deliveries, nil)
+	store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(subscription.ID)).Times(1).Return(subscription, nil)
+	store.EXPECT().MarkWebhookDeliveryDelivered(gomock.Any(), gomock.Any()).Times(0)
+	store.EXPECT().MarkWebhookDeliveryFailed(gomock.Any(), gomock.Any()).Times(0)
+
+	sender := NewSender(store, time.Second)
+	sender.client = subscriber.Client()
+	sender.lease = 50 * time.Millisecond
+
+	count, err := sender.SendPending(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, len(deliveries), count)
+
+	// the first post used the whole lease, the second delivery is left for the next claim
+	require.Equal(t, int32(1), atomic.LoadInt32(&posts))
+}
+
+func TestAllowedIP(t *testing.T) {
+	testCases := []struct {
+		ip      string
+		allowed bool
+	}{
+		{ip: "93.184.216.34", allowed: true},
+		{ip: "2606:2800:220:1:248:1893:25c8:1946", allowed: true},
+		{ip: "127.0.0.1", allowed: false},
+		{ip: "::1", allowed: false},
+		{ip: "10.1.2.3", allowed: false},
+		{ip: "172.16.0.1", allowed: false},
+		{ip: "192.168.1.1", allowed: false},
+		{ip: "fd00::1", allowed: false},
+		{ip: "169.254.169.254", allowed: false},
+		{ip: "fe80::1", allowed: false},
+		{ip: "0.0.0.0", allowed: false},
+		{ip: "::ffff:127.0.0.1", allowed: false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.ip, func(t *testing.T) {
+			require.Equal(t, testCase.allowed, allowedIP(net.ParseIP(testCase.ip)))
+		})
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	require.NoError(t, ValidateURL("https://example.com/webhooks"))
+	require.ErrorIs(t, ValidateURL("http://example.com/webhooks"), ErrInsecureURL)
+	require.ErrorIs(t, ValidateURL("example.com/webhooks"), ErrInsecureURL)
+	require.Error(t, ValidateURL("https:///webhooks"))
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":1}`)
+
+	signature := Sign("secret", "1700000000", body)
+	require.Regexp(t, "^sha256=[0-9a-f]{64}$", signature)
+
+	require.Equal(t, signature, Sign("secret", "1700000000", body))
+	require.NotEqual(t, signature, Sign("other", "1700000000", body))
+	require.NotEqual(t, signature, Sign("secret", "1700000001", body))
+}
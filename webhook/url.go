@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"syscall"
+)
+
+var (
+	ErrInsecureURL      = errors.New("webhook url must use https")
+	ErrForbiddenAddress = errors.New("webhook address is not allowed")
+	errMissingURLHost   = errors.New("webhook url must have a host")
+)
+
+// ValidateURL checks the url of a subscription can be posted to, only https urls are accepted
+// the host is resolved when the deliveries are sent, so the addresses are checked by the dialer of the sender
+func ValidateURL(rawURL string) error {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	if parsedURL.Scheme != "https" {
+		return ErrInsecureURL
+	}
+
+	if parsedURL.Hostname() == "" {
+		return errMissingURLHost
+	}
+
+	return nil
+}
+
+// dialControl refuses the connections to the loopback, private and link-local addresses
+// it runs after the host is resolved, so a public name that resolves to an internal address is refused too
+func dialControl(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !allowedIP(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+
+	return nil
+}
+
+func allowedIP(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsUnspecified()
+}
@@ -0,0 +1,42 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// types of the events the users can subscribe to
+const (
+	EventAccountCredited = "account.credited"
+	EventAccountDebited  = "account.debited"
+)
+
+// headers of the deliveries, the subscribers verify the signature with the secret of their subscription
+const (
+	IDHeaderKey        = "X-Webhook-ID"
+	TimestampHeaderKey = "X-Webhook-Timestamp"
+	SignatureHeaderKey = "X-Webhook-Signature"
+)
+
+// Message is the body posted to the subscribers
+// the id is the id of the delivery, it stays the same across the retries and replays
+type Message struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Sign returns the signature of a delivery, the hex HMAC-SHA256 of the timestamp and the body joined with a dot
+// the timestamp is signed too, so the subscribers can reject old deliveries that are sent again
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,36 @@
+package worker
+
+import (
+	"context"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+// BatchFunc handles one batch of the items that are due, e.g. the events of the outbox, and returns how many it claimed
+type BatchFunc func(ctx context.Context) (int, error)
+
+// Poll calls the batch func every poll interval until the context is cancelled
+// it keeps going while full batches come back, so a backlog doesn't wait for the next tick
+// the errors are logged with the message and the items are claimed again on a later tick
+func Poll(ctx context.Context, pollInterval time.Duration, batchSize int, batch BatchFunc, errorMessage string) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				count, err := batch(ctx)
+				if err != nil {
+					log.Error().Err(err).Msg(errorMessage)
+				}
+
+				if err != nil || count < batchSize || ctx.Err() != nil {
+					break
+				}
+			}
+		}
+	}
+}
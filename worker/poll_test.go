@@ -0,0 +1,70 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestPoll(t *testing.T) {
+	testCases := []struct {
+		name          string
+		counts        []int
+		err           error
+		expectedCalls int
+	}{
+		{
+			name:          "DrainsFullBatches",
+			counts:        []int{2, 2, 1},
+			expectedCalls: 3,
+		},
+		{
+			name:          "EmptyBatch",
+			counts:        []int{0},
+			expectedCalls: 1,
+		},
+		{
+			name:          "StopsOnError",
+			counts:        []int{2},
+			err:           errors.New("claim failed"),
+			expectedCalls: 1,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+
+			calls := 0
+			batch := func(ctx context.Context) (int, error) {
+				count := tc.counts[calls]
+				calls++
+
+				// the first tick ends with the last count, so the poll is stopped before the next one
+				if calls == len(tc.counts) {
+					cancel()
+				}
+
+				return count, tc.err
+			}
+
+			go func() {
+				Poll(ctx, time.Millisecond, 2, batch, "cannot poll")
+				close(done)
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("poll did not return after the context was cancelled")
+			}
+
+			require.Equal(t, tc.expectedCalls, calls)
+		})
+	}
+}